@@ -0,0 +1,111 @@
+// Package apikeygroup maintains the group of handlers for the API keys of the authenticated user.
+package apikeygroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/yashshah7197/shrt/business/core/apikey"
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/foundation/web"
+)
+
+// Handlers manages the set of API key endpoints.
+type Handlers struct {
+	APIKey *apikey.Core
+}
+
+// Create issues a new API key acting for the authenticated subject. The key itself is only ever
+// part of this response.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	var nk apikey.NewKey
+	if err := web.Decode(r, &nk); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	k, err := h.APIKey.Create(ctx, nk, claims.Workspace, claims.Subject, claims.Role, v.Now)
+	if err != nil {
+		return fmt.Errorf("creating api key[%s]: %w", nk.Name, err)
+	}
+
+	return web.Respond(ctx, w, k, http.StatusCreated)
+}
+
+// Delete revokes an API key of the authenticated subject.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if _, err := h.queryOwned(ctx, id); err != nil {
+		return err
+	}
+
+	if err := h.APIKey.Delete(ctx, id); err != nil {
+		if errors.Is(err, apikey.ErrNotFound) {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("deleting api key[%s]: %w", id, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Query returns every API key of the authenticated subject, newest first.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	keys, err := h.APIKey.QueryByOwner(ctx, claims.Workspace, claims.Subject)
+	if err != nil {
+		return fmt.Errorf("querying api keys for subject[%s]: %w", claims.Subject, err)
+	}
+
+	return web.Respond(ctx, w, keys, http.StatusOK)
+}
+
+// QueryByID returns a single API key of the authenticated subject.
+func (h Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	k, err := h.queryOwned(ctx, web.Param(r, "id"))
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, k, http.StatusOK)
+}
+
+// queryOwned fetches the API key identified by the given ID and ensures that it was created by the
+// authenticated subject in their workspace. Any other key is reported as not found.
+func (h Handlers) queryOwned(ctx context.Context, id string) (apikey.Key, error) {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return apikey.Key{}, validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	k, err := h.APIKey.QueryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, apikey.ErrNotFound) {
+			return apikey.Key{}, validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return apikey.Key{}, fmt.Errorf("querying api key[%s]: %w", id, err)
+	}
+
+	// If you are looking at a key somebody else created.
+	if k.Owner != claims.Subject || k.Workspace != claims.Workspace {
+		return apikey.Key{}, validate.NewRequestError(apikey.ErrNotFound, http.StatusNotFound)
+	}
+
+	return k, nil
+}
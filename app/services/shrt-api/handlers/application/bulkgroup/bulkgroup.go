@@ -0,0 +1,124 @@
+// Package bulkgroup maintains the group of handlers for creating short links in bulk.
+package bulkgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/yashshah7197/shrt/business/core/bulk"
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/foundation/web"
+)
+
+// Handlers manages the set of bulk upload endpoints.
+type Handlers struct {
+	Bulk      *bulk.Core
+	SyncLimit int64
+}
+
+// Create creates a short link for every row of a CSV or NDJSON upload in the workspace of the
+// authenticated subject, owned by them. The format is taken from the Content-Type header, or the
+// format query parameter. Uploads of up to SyncLimit bytes are processed right away and answered
+// with the outcome of every row. Anything larger, an upload of unknown length, or a request with
+// async=true is processed in the background and answered with a job to poll.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	format, err := uploadFormat(r)
+	if err != nil {
+		return validate.NewRequestError(err, http.StatusUnsupportedMediaType)
+	}
+
+	if r.ContentLength < 0 || r.ContentLength > h.SyncLimit || r.URL.Query().Get("async") == "true" {
+		job, err := h.Bulk.Start(ctx, claims.Workspace, claims.Subject, format, r.Body, v.Now)
+		if err != nil {
+			switch {
+			case errors.Is(err, bulk.ErrTooLarge):
+				return validate.NewRequestError(err, http.StatusRequestEntityTooLarge)
+			case errors.Is(err, bulk.ErrShuttingDown):
+				return validate.NewRequestError(err, http.StatusServiceUnavailable)
+			}
+			return fmt.Errorf("starting bulk job: %w", err)
+		}
+
+		w.Header().Set("Location", "/v1/links:bulk/"+job.ID)
+		return web.Respond(ctx, w, job, http.StatusAccepted)
+	}
+
+	job, err := h.Bulk.Run(ctx, claims.Workspace, claims.Subject, format, r.Body, v.Now)
+	if err != nil {
+		if errors.Is(err, bulk.ErrInvalidUpload) {
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		}
+		return fmt.Errorf("running bulk upload: %w", err)
+	}
+
+	return web.Respond(ctx, w, job, http.StatusOK)
+}
+
+// QueryByID returns a single bulk job started in the workspace of the authenticated subject.
+func (h Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	id := web.Param(r, "id")
+	job, err := h.Bulk.QueryByID(ctx, id, v.Now)
+	if err != nil {
+		if errors.Is(err, bulk.ErrNotFound) {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("querying bulk job[%s]: %w", id, err)
+	}
+
+	// If you are looking at a job of another workspace.
+	if job.Workspace != claims.Workspace {
+		return validate.NewRequestError(bulk.ErrNotFound, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, job, http.StatusOK)
+}
+
+// uploadFormat works out the format of an upload from the format query parameter or, failing that,
+// the Content-Type header.
+func uploadFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch format {
+		case bulk.FormatCSV, bulk.FormatNDJSON:
+			return format, nil
+		}
+		return "", fmt.Errorf("unsupported format %q", format)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "", errors.New("expected a text/csv or application/x-ndjson upload")
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return bulk.FormatCSV, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return bulk.FormatNDJSON, nil
+	}
+
+	return "", fmt.Errorf("unsupported content type %q", mediaType)
+}
@@ -0,0 +1,165 @@
+// Package domaingroup maintains the group of handlers for managing the branded domains.
+package domaingroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/yashshah7197/shrt/business/core/brand"
+	"github.com/yashshah7197/shrt/business/core/link"
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/foundation/web"
+)
+
+// Handlers manages the set of branded domain endpoints.
+type Handlers struct {
+	Brand *brand.Core
+	Link  *link.Core
+}
+
+// Create adds a new branded domain to the workspace given in the payload, or to the workspace of
+// the authenticated subject when there is none. Only operators may add domains.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	var nd brand.NewDomain
+	if err := web.Decode(r, &nd); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	workspace := claims.Workspace
+	if nd.Workspace != "" {
+		workspace = nd.Workspace
+	}
+
+	d, err := h.Brand.Create(ctx, nd, workspace, claims.Subject, v.Now)
+	if err != nil {
+		if errors.Is(err, brand.ErrExists) {
+			return validate.NewRequestError(err, http.StatusConflict)
+		}
+		return fmt.Errorf("creating domain[%+v]: %w", nd, err)
+	}
+
+	return web.Respond(ctx, w, d, http.StatusCreated)
+}
+
+// Update modifies the settings of an existing branded domain of any workspace. Only operators may
+// change domains.
+func (h Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	host := web.Param(r, "host")
+
+	var ud brand.UpdateDomain
+	if err := web.Decode(r, &ud); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	d, err := h.Brand.Update(ctx, "", host, ud, v.Now)
+	if err != nil {
+		if errors.Is(err, brand.ErrNotFound) {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("updating domain[%s]: %w", host, err)
+	}
+
+	return web.Respond(ctx, w, d, http.StatusOK)
+}
+
+// Delete removes a branded domain of any workspace. Domains which still have links, including links
+// in the trash, can't be removed. Only operators may remove domains.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	host := brand.Normalize(web.Param(r, "host"))
+	d, err := h.Brand.QueryByHost(ctx, "", host)
+	if err != nil {
+		if errors.Is(err, brand.ErrNotFound) {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("querying domain[%s]: %w", host, err)
+	}
+
+	filter := link.QueryFilter{
+		Host: host,
+		Now:  v.Now,
+	}
+	links, _, err := h.Link.Query(ctx, filter, nil, 1)
+	if err != nil {
+		return fmt.Errorf("querying links on domain[%s]: %w", host, err)
+	}
+	if len(links) > 0 {
+		return validate.NewRequestError(brand.ErrInUse, http.StatusConflict)
+	}
+
+	if err := h.Brand.Delete(ctx, d.Workspace, host); err != nil {
+		if errors.Is(err, brand.ErrNotFound) {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("deleting domain[%s]: %w", host, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// QueryByHost returns a single branded domain in the workspace of the authenticated subject.
+func (h Handlers) QueryByHost(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	d, err := h.queryOwned(ctx, web.Param(r, "host"))
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, d, http.StatusOK)
+}
+
+// Query returns every branded domain in the workspace of the authenticated subject.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	domains, err := h.Brand.QueryByWorkspace(ctx, claims.Workspace)
+	if err != nil {
+		return fmt.Errorf("querying domains for workspace[%s]: %w", claims.Workspace, err)
+	}
+
+	return web.Respond(ctx, w, domains, http.StatusOK)
+}
+
+// queryOwned fetches the branded domain identified by the given host and ensures that it belongs to
+// the workspace of the authenticated subject. Domains of other workspaces are reported as not
+// found.
+func (h Handlers) queryOwned(ctx context.Context, host string) (brand.Domain, error) {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return brand.Domain{}, validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	d, err := h.Brand.QueryByHost(ctx, claims.Workspace, host)
+	if err != nil {
+		if errors.Is(err, brand.ErrNotFound) {
+			return brand.Domain{}, validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return brand.Domain{}, fmt.Errorf("querying domain[%s]: %w", host, err)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,159 @@
+// Package foldergroup maintains the group of handlers for folder access.
+package foldergroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/yashshah7197/shrt/business/core/folder"
+	"github.com/yashshah7197/shrt/business/core/link"
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/foundation/web"
+)
+
+// Handlers manages the set of folder endpoints.
+type Handlers struct {
+	Folder *folder.Core
+	Link   *link.Core
+}
+
+// Create adds a new folder to the workspace of the authenticated subject.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	var nf folder.NewFolder
+	if err := web.Decode(r, &nf); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	f, err := h.Folder.Create(ctx, nf, claims.Workspace, claims.Subject, v.Now)
+	if err != nil {
+		return fmt.Errorf("creating folder[%+v]: %w", nf, err)
+	}
+
+	return web.Respond(ctx, w, f, http.StatusCreated)
+}
+
+// Update renames or moves an existing folder in the workspace of the authenticated subject.
+func (h Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	existing, err := h.queryOwned(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var uf folder.UpdateFolder
+	if err := web.Decode(r, &uf); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	f, err := h.Folder.Update(ctx, existing.Workspace, id, uf, v.Now)
+	if err != nil {
+		if errors.Is(err, folder.ErrNotFound) {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("updating folder[%s]: %w", id, err)
+	}
+
+	return web.Respond(ctx, w, f, http.StatusOK)
+}
+
+// Delete removes an existing folder in the workspace of the authenticated subject. Only folders
+// without any subfolders or links, including links in the trash, can be removed.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	f, err := h.queryOwned(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	filter := link.QueryFilter{
+		Workspace: f.Workspace,
+		FolderIDs: []string{id},
+		Now:       v.Now,
+	}
+	links, _, err := h.Link.Query(ctx, filter, nil, 1)
+	if err != nil {
+		return fmt.Errorf("querying links in folder[%s]: %w", id, err)
+	}
+	if len(links) > 0 {
+		return validate.NewRequestError(folder.ErrNotEmpty, http.StatusConflict)
+	}
+
+	if err := h.Folder.Delete(ctx, f.Workspace, id); err != nil {
+		switch {
+		case errors.Is(err, folder.ErrNotFound):
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, folder.ErrNotEmpty):
+			return validate.NewRequestError(err, http.StatusConflict)
+		}
+		return fmt.Errorf("deleting folder[%s]: %w", id, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Query returns every folder in the workspace of the authenticated subject, ordered by path.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	folders, err := h.Folder.QueryByWorkspace(ctx, claims.Workspace)
+	if err != nil {
+		return fmt.Errorf("querying folders for workspace[%s]: %w", claims.Workspace, err)
+	}
+
+	return web.Respond(ctx, w, folders, http.StatusOK)
+}
+
+// QueryByID returns a single folder in the workspace of the authenticated subject.
+func (h Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	f, err := h.queryOwned(ctx, web.Param(r, "id"))
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, f, http.StatusOK)
+}
+
+// queryOwned fetches the folder identified by the given ID and ensures that it belongs to the
+// workspace of the authenticated subject. Folders of other workspaces are reported as not found.
+func (h Handlers) queryOwned(ctx context.Context, id string) (folder.Folder, error) {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return folder.Folder{}, validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	f, err := h.Folder.QueryByID(ctx, claims.Workspace, id)
+	if err != nil {
+		if errors.Is(err, folder.ErrNotFound) {
+			return folder.Folder{}, validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return folder.Folder{}, fmt.Errorf("querying folder[%s]: %w", id, err)
+	}
+
+	return f, nil
+}
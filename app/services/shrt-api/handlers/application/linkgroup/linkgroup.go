@@ -0,0 +1,732 @@
+// Package linkgroup maintains the group of handlers for short link access.
+package linkgroup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/color"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/brand"
+	"github.com/yashshah7197/shrt/business/core/click"
+	"github.com/yashshah7197/shrt/business/core/folder"
+	"github.com/yashshah7197/shrt/business/core/link"
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/foundation/qrcode"
+	"github.com/yashshah7197/shrt/foundation/web"
+)
+
+// The bounds on the number of links in a page of a query.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// The bounds on the number of results of a search.
+const (
+	defaultSearchSize = 20
+	maxSearchSize     = 100
+)
+
+// The defaults and bounds of a rendered QR code. The size is in pixels and the margin in modules.
+const (
+	defaultQRSize   = 512
+	minQRSize       = 64
+	maxQRSize       = 4096
+	defaultQRMargin = 4
+	maxQRMargin     = 16
+)
+
+// Handlers manages the set of short link endpoints. Links on a branded domain are addressed with
+// the host query parameter, and links in the default namespace without it. Short URLs are built on
+// the branded domain of a link, or else on the base URL, or on the host the request came in on
+// when there is none.
+type Handlers struct {
+	Link    *link.Core
+	Click   *click.Core
+	Folder  *folder.Core
+	BaseURL string
+}
+
+// Create adds a new short link to the workspace of the authenticated subject, owned by them.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	var nl link.NewLink
+	if err := web.Decode(r, &nl); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	lnk, err := h.Link.Create(ctx, nl, claims.Workspace, claims.Subject, v.Now)
+	if err != nil {
+		if errors.Is(err, link.ErrCodeExhausted) {
+			return validate.NewRequestError(err, http.StatusServiceUnavailable)
+		}
+		return fmt.Errorf("creating link[%+v]: %w", nl, err)
+	}
+
+	return web.Respond(ctx, w, lnk, http.StatusCreated)
+}
+
+// Update modifies an existing short link in the workspace of the authenticated subject.
+func (h Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	host, code := hostParam(r), web.Param(r, "code")
+	if _, err := h.queryOwned(ctx, host, code); err != nil {
+		return err
+	}
+
+	var ul link.UpdateLink
+	if err := web.Decode(r, &ul); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	lnk, err := h.Link.Update(ctx, claims.Workspace, host, code, ul, claims.Subject, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, link.ErrNotFound):
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, link.ErrDeleted):
+			return validate.NewRequestError(err, http.StatusConflict)
+		}
+		return fmt.Errorf("updating link[%s]: %w", link.Key(host, code), err)
+	}
+
+	return web.Respond(ctx, w, lnk, http.StatusOK)
+}
+
+// QueryRevisions returns the revision history of a single short link in the workspace of the
+// authenticated subject, newest first.
+func (h Handlers) QueryRevisions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	host, code := hostParam(r), web.Param(r, "code")
+	lnk, err := h.queryOwned(ctx, host, code)
+	if err != nil {
+		return err
+	}
+
+	revs, err := h.Link.QueryRevisions(ctx, lnk.Workspace, host, code)
+	if err != nil {
+		if errors.Is(err, link.ErrNotFound) {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("querying revisions of link[%s]: %w", link.Key(host, code), err)
+	}
+
+	return web.Respond(ctx, w, revs, http.StatusOK)
+}
+
+// Rollback restores a short link in the workspace of the authenticated subject to the settings
+// recorded in one of its revisions.
+func (h Handlers) Rollback(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	host, code := hostParam(r), web.Param(r, "code")
+	if _, err := h.queryOwned(ctx, host, code); err != nil {
+		return err
+	}
+
+	number, err := strconv.Atoi(web.Param(r, "revision"))
+	if err != nil || number < 1 {
+		return validate.NewRequestError(link.ErrNoRevision, http.StatusNotFound)
+	}
+
+	lnk, err := h.Link.Rollback(ctx, claims.Workspace, host, code, number, claims.Subject, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, link.ErrNotFound), errors.Is(err, link.ErrNoRevision):
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, link.ErrDeleted):
+			return validate.NewRequestError(err, http.StatusConflict)
+		}
+		return fmt.Errorf("rolling back link[%s] to revision[%d]: %w", link.Key(host, code), number, err)
+	}
+
+	return web.Respond(ctx, w, lnk, http.StatusOK)
+}
+
+// Delete moves an existing short link in the workspace of the authenticated subject to the trash.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	host, code := hostParam(r), web.Param(r, "code")
+	if _, err := h.queryOwned(ctx, host, code); err != nil {
+		return err
+	}
+
+	if err := h.Link.Delete(ctx, claims.Workspace, host, code, claims.Subject, v.Now); err != nil {
+		if errors.Is(err, link.ErrNotFound) {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("deleting link[%s]: %w", link.Key(host, code), err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Restore takes a short link in the workspace of the authenticated subject back out of the trash.
+func (h Handlers) Restore(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	host, code := hostParam(r), web.Param(r, "code")
+	if _, err := h.queryOwned(ctx, host, code); err != nil {
+		return err
+	}
+
+	lnk, err := h.Link.Restore(ctx, claims.Workspace, host, code, claims.Subject, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, link.ErrNotFound):
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, link.ErrNotDeleted):
+			return validate.NewRequestError(err, http.StatusConflict)
+		case errors.Is(err, link.ErrPastRestore):
+			return validate.NewRequestError(err, http.StatusGone)
+		}
+		return fmt.Errorf("restoring link[%s]: %w", link.Key(host, code), err)
+	}
+
+	return web.Respond(ctx, w, lnk, http.StatusOK)
+}
+
+// Query returns a page of the short links in the workspace of the authenticated subject. The links
+// are filtered by the owner, tag, folder, host, created_from, created_to, domain, state and deleted
+// query parameters. The tag parameter may be repeated, and subfolders=true takes in every folder
+// below the folder as well. The links in the trash are returned instead when deleted is true. The
+// page size is taken from the limit parameter, and the next page, if any, is linked from the Link
+// header.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	filter, after, limit, err := h.parseQuery(ctx, r, claims.Workspace, v.Now)
+	if err != nil {
+		return err
+	}
+
+	links, next, err := h.Link.Query(ctx, filter, after, limit)
+	if err != nil {
+		return fmt.Errorf("querying links for workspace[%s]: %w", claims.Workspace, err)
+	}
+
+	if next != nil {
+		values := r.URL.Query()
+		values.Set("cursor", next.String())
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, values.Encode()))
+	}
+
+	return web.Respond(ctx, w, links, http.StatusOK)
+}
+
+// Search finds the links matching the q query parameter by their code, title, notes or destination,
+// best match first, with the matching words of each field highlighted. Only the links in the
+// workspace of the authenticated subject are searched. The number of results is taken from the
+// limit parameter.
+func (h Handlers) Search(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	values := r.URL.Query()
+	limit := defaultSearchSize
+	if raw := values.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSearchSize {
+			var fields validate.FieldErrors
+			fields.Add("limit", fmt.Sprintf("must be a number between 1 and %d", maxSearchSize))
+			return fields.Err()
+		}
+		limit = n
+	}
+
+	results, err := h.Link.Search(ctx, values.Get("q"), claims.Workspace, limit)
+	if err != nil {
+		return fmt.Errorf("searching links for workspace[%s]: %w", claims.Workspace, err)
+	}
+
+	return web.Respond(ctx, w, results, http.StatusOK)
+}
+
+// QueryTags returns every tag in use in the workspace of the authenticated subject along with how
+// many of its links carry it.
+func (h Handlers) QueryTags(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	tags, err := h.Link.QueryTags(ctx, claims.Workspace)
+	if err != nil {
+		return fmt.Errorf("querying tags for workspace[%s]: %w", claims.Workspace, err)
+	}
+
+	return web.Respond(ctx, w, tags, http.StatusOK)
+}
+
+// RenameTag renames a tag across every link in the workspace of the authenticated subject.
+func (h Handlers) RenameTag(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	var rt link.RenameTag
+	if err := web.Decode(r, &rt); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	tag := web.Param(r, "tag")
+	changed, err := h.Link.RenameTag(ctx, claims.Workspace, tag, rt, claims.Subject, v.Now)
+	if err != nil {
+		return fmt.Errorf("renaming tag[%s] for workspace[%s]: %w", tag, claims.Workspace, err)
+	}
+
+	return web.Respond(ctx, w, retagResult{Links: changed}, http.StatusOK)
+}
+
+// MergeTags folds a set of tags into a single one across every link in the workspace of the
+// authenticated subject.
+func (h Handlers) MergeTags(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	var mt link.MergeTags
+	if err := web.Decode(r, &mt); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	changed, err := h.Link.MergeTags(ctx, claims.Workspace, mt, claims.Subject, v.Now)
+	if err != nil {
+		return fmt.Errorf("merging tags[%+v] for workspace[%s]: %w", mt, claims.Workspace, err)
+	}
+
+	return web.Respond(ctx, w, retagResult{Links: changed}, http.StatusOK)
+}
+
+// retagResult reports how many links a tag rename or merge changed.
+type retagResult struct {
+	Links int `json:"links"`
+}
+
+// QueryByCode returns a single short link in the workspace of the authenticated subject.
+func (h Handlers) QueryByCode(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	lnk, err := h.queryOwned(ctx, hostParam(r), web.Param(r, "code"))
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, lnk, http.StatusOK)
+}
+
+// Stats returns the click statistics of a single short link in the workspace of the authenticated
+// subject. Only the owner of the link or an owner of the workspace may see them. The window and
+// shape of the statistics are taken from the interval, tz, from, to and top query parameters.
+func (h Handlers) Stats(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	host, code := hostParam(r), web.Param(r, "code")
+	lnk, err := h.queryOwned(ctx, host, code)
+	if err != nil {
+		return err
+	}
+
+	// If you are not the owner of the link or of the workspace.
+	if !claims.Authorized(auth.RoleOwner) && claims.Subject != lnk.Owner {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	sq, err := parseStatsQuery(r)
+	if err != nil {
+		return err
+	}
+
+	stats, err := h.Click.Stats(ctx, host, code, sq, v.Now)
+	if err != nil {
+		return fmt.Errorf("querying stats for link[%s]: %w", link.Key(host, code), err)
+	}
+
+	return web.Respond(ctx, w, stats, http.StatusOK)
+}
+
+// QR renders a QR code of the short URL of a single short link in the workspace of the
+// authenticated subject. The format query parameter picks png or svg, size sets the width in
+// pixels, margin the quiet zone in modules, ecc the error correction level out of L, M, Q and H, and
+// fg and bg the colours as hex. The code only depends on the short URL and the parameters, so it is
+// served with an ETag.
+func (h Handlers) QR(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	lnk, err := h.queryOwned(ctx, hostParam(r), web.Param(r, "code"))
+	if err != nil {
+		return err
+	}
+
+	q, err := parseQRQuery(r)
+	if err != nil {
+		return err
+	}
+
+	target := h.shortURL(r, lnk)
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%d|%s|%v|%v", target, q.format, q.style.Size, q.style.Margin, q.level, q.style.Foreground, q.style.Background)))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if matchETag(r.Header.Get("If-None-Match"), etag) {
+		web.SetStatusCode(ctx, http.StatusNotModified)
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	code, err := qrcode.Encode([]byte(target), q.level)
+	if err != nil {
+		return fmt.Errorf("encoding qr code for link[%s]: %w", lnk.Key(), err)
+	}
+
+	if q.style.Size < code.Modules(q.style) {
+		var fields validate.FieldErrors
+		fields.Add("size", fmt.Sprintf("must be at least %d for this code", code.Modules(q.style)))
+		return fields.Err()
+	}
+
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if q.format == "svg" {
+		contentType = "image/svg+xml"
+		err = code.WriteSVG(&buf, q.style)
+	} else {
+		err = code.WritePNG(&buf, q.style)
+	}
+	if err != nil {
+		return fmt.Errorf("rendering qr code for link[%s]: %w", lnk.Key(), err)
+	}
+
+	return web.RespondRaw(ctx, w, contentType, buf.Bytes(), http.StatusOK)
+}
+
+// qrQuery holds how a QR code is to be rendered.
+type qrQuery struct {
+	format string
+	level  qrcode.Level
+	style  qrcode.Style
+}
+
+// parseQRQuery reads how a QR code is to be rendered from the query parameters of the request.
+func parseQRQuery(r *http.Request) (qrQuery, error) {
+	values := r.URL.Query()
+	q := qrQuery{
+		format: "png",
+		level:  qrcode.Medium,
+		style: qrcode.Style{
+			Size:       defaultQRSize,
+			Margin:     defaultQRMargin,
+			Foreground: color.NRGBA{A: 0xFF},
+			Background: color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+		},
+	}
+
+	var fields validate.FieldErrors
+
+	if format := values.Get("format"); format != "" {
+		q.format = strings.ToLower(format)
+		if q.format != "png" && q.format != "svg" {
+			fields.Add("format", "must be one of png or svg")
+		}
+	}
+
+	if raw := values.Get("size"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < minQRSize || n > maxQRSize {
+			fields.Add("size", fmt.Sprintf("must be a number between %d and %d", minQRSize, maxQRSize))
+		}
+		q.style.Size = n
+	}
+
+	if raw := values.Get("margin"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > maxQRMargin {
+			fields.Add("margin", fmt.Sprintf("must be a number between 0 and %d", maxQRMargin))
+		}
+		q.style.Margin = n
+	}
+
+	if raw := values.Get("ecc"); raw != "" {
+		level, err := qrcode.ParseLevel(raw)
+		if err != nil {
+			fields.Add("ecc", "must be one of L, M, Q or H")
+		}
+		q.level = level
+	}
+
+	if raw := values.Get("fg"); raw != "" {
+		c, err := qrcode.ParseColor(raw)
+		if err != nil {
+			fields.Add("fg", "must be a hex colour")
+		}
+		q.style.Foreground = c
+	}
+
+	if raw := values.Get("bg"); raw != "" {
+		c, err := qrcode.ParseColor(raw)
+		if err != nil {
+			fields.Add("bg", "must be a hex colour")
+		}
+		q.style.Background = c
+	}
+
+	if q.style.Foreground == q.style.Background {
+		fields.Add("fg", "must differ from bg")
+	}
+
+	return q, fields.Err()
+}
+
+// shortURL returns the short URL of the given link. Links on a branded domain are served on that
+// domain, over the same scheme as the base URL.
+func (h Handlers) shortURL(r *http.Request, lnk link.Link) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	base := h.BaseURL
+	if i := strings.Index(base, "://"); i >= 0 {
+		scheme = base[:i]
+	}
+
+	switch {
+	case lnk.Host != "":
+		base = scheme + "://" + lnk.Host
+	case base == "":
+		base = scheme + "://" + r.Host
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + lnk.Code
+}
+
+// matchETag reports whether the If-None-Match header lists the given entity tag. Weak comparison
+// is used, as it is for every conditional GET.
+func matchETag(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseStatsQuery reads the statistics query from the query parameters of the request.
+func parseStatsQuery(r *http.Request) (click.StatsQuery, error) {
+	values := r.URL.Query()
+	sq := click.StatsQuery{
+		Interval: values.Get("interval"),
+	}
+
+	var fields validate.FieldErrors
+
+	if tz := values.Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			fields.Add("tz", "must be an IANA time zone name")
+		}
+		sq.Location = loc
+	}
+
+	if from := values.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			fields.Add("from", "must be an RFC 3339 timestamp")
+		}
+		sq.From = t
+	}
+
+	if to := values.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			fields.Add("to", "must be an RFC 3339 timestamp")
+		}
+		sq.To = t
+	}
+
+	if top := values.Get("top"); top != "" {
+		n, err := strconv.Atoi(top)
+		if err != nil || n < 1 {
+			fields.Add("top", "must be a positive number")
+		}
+		sq.Top = n
+	}
+
+	return sq, fields.Err()
+}
+
+// parseQuery reads the filter, cursor and page size of a query of the links of the given workspace
+// from the query parameters of the request.
+func (h Handlers) parseQuery(ctx context.Context, r *http.Request, workspace string, now time.Time) (link.QueryFilter, *link.Cursor, int, error) {
+	values := r.URL.Query()
+	deleted := values.Get("deleted") == "true"
+	filter := link.QueryFilter{
+		Workspace: workspace,
+		Owner:     values.Get("owner"),
+		Tags:      values["tag"],
+		Host:      hostParam(r),
+		Domain:    strings.ToLower(values.Get("domain")),
+		State:     values.Get("state"),
+		Deleted:   &deleted,
+		Now:       now,
+	}
+
+	var fields validate.FieldErrors
+
+	if id := values.Get("folder"); id != "" {
+		filter.FolderIDs = []string{id}
+		if values.Get("subfolders") == "true" {
+			ids, err := h.Folder.Subtree(ctx, workspace, id)
+			switch {
+			case errors.Is(err, folder.ErrNotFound):
+			case err != nil:
+				return link.QueryFilter{}, nil, 0, fmt.Errorf("querying subfolders of folder[%s]: %w", id, err)
+			default:
+				filter.FolderIDs = ids
+			}
+		}
+	}
+
+	if from := values.Get("created_from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			fields.Add("created_from", "must be an RFC 3339 timestamp")
+		}
+		filter.CreatedFrom = &t
+	}
+
+	if to := values.Get("created_to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			fields.Add("created_to", "must be an RFC 3339 timestamp")
+		}
+		filter.CreatedTo = &t
+	}
+
+	var after *link.Cursor
+	if cursor := values.Get("cursor"); cursor != "" {
+		c, err := link.ParseCursor(cursor)
+		if err != nil {
+			fields.Add("cursor", err.Error())
+		}
+		after = &c
+	}
+
+	limit := defaultPageSize
+	if raw := values.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxPageSize {
+			fields.Add("limit", fmt.Sprintf("must be a number between 1 and %d", maxPageSize))
+		}
+		limit = n
+	}
+
+	return filter, after, limit, fields.Err()
+}
+
+// hostParam returns the host a link is addressed on by the request, which is blank for the default
+// namespace.
+func hostParam(r *http.Request) string {
+	return brand.Normalize(r.URL.Query().Get("host"))
+}
+
+// queryOwned fetches the short link identified by the given host and code and ensures that it
+// belongs to the workspace of the authenticated subject. Links of other workspaces are reported as
+// not found, so their codes can't be probed.
+func (h Handlers) queryOwned(ctx context.Context, host string, code string) (link.Link, error) {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return link.Link{}, validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	lnk, err := h.Link.QueryByCode(ctx, claims.Workspace, host, code)
+	if err != nil {
+		if errors.Is(err, link.ErrNotFound) {
+			return link.Link{}, validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return link.Link{}, fmt.Errorf("querying link[%s]: %w", link.Key(host, code), err)
+	}
+
+	return lnk, nil
+}
@@ -0,0 +1,71 @@
+// Package policygroup maintains the group of handlers for managing the blocklist of domains.
+package policygroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/yashshah7197/shrt/business/core/policy"
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/foundation/web"
+)
+
+// Handlers manages the set of blocklist endpoints.
+type Handlers struct {
+	Policy *policy.Core
+}
+
+// Create adds a new domain to the blocklist.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	var nbd policy.NewBlockedDomain
+	if err := web.Decode(r, &nbd); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	bd, err := h.Policy.CreateBlocked(ctx, nbd, claims.Subject, v.Now)
+	if err != nil {
+		if errors.Is(err, policy.ErrExists) {
+			return validate.NewRequestError(err, http.StatusConflict)
+		}
+		return fmt.Errorf("creating blocked domain[%+v]: %w", nbd, err)
+	}
+
+	return web.Respond(ctx, w, bd, http.StatusCreated)
+}
+
+// Delete removes a domain from the blocklist.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	pattern := web.Param(r, "pattern")
+
+	if err := h.Policy.DeleteBlocked(ctx, pattern); err != nil {
+		if errors.Is(err, policy.ErrNotFound) {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("deleting blocked domain[%s]: %w", pattern, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Query returns every domain on the blocklist.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	domains, err := h.Policy.QueryBlocked(ctx)
+	if err != nil {
+		return fmt.Errorf("querying blocked domains: %w", err)
+	}
+
+	return web.Respond(ctx, w, domains, http.StatusOK)
+}
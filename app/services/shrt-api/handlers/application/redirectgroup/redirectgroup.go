@@ -0,0 +1,320 @@
+// Package redirectgroup maintains the group of handlers for resolving short links.
+package redirectgroup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/yashshah7197/shrt/business/core/brand"
+	"github.com/yashshah7197/shrt/business/core/click"
+	"github.com/yashshah7197/shrt/business/core/link"
+	"github.com/yashshah7197/shrt/business/sys/access"
+	"github.com/yashshah7197/shrt/foundation/ratelimit"
+	"github.com/yashshah7197/shrt/foundation/realip"
+	"github.com/yashshah7197/shrt/foundation/web"
+)
+
+// notFoundPage is served to visitors who follow a short link that does not exist.
+const notFoundPage = `<!DOCTYPE html>
+<html>
+<head><title>Link not found</title></head>
+<body><h1>Link not found</h1><p>The short link you followed does not exist.</p></body>
+</html>
+`
+
+// gonePage is served to visitors who follow a short link outside of its activation window or after
+// its click budget has been used up, when the link has no fallback URL.
+const gonePage = `<!DOCTYPE html>
+<html>
+<head><title>Link unavailable</title></head>
+<body><h1>Link unavailable</h1><p>The short link you followed is not available anymore.</p></body>
+</html>
+`
+
+// removedPage is served to visitors who follow a short link which its owner has deleted, unless a
+// page of our own to send them to is configured.
+const removedPage = `<!DOCTYPE html>
+<html>
+<head><title>Link removed</title></head>
+<body><h1>Link removed</h1><p>The short link you followed has been removed by its owner.</p></body>
+</html>
+`
+
+// warningPage is served to visitors who follow a short link which has been flagged for pointing at
+// a threat. The destination is deliberately left out, so that it can't be followed from the page.
+var warningPage = template.Must(template.New("warning").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Dangerous link</title></head>
+<body>
+<h1>Dangerous link</h1>
+<p>The short link you followed has been disabled because it leads to a site reported for
+{{range $i, $t := .Threats}}{{if $i}}, {{end}}{{$t}}{{else}}harmful content{{end}}.</p>
+<p>Visiting that site could harm your device or put your personal information at risk.</p>
+</body>
+</html>
+`))
+
+// passwordPage is served to visitors who follow a password protected short link without a pass.
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Password required</title></head>
+<body>
+<h1>Password required</h1>
+<p>The short link you followed is protected by a password.</p>
+{{if .Message}}<p><strong>{{.Message}}</strong></p>{{end}}
+<form method="post" action="{{.Action}}">
+<input type="password" name="password" autofocus required>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// maxPasswordForm bounds the size of a submitted password form.
+const maxPasswordForm = 4 << 10
+
+// Handlers manages the set of redirect endpoints. Requests which come in on a branded domain
+// resolve codes in the namespace of that domain, and every other request resolves them in the
+// default namespace.
+type Handlers struct {
+	Link           *link.Core
+	Brand          *brand.Core
+	RedirectStatus int
+	RemovedURL     string
+	Gate           *access.Gate
+	Limiter        *ratelimit.Limiter
+	Clicks         *click.Recorder
+	RealIP         *realip.Resolver
+}
+
+// Redirect resolves a short code and redirects the visitor to its destination. Since the client is
+// usually a browser, links which can't be followed are answered with a page rather than a JSON
+// error. Only GET requests spend a click from the budget of a link and are recorded as clicks.
+func (h Handlers) Redirect(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	d, err := h.domain(ctx, r)
+	if err != nil {
+		return err
+	}
+	host, code := d.Host, web.Param(r, "code")
+
+	lnk, err := h.Link.QueryByCode(ctx, "", host, code)
+	if err == nil && lnk.Deleted() {
+		err = link.ErrDeleted
+	}
+	if err == nil && lnk.Flagged() {
+		err = link.ErrFlagged
+	}
+	if err == nil && !lnk.Active(v.Now) {
+		err = link.ErrInactive
+	}
+
+	if err == nil && lnk.Protected {
+		if err := h.Gate.Check(r, host, code, v.Now); err != nil {
+			if !errors.Is(err, access.ErrNoPass) {
+				return fmt.Errorf("checking pass for link[%s]: %w", link.Key(host, code), err)
+			}
+			return h.passwordPrompt(ctx, w, code, "", http.StatusOK)
+		}
+	}
+
+	if err == nil && r.Method != http.MethodHead {
+		lnk, err = h.Link.Visit(ctx, lnk, v.Now)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, link.ErrNotFound):
+			return h.notFound(ctx, w, r, d)
+
+		case errors.Is(err, link.ErrDeleted):
+			return h.removed(ctx, w, r)
+
+		case errors.Is(err, link.ErrFlagged):
+			return h.warning(ctx, w, lnk)
+
+		case errors.Is(err, link.ErrInactive):
+			if lnk.FallbackURL != "" {
+				return web.Redirect(ctx, w, r, lnk.FallbackURL, http.StatusFound)
+			}
+			return web.RespondRaw(ctx, w, "text/html; charset=utf-8", []byte(gonePage), http.StatusGone)
+
+		default:
+			return fmt.Errorf("resolving link[%s]: %w", link.Key(host, code), err)
+		}
+	}
+
+	// Fall back to the default of the domain, and then of the service, if the link doesn't specify
+	// its own status.
+	statusCode := lnk.RedirectStatus
+	if statusCode == 0 {
+		statusCode = d.RedirectStatus
+	}
+	if statusCode == 0 {
+		statusCode = h.RedirectStatus
+	}
+
+	// Hand the click off to be stored in the background so the visitor isn't kept waiting.
+	if r.Method != http.MethodHead {
+		h.Clicks.Record(ctx, click.Event{
+			Code:       lnk.Code,
+			Host:       lnk.Host,
+			OccurredAt: v.Now,
+			Referrer:   r.Referer(),
+			UserAgent:  r.UserAgent(),
+			ClientIP:   h.clientIP(r),
+			TraceID:    v.TraceID,
+		})
+	}
+
+	return web.Redirect(ctx, w, r, lnk.Destination, statusCode)
+}
+
+// Unlock checks the password submitted for a protected short link. A visitor who gets it right is
+// handed a short-lived pass and sent back to the short link, which now lets them through. Attempts
+// are limited per link and client address until one succeeds, to slow down guessing.
+func (h Handlers) Unlock(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	d, err := h.domain(ctx, r)
+	if err != nil {
+		return err
+	}
+	host, code := d.Host, web.Param(r, "code")
+	key := link.Key(host, code) + "|" + h.clientIP(r)
+
+	// Every attempt is counted before the password is checked, and the count is only cleared once
+	// the right one is given.
+	if !h.Limiter.Take(key, v.Now) {
+		return h.passwordPrompt(ctx, w, code, "Too many incorrect attempts. Please try again later.", http.StatusTooManyRequests)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasswordForm)
+	if err := r.ParseForm(); err != nil {
+		return h.passwordPrompt(ctx, w, code, "The submitted form could not be read.", http.StatusBadRequest)
+	}
+
+	lnk, err := h.Link.Unlock(ctx, host, code, r.PostForm.Get("password"))
+	if err != nil {
+		switch {
+		case errors.Is(err, link.ErrNotFound):
+			return h.notFound(ctx, w, r, d)
+
+		case errors.Is(err, link.ErrDeleted):
+			return h.removed(ctx, w, r)
+
+		case errors.Is(err, link.ErrWrongPassword):
+			return h.passwordPrompt(ctx, w, code, "Incorrect password.", http.StatusUnauthorized)
+
+		default:
+			return fmt.Errorf("unlocking link[%s]: %w", link.Key(host, code), err)
+		}
+	}
+	h.Limiter.Reset(key)
+
+	if lnk.Protected {
+		cookie, err := h.Gate.Issue(r, host, code, v.Now)
+		if err != nil {
+			return fmt.Errorf("issuing pass for link[%s]: %w", link.Key(host, code), err)
+		}
+		http.SetCookie(w, cookie)
+	}
+
+	return web.Redirect(ctx, w, r, "/"+url.PathEscape(code), http.StatusSeeOther)
+}
+
+// domain returns the branded domain the request came in on. Requests on any other host get the zero
+// domain, whose blank host is the default namespace.
+func (h Handlers) domain(ctx context.Context, r *http.Request) (brand.Domain, error) {
+	d, err := h.Brand.QueryByHost(ctx, "", r.Host)
+	if err != nil {
+		if errors.Is(err, brand.ErrNotFound) {
+			return brand.Domain{}, nil
+		}
+		return brand.Domain{}, fmt.Errorf("querying domain[%s]: %w", r.Host, err)
+	}
+
+	return d, nil
+}
+
+// notFound tells the visitor that the short link they followed does not exist, either by sending
+// them to the not found page of the domain or by serving the built in one.
+func (h Handlers) notFound(ctx context.Context, w http.ResponseWriter, r *http.Request, d brand.Domain) error {
+	if d.NotFoundURL != "" {
+		return web.Redirect(ctx, w, r, d.NotFoundURL, http.StatusFound)
+	}
+
+	return web.RespondRaw(ctx, w, "text/html; charset=utf-8", []byte(notFoundPage), http.StatusNotFound)
+}
+
+// removed tells the visitor that the short link they followed was deleted, either by sending them
+// to the configured removed page or by serving the built in one.
+func (h Handlers) removed(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if h.RemovedURL != "" {
+		return web.Redirect(ctx, w, r, h.RemovedURL, http.StatusFound)
+	}
+
+	return web.RespondRaw(ctx, w, "text/html; charset=utf-8", []byte(removedPage), http.StatusGone)
+}
+
+// warning serves the warning page for a flagged short link.
+func (h Handlers) warning(ctx context.Context, w http.ResponseWriter, lnk link.Link) error {
+	data := struct {
+		Threats []string
+	}{
+		Threats: lnk.Threats,
+	}
+
+	var buf bytes.Buffer
+	if err := warningPage.Execute(&buf, data); err != nil {
+		return fmt.Errorf("rendering warning page: %w", err)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	return web.RespondRaw(ctx, w, "text/html; charset=utf-8", buf.Bytes(), http.StatusForbidden)
+}
+
+// passwordPrompt serves the password page for the short link identified by the given code, along
+// with an optional message for the visitor.
+func (h Handlers) passwordPrompt(ctx context.Context, w http.ResponseWriter, code string, message string, statusCode int) error {
+	data := struct {
+		Action  string
+		Message string
+	}{
+		Action:  "/" + url.PathEscape(code),
+		Message: message,
+	}
+
+	var buf bytes.Buffer
+	if err := passwordPage.Execute(&buf, data); err != nil {
+		return fmt.Errorf("rendering password page: %w", err)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	return web.RespondRaw(ctx, w, "text/html; charset=utf-8", buf.Bytes(), statusCode)
+}
+
+// clientIP returns the address of the client which sent the request, or a blank string if it
+// can't be told.
+func (h Handlers) clientIP(r *http.Request) string {
+	ip := h.RealIP.ClientIP(r)
+	if ip == nil {
+		return ""
+	}
+
+	return ip.String()
+}
@@ -0,0 +1,75 @@
+// Package reservedgroup maintains the group of handlers for managing reserved and blocked words.
+package reservedgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/yashshah7197/shrt/business/core/reserved"
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/foundation/web"
+)
+
+// Handlers manages the set of registry endpoints.
+type Handlers struct {
+	Reserved *reserved.Core
+}
+
+// Create adds a new reserved or blocked word to the registry.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	var nw reserved.NewWord
+	if err := web.Decode(r, &nw); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	word, err := h.Reserved.Create(ctx, nw, claims.Subject, v.Now)
+	if err != nil {
+		if errors.Is(err, reserved.ErrExists) {
+			return validate.NewRequestError(err, http.StatusConflict)
+		}
+		return fmt.Errorf("creating word[%+v]: %w", nw, err)
+	}
+
+	return web.Respond(ctx, w, word, http.StatusCreated)
+}
+
+// Delete removes a word from the registry.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	word := web.Param(r, "word")
+
+	if err := h.Reserved.Delete(ctx, word); err != nil {
+		switch {
+		case errors.Is(err, reserved.ErrNotFound):
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, reserved.ErrSystem):
+			return validate.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("deleting word[%s]: %w", word, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Query returns every word in the registry.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	words, err := h.Reserved.Query(ctx)
+	if err != nil {
+		return fmt.Errorf("querying words: %w", err)
+	}
+
+	return web.Respond(ctx, w, words, http.StatusOK)
+}
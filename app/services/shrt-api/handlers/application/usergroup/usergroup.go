@@ -0,0 +1,261 @@
+// Package usergroup maintains the group of handlers for user accounts, signing up and signing in.
+package usergroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yashshah7197/shrt/business/core/session"
+	"github.com/yashshah7197/shrt/business/core/user"
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/foundation/web"
+)
+
+// issuer identifies the service as the issuer of the tokens it hands out.
+const issuer = "shrt-api"
+
+// Handlers manages the set of user endpoints. Access tokens handed out on signing in stay valid for
+// the token TTL, after which they are refreshed with the refresh token handed out along with them.
+type Handlers struct {
+	User     *user.Core
+	Session  *session.Core
+	Auth     *auth.Auth
+	TokenTTL time.Duration
+}
+
+// Signup creates a new user along with a new workspace for them to own.
+func (h Handlers) Signup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var ns user.NewSignup
+	if err := web.Decode(r, &ns); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	u, err := h.User.Signup(ctx, ns, v.Now)
+	if err != nil {
+		return fmt.Errorf("signing up user[%s]: %w", ns.Email, err)
+	}
+
+	return web.Respond(ctx, w, u, http.StatusCreated)
+}
+
+// Login checks the email address and password of a user and starts a new session for them, handing
+// out an access token for their workspace and role in it along with a refresh token.
+func (h Handlers) Login(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var login user.Login
+	if err := web.Decode(r, &login); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	u, err := h.User.Authenticate(ctx, login, v.Now)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrAuthenticationFailure):
+			return validate.NewRequestError(err, http.StatusUnauthorized)
+		case errors.Is(err, user.ErrLocked):
+			return validate.NewRequestError(err, http.StatusTooManyRequests)
+		}
+		return fmt.Errorf("authenticating user: %w", err)
+	}
+
+	access := session.Access{
+		ID:        uuid.NewString(),
+		ExpiresAt: v.Now.Add(h.TokenTTL),
+	}
+
+	rt, err := h.Session.Start(ctx, u.ID, access, v.Now)
+	if err != nil {
+		return fmt.Errorf("starting session for user[%s]: %w", u.ID, err)
+	}
+
+	return h.respondTokens(ctx, w, u, access, rt, v.Now)
+}
+
+// Refresh trades in a refresh token for a new access token and a new refresh token. A refresh token
+// which was already used ends the session it belongs to.
+func (h Handlers) Refresh(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req refreshRequest
+	if err := web.Decode(r, &req); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	access := session.Access{
+		ID:        uuid.NewString(),
+		ExpiresAt: v.Now.Add(h.TokenTTL),
+	}
+
+	rt, err := h.Session.Rotate(ctx, req.RefreshToken, access, v.Now)
+	if err != nil {
+		if errors.Is(err, session.ErrInvalidToken) || errors.Is(err, session.ErrReused) {
+			return validate.NewRequestError(err, http.StatusUnauthorized)
+		}
+		return fmt.Errorf("rotating refresh token: %w", err)
+	}
+
+	// The role of the user may have changed since they signed in, so it is looked up again.
+	u, err := h.User.QueryByID(ctx, rt.Subject)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return validate.NewRequestError(session.ErrInvalidToken, http.StatusUnauthorized)
+		}
+		return fmt.Errorf("querying user[%s]: %w", rt.Subject, err)
+	}
+
+	return h.respondTokens(ctx, w, u, access, rt, v.Now)
+}
+
+// Logout ends the session of the authenticated subject. The access token the request was made with
+// is revoked, and so are the refresh tokens handed out along with it.
+func (h Handlers) Logout(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	// API keys and tokens which predate token IDs can't be revoked this way.
+	if claims.ID == "" {
+		return validate.NewRequestError(errors.New("only tokens with an id can be signed out of"), http.StatusBadRequest)
+	}
+
+	access := session.Access{
+		ID:        claims.ID,
+		ExpiresAt: claims.ExpiresAt,
+	}
+
+	if err := h.Session.End(ctx, access, v.Now); err != nil {
+		return fmt.Errorf("ending session of token[%s]: %w", claims.ID, err)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// respondTokens signs the access token for the user with the active key and responds with it and
+// the refresh token handed out along with it.
+func (h Handlers) respondTokens(ctx context.Context, w http.ResponseWriter, u user.User, access session.Access, rt session.IssuedToken, now time.Time) error {
+	claims := auth.Claims{
+		ID:        access.ID,
+		Issuer:    issuer,
+		Subject:   u.ID,
+		Workspace: u.Workspace,
+		Role:      u.Role,
+		IssuedAt:  now,
+		ExpiresAt: access.ExpiresAt,
+	}
+
+	token, err := h.Auth.GenerateToken(claims)
+	if err != nil {
+		return fmt.Errorf("generating token for user[%s]: %w", u.ID, err)
+	}
+
+	resp := tokenResponse{
+		Token:            token,
+		ExpiresAt:        claims.ExpiresAt,
+		RefreshToken:     rt.Secret,
+		RefreshExpiresAt: rt.ExpiresAt,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// tokenResponse carries the tokens handed out on signing in and on refreshing.
+type tokenResponse struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// refreshRequest carries the refresh token to trade in.
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Create adds a new member to the workspace of the authenticated subject.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	var nu user.NewUser
+	if err := web.Decode(r, &nu); err != nil {
+		return validate.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	u, err := h.User.Create(ctx, nu, claims.Workspace, v.Now)
+	if err != nil {
+		return fmt.Errorf("creating user[%s]: %w", nu.Email, err)
+	}
+
+	return web.Respond(ctx, w, u, http.StatusCreated)
+}
+
+// Query returns every member of the workspace of the authenticated subject.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	users, err := h.User.QueryByWorkspace(ctx, claims.Workspace)
+	if err != nil {
+		return fmt.Errorf("querying users for workspace[%s]: %w", claims.Workspace, err)
+	}
+
+	return web.Respond(ctx, w, users, http.StatusOK)
+}
+
+// QueryByID returns a single member of the workspace of the authenticated subject. Users of other
+// workspaces are reported as not found.
+func (h Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+	}
+
+	id := web.Param(r, "id")
+	u, err := h.User.QueryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("querying user[%s]: %w", id, err)
+	}
+
+	// If you are looking at a user of another workspace.
+	if u.Workspace != claims.Workspace {
+		return validate.NewRequestError(user.ErrNotFound, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, u, http.StatusOK)
+}
@@ -2,9 +2,16 @@
 package checkgroup
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"os"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/data/schema"
+	"github.com/yashshah7197/shrt/business/sys/database"
 
 	"go.uber.org/zap"
 )
@@ -13,20 +20,36 @@ import (
 type Handlers struct {
 	Build  string
 	Logger *zap.SugaredLogger
+	DB     *sql.DB
 }
 
-// Readiness checks if the database is ready and if not, will return a 500 status. Do not respond
-// by just returning an error because further up in the call stack it will interpret that as a
-// non-trusted error.
+// Readiness checks if the database is ready and if not, will return a 500 status. It also reports
+// the applied schema version and fails while migrations are pending. Do not respond by just
+// returning an error because further up in the call stack it will interpret that as a non-trusted
+// error.
 func (h Handlers) Readiness(w http.ResponseWriter, r *http.Request) {
 	data := struct {
-		Status string `json:"status"`
+		Status        string `json:"status"`
+		SchemaVersion int    `json:"schema_version,omitempty"`
 	}{
 		Status: "OK",
 	}
 
 	statusCode := http.StatusOK
 
+	// The database is only checked when the service has been configured to use one.
+	if h.DB != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+		defer cancel()
+
+		version, err := h.checkDB(ctx)
+		data.SchemaVersion = version
+		if err != nil {
+			data.Status = err.Error()
+			statusCode = http.StatusInternalServerError
+		}
+	}
+
 	if err := response(w, statusCode, data); err != nil {
 		h.Logger.Errorw("readiness", "ERROR", err)
 	}
@@ -70,6 +93,30 @@ func (h Handlers) Liveness(w http.ResponseWriter, r *http.Request) {
 	h.Logger.Infow("liveness", "statuscode", statusCode, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
 }
 
+// checkDB makes sure the database can be reached and that every embedded migration has been
+// applied. It returns the schema version applied to the database.
+func (h Handlers) checkDB(ctx context.Context) (int, error) {
+	if err := database.StatusCheck(ctx, h.DB); err != nil {
+		return 0, fmt.Errorf("db not ready: %w", err)
+	}
+
+	version, err := schema.Version(ctx, h.DB)
+	if err != nil {
+		return 0, fmt.Errorf("db not ready: %w", err)
+	}
+
+	latest, err := schema.Latest()
+	if err != nil {
+		return version, fmt.Errorf("db not ready: %w", err)
+	}
+
+	if version < latest {
+		return version, fmt.Errorf("db not ready: migrations pending: at version %d of %d", version, latest)
+	}
+
+	return version, nil
+}
+
 // response converts the given data to JSON, writes the Content-Type header and HTTP status code,
 // and then sends the response back to the client.
 func response(w http.ResponseWriter, statusCode int, data interface{}) error {
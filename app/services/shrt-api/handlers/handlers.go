@@ -2,17 +2,43 @@
 package handlers
 
 import (
+	"database/sql"
 	"expvar"
-	"github.com/yashshah7197/shrt/business/sys/auth"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers/application/apikeygroup"
+	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers/application/bulkgroup"
+	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers/application/domaingroup"
+	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers/application/foldergroup"
+	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers/application/linkgroup"
+	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers/application/policygroup"
+	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers/application/redirectgroup"
+	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers/application/reservedgroup"
 	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers/application/testgroup"
+	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers/application/usergroup"
 	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers/debug/checkgroup"
+	"github.com/yashshah7197/shrt/business/core/apikey"
+	"github.com/yashshah7197/shrt/business/core/brand"
+	"github.com/yashshah7197/shrt/business/core/bulk"
+	"github.com/yashshah7197/shrt/business/core/click"
+	"github.com/yashshah7197/shrt/business/core/folder"
+	"github.com/yashshah7197/shrt/business/core/link"
+	"github.com/yashshah7197/shrt/business/core/policy"
+	"github.com/yashshah7197/shrt/business/core/reserved"
+	"github.com/yashshah7197/shrt/business/core/session"
+	"github.com/yashshah7197/shrt/business/core/user"
+	"github.com/yashshah7197/shrt/business/sys/access"
+	"github.com/yashshah7197/shrt/business/sys/auth"
 	"github.com/yashshah7197/shrt/business/web/middleware"
+	"github.com/yashshah7197/shrt/foundation/ratelimit"
+	"github.com/yashshah7197/shrt/foundation/realip"
 	"github.com/yashshah7197/shrt/foundation/web"
 
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
@@ -37,13 +63,14 @@ func DebugStandardLibraryMux() *http.ServeMux {
 // routes for the service. This bypassing the use of the DefaultServeMux. Using the DefaultServeMux
 // could be a security risk since a dependency could inject a handler into our service without
 // us knowing it.
-func DebugMux(build string, logger *zap.SugaredLogger) http.Handler {
+func DebugMux(build string, logger *zap.SugaredLogger, db *sql.DB) http.Handler {
 	mux := DebugStandardLibraryMux()
 
 	// Register the debug check endpoints.
 	cgh := checkgroup.Handlers{
 		Build:  build,
 		Logger: logger,
+		DB:     db,
 	}
 	mux.HandleFunc("/debug/readiness", cgh.Readiness)
 	mux.HandleFunc("/debug/liveness", cgh.Liveness)
@@ -53,9 +80,30 @@ func DebugMux(build string, logger *zap.SugaredLogger) http.Handler {
 
 // APIMuxConfig contains all the mandatory systems required by the handlers.
 type APIMuxConfig struct {
-	Shutdown chan os.Signal
-	Logger   *zap.SugaredLogger
-	Auth     *auth.Auth
+	Shutdown       chan os.Signal
+	Logger         *zap.SugaredLogger
+	Auth           *auth.Auth
+	TokenTTL       time.Duration
+	User           *user.Core
+	Session        *session.Core
+	APIKey         *apikey.Core
+	Link           *link.Core
+	Click          *click.Core
+	Folder         *folder.Core
+	Bulk           *bulk.Core
+	BulkSyncLimit  int64
+	UploadTimeout  time.Duration
+	Reserved       *reserved.Core
+	Policy         *policy.Core
+	Brand          *brand.Core
+	Operator       string
+	RedirectStatus int
+	RemovedURL     string
+	BaseURL        string
+	Gate           *access.Gate
+	Limiter        *ratelimit.Limiter
+	Clicks         *click.Recorder
+	RealIP         *realip.Resolver
 }
 
 // APIMux constructs an http.Handler with all application routes defined.
@@ -72,11 +120,43 @@ func APIMux(cfg APIMuxConfig) *web.App {
 	// Bind the different routes for the API.
 	bindRoutes(app, cfg)
 
+	// Keep custom aliases from shadowing any of the routes we just bound.
+	reserveRoutes(app, cfg.Reserved)
+
 	return app
 }
 
+// reserveRoutes protects the leading static segment of every route registered on the app, so that
+// it can't be claimed as a short code.
+func reserveRoutes(app *web.App, r *reserved.Core) {
+	walkFn := func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		segment := strings.SplitN(strings.TrimPrefix(route, "/"), "/", 2)[0]
+		if segment != "" && !strings.HasPrefix(segment, "{") {
+			r.Protect(segment)
+		}
+		return nil
+	}
+
+	// The walk function never fails, so neither does the walk.
+	chi.Walk(app.Mux, walkFn)
+}
+
 // bindRoutes binds all the API routes to their handlers.
 func bindRoutes(app *web.App, cfg APIMuxConfig) {
+	// Requests authenticate with either a token or an API key. Keys are only looked up when the
+	// service is set up with them.
+	var keys auth.KeyValidator
+	if cfg.APIKey != nil {
+		keys = cfg.APIKey
+	}
+	authn := middleware.Authenticate(cfg.Auth, keys)
+
+	// Every member of a workspace may read its contents, but only owners and editors may change
+	// them. The settings of the service itself are left to the owners of the operator workspace.
+	editor := middleware.Authorize(auth.RoleOwner, auth.RoleEditor)
+	owner := middleware.Authorize(auth.RoleOwner)
+	operator := middleware.AuthorizeWorkspace(cfg.Operator)
+
 	tgh := testgroup.Handlers{
 		Logger: cfg.Logger,
 	}
@@ -86,7 +166,119 @@ func bindRoutes(app *web.App, cfg APIMuxConfig) {
 		http.MethodGet,
 		"/testauth",
 		tgh.Test,
-		middleware.Authenticate(cfg.Auth),
-		middleware.Authorize(auth.RoleAdmin),
+		authn,
+		middleware.Authorize(auth.RoleOwner),
 	)
+
+	// Register the account endpoints. Anyone may sign up, which starts a new workspace, while the
+	// members of an existing workspace are added by its owners.
+	ugh := usergroup.Handlers{
+		User:     cfg.User,
+		Session:  cfg.Session,
+		Auth:     cfg.Auth,
+		TokenTTL: cfg.TokenTTL,
+	}
+	app.Handle(http.MethodPost, "/v1/auth/signup", ugh.Signup)
+	app.Handle(http.MethodPost, "/v1/auth/login", ugh.Login)
+	app.Handle(http.MethodPost, "/v1/auth/refresh", ugh.Refresh)
+	app.Handle(http.MethodPost, "/v1/auth/logout", ugh.Logout, authn)
+	app.Handle(http.MethodGet, "/v1/users", ugh.Query, authn)
+	app.Handle(http.MethodPost, "/v1/users", ugh.Create, authn, owner)
+	app.Handle(http.MethodGet, "/v1/users/{id}", ugh.QueryByID, authn)
+
+	// Register the API key endpoints. Every member manages the keys they created themselves.
+	agh := apikeygroup.Handlers{
+		APIKey: cfg.APIKey,
+	}
+	app.Handle(http.MethodGet, "/v1/apikeys", agh.Query, authn)
+	app.Handle(http.MethodPost, "/v1/apikeys", agh.Create, authn)
+	app.Handle(http.MethodGet, "/v1/apikeys/{id}", agh.QueryByID, authn)
+	app.Handle(http.MethodDelete, "/v1/apikeys/{id}", agh.Delete, authn)
+
+	// Register the short link management endpoints.
+	lgh := linkgroup.Handlers{
+		Link:    cfg.Link,
+		Click:   cfg.Click,
+		Folder:  cfg.Folder,
+		BaseURL: cfg.BaseURL,
+	}
+	app.Handle(http.MethodGet, "/v1/links", lgh.Query, authn)
+	app.Handle(http.MethodPost, "/v1/links", lgh.Create, authn, editor)
+	app.Handle(http.MethodGet, "/v1/links:search", lgh.Search, authn)
+	app.Handle(http.MethodGet, "/v1/links/{code}", lgh.QueryByCode, authn)
+	app.Handle(http.MethodPut, "/v1/links/{code}", lgh.Update, authn, editor)
+	app.Handle(http.MethodDelete, "/v1/links/{code}", lgh.Delete, authn, editor)
+	app.Handle(http.MethodGet, "/v1/links/{code}/stats", lgh.Stats, authn, editor)
+	app.Handle(http.MethodGet, "/v1/links/{code}/qr", lgh.QR, authn)
+	app.Handle(http.MethodGet, "/v1/links/{code}/revisions", lgh.QueryRevisions, authn)
+	app.Handle(http.MethodPost, "/v1/links/{code}/revisions/{revision}/rollback", lgh.Rollback, authn, editor)
+	app.Handle(http.MethodPost, "/v1/links/{code}/restore", lgh.Restore, authn, editor)
+	app.Handle(http.MethodGet, "/v1/tags", lgh.QueryTags, authn)
+	app.Handle(http.MethodPost, "/v1/tags:merge", lgh.MergeTags, authn, editor)
+	app.Handle(http.MethodPost, "/v1/tags/{tag}/rename", lgh.RenameTag, authn, editor)
+
+	// Register the folder management endpoints.
+	fgh := foldergroup.Handlers{
+		Folder: cfg.Folder,
+		Link:   cfg.Link,
+	}
+	app.Handle(http.MethodGet, "/v1/folders", fgh.Query, authn)
+	app.Handle(http.MethodPost, "/v1/folders", fgh.Create, authn, editor)
+	app.Handle(http.MethodGet, "/v1/folders/{id}", fgh.QueryByID, authn)
+	app.Handle(http.MethodPut, "/v1/folders/{id}", fgh.Update, authn, editor)
+	app.Handle(http.MethodDelete, "/v1/folders/{id}", fgh.Delete, authn, editor)
+
+	// Register the bulk upload endpoints. Uploads are given longer to arrive than other requests.
+	bgh := bulkgroup.Handlers{
+		Bulk:      cfg.Bulk,
+		SyncLimit: cfg.BulkSyncLimit,
+	}
+	app.Handle(http.MethodPost, "/v1/links:bulk", bgh.Create, authn, editor, middleware.Deadline(cfg.UploadTimeout))
+	app.Handle(http.MethodGet, "/v1/links:bulk/{id}", bgh.QueryByID, authn)
+
+	// Register the reserved and blocked word management endpoints.
+	wgh := reservedgroup.Handlers{
+		Reserved: cfg.Reserved,
+	}
+	app.Handle(http.MethodGet, "/v1/reserved", wgh.Query, authn, owner, operator)
+	app.Handle(http.MethodPost, "/v1/reserved", wgh.Create, authn, owner, operator)
+	app.Handle(http.MethodDelete, "/v1/reserved/{word}", wgh.Delete, authn, owner, operator)
+
+	// Register the blocklist management endpoints.
+	pgh := policygroup.Handlers{
+		Policy: cfg.Policy,
+	}
+	app.Handle(http.MethodGet, "/v1/blocklist", pgh.Query, authn, owner, operator)
+	app.Handle(http.MethodPost, "/v1/blocklist", pgh.Create, authn, owner, operator)
+	app.Handle(http.MethodDelete, "/v1/blocklist/{pattern}", pgh.Delete, authn, owner, operator)
+
+	// Register the branded domain management endpoints. Members may look at the domains of their
+	// workspace, but only operators may add, change or remove domains, once they have verified who
+	// the hostname belongs to.
+	dgh := domaingroup.Handlers{
+		Brand: cfg.Brand,
+		Link:  cfg.Link,
+	}
+	app.Handle(http.MethodGet, "/v1/domains", dgh.Query, authn)
+	app.Handle(http.MethodPost, "/v1/domains", dgh.Create, authn, owner, operator)
+	app.Handle(http.MethodGet, "/v1/domains/{host}", dgh.QueryByHost, authn)
+	app.Handle(http.MethodPut, "/v1/domains/{host}", dgh.Update, authn, owner, operator)
+	app.Handle(http.MethodDelete, "/v1/domains/{host}", dgh.Delete, authn, owner, operator)
+
+	// Register the public redirect endpoints. HEAD is supported so link checkers can probe a short
+	// link without being treated as a visitor. Passwords for protected links are posted back to the
+	// short link itself. Codes are resolved on the branded domain the request came in on, if any.
+	rgh := redirectgroup.Handlers{
+		Link:           cfg.Link,
+		Brand:          cfg.Brand,
+		RedirectStatus: cfg.RedirectStatus,
+		RemovedURL:     cfg.RemovedURL,
+		Gate:           cfg.Gate,
+		Limiter:        cfg.Limiter,
+		Clicks:         cfg.Clicks,
+		RealIP:         cfg.RealIP,
+	}
+	app.Handle(http.MethodGet, "/{code}", rgh.Redirect)
+	app.Handle(http.MethodHead, "/{code}", rgh.Redirect)
+	app.Handle(http.MethodPost, "/{code}", rgh.Unlock)
 }
@@ -2,19 +2,77 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"expvar"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"syscall"
 	"time"
 
+	// Embed the time zone database since the service image doesn't ship one.
+	_ "time/tzdata"
+
 	"github.com/yashshah7197/shrt/app/services/shrt-api/handlers"
+	"github.com/yashshah7197/shrt/business/core/apikey"
+	"github.com/yashshah7197/shrt/business/core/apikey/stores/apikeydb"
+	"github.com/yashshah7197/shrt/business/core/apikey/stores/apikeyfile"
+	"github.com/yashshah7197/shrt/business/core/apikey/stores/apikeymem"
+	"github.com/yashshah7197/shrt/business/core/brand"
+	"github.com/yashshah7197/shrt/business/core/brand/stores/branddb"
+	"github.com/yashshah7197/shrt/business/core/brand/stores/brandfile"
+	"github.com/yashshah7197/shrt/business/core/brand/stores/brandmem"
+	"github.com/yashshah7197/shrt/business/core/bulk"
+	"github.com/yashshah7197/shrt/business/core/click"
+	"github.com/yashshah7197/shrt/business/core/click/stores/clickdb"
+	"github.com/yashshah7197/shrt/business/core/click/stores/clickfile"
+	"github.com/yashshah7197/shrt/business/core/click/stores/clickmem"
+	"github.com/yashshah7197/shrt/business/core/folder"
+	"github.com/yashshah7197/shrt/business/core/folder/stores/folderdb"
+	"github.com/yashshah7197/shrt/business/core/folder/stores/folderfile"
+	"github.com/yashshah7197/shrt/business/core/folder/stores/foldermem"
+	"github.com/yashshah7197/shrt/business/core/link"
+	"github.com/yashshah7197/shrt/business/core/link/stores/linkdb"
+	"github.com/yashshah7197/shrt/business/core/link/stores/linkfile"
+	"github.com/yashshah7197/shrt/business/core/link/stores/linkmem"
+	"github.com/yashshah7197/shrt/business/core/policy"
+	"github.com/yashshah7197/shrt/business/core/policy/stores/policydb"
+	"github.com/yashshah7197/shrt/business/core/policy/stores/policyfile"
+	"github.com/yashshah7197/shrt/business/core/policy/stores/policymem"
+	"github.com/yashshah7197/shrt/business/core/reserved"
+	"github.com/yashshah7197/shrt/business/core/reserved/stores/reserveddb"
+	"github.com/yashshah7197/shrt/business/core/reserved/stores/reservedfile"
+	"github.com/yashshah7197/shrt/business/core/reserved/stores/reservedmem"
+	"github.com/yashshah7197/shrt/business/core/revocation"
+	"github.com/yashshah7197/shrt/business/core/revocation/stores/revocationdb"
+	"github.com/yashshah7197/shrt/business/core/revocation/stores/revocationfile"
+	"github.com/yashshah7197/shrt/business/core/revocation/stores/revocationmem"
+	"github.com/yashshah7197/shrt/business/core/session"
+	"github.com/yashshah7197/shrt/business/core/session/stores/sessiondb"
+	"github.com/yashshah7197/shrt/business/core/session/stores/sessionfile"
+	"github.com/yashshah7197/shrt/business/core/session/stores/sessionmem"
+	"github.com/yashshah7197/shrt/business/core/user"
+	"github.com/yashshah7197/shrt/business/core/user/stores/userdb"
+	"github.com/yashshah7197/shrt/business/core/user/stores/userfile"
+	"github.com/yashshah7197/shrt/business/core/user/stores/usermem"
+	"github.com/yashshah7197/shrt/business/sys/access"
 	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/codegen"
+	"github.com/yashshah7197/shrt/business/sys/database"
+	"github.com/yashshah7197/shrt/business/sys/geoip"
+	"github.com/yashshah7197/shrt/business/sys/reputation"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/business/sys/workspace"
 	"github.com/yashshah7197/shrt/foundation/keystore"
+	"github.com/yashshah7197/shrt/foundation/ratelimit"
+	"github.com/yashshah7197/shrt/foundation/realip"
+	"github.com/yashshah7197/shrt/foundation/ticker"
+	"github.com/yashshah7197/shrt/foundation/web"
 
 	"github.com/ardanlabs/conf"
 	"go.uber.org/automaxprocs/maxprocs"
@@ -65,10 +123,87 @@ func run(logger *zap.SugaredLogger) error {
 			WriteTimeout    time.Duration `conf:"default:10s"`
 			IdleTimeout     time.Duration `conf:"default:120s"`
 			ShutdownTimeout time.Duration `conf:"default:20s"`
+			RedirectStatus  int           `conf:"default:302"`
+			TrustedProxies  []string
 		}
 		Auth struct {
-			KeysFolder  string `conf:"default:zarf/keys/"`
-			ActiveKeyID string `conf:"default:ecdf8542-fbf3-404d-acdc-f41527a0c3c8"`
+			KeysFolder   string `conf:"default:zarf/keys/"`
+			ActiveKeyID  string `conf:"default:ecdf8542-fbf3-404d-acdc-f41527a0c3c8"`
+			RetiredKeys  []string
+			Operator     string        `conf:"required"`
+			TokenTTL     time.Duration `conf:"default:15m"`
+			RefreshTTL   time.Duration `conf:"default:720h"`
+			SyncInterval time.Duration `conf:"default:30s"`
+			MaxFailures  int           `conf:"default:5"`
+			Lockout      time.Duration `conf:"default:15m"`
+		}
+		Store struct {
+			Type       string `conf:"default:memory"`
+			DataFolder string `conf:"default:data/"`
+		}
+		Links struct {
+			SweepInterval       time.Duration `conf:"default:1m"`
+			PassTTL             time.Duration `conf:"default:15m"`
+			MaxPasswordFailures int           `conf:"default:5"`
+			PasswordLockout     time.Duration `conf:"default:15m"`
+			TrashRetention      time.Duration `conf:"default:720h"`
+			RemovedURL          string
+			BaseURL             string
+		}
+		Policy struct {
+			Schemes        []string `conf:"default:http;https"`
+			OwnDomains     []string
+			Shorteners     []string      `conf:"default:bit.ly;tinyurl.com;t.co;goo.gl;ow.ly;is.gd;buff.ly;rebrand.ly;cutt.ly;tiny.cc"`
+			MaxHops        int           `conf:"default:3"`
+			ResolveTimeout time.Duration `conf:"default:5s"`
+		}
+		Reputation struct {
+			ListPaths       []string
+			ReloadInterval  time.Duration `conf:"default:1m"`
+			ProviderURL     string
+			ProviderKey     string        `conf:"mask"`
+			ProviderTimeout time.Duration `conf:"default:5s"`
+			RescanInterval  time.Duration `conf:"default:1h"`
+		}
+		Search struct {
+			ReindexInterval time.Duration `conf:"default:5m"`
+		}
+		Bulk struct {
+			MaxRows     int           `conf:"default:50000"`
+			MaxResults  int           `conf:"default:1000"`
+			MaxSize     int64         `conf:"default:67108864"`
+			SyncLimit   int64         `conf:"default:262144"`
+			Timeout     time.Duration `conf:"default:5m"`
+			MaxJobs     int           `conf:"default:2"`
+			Retention   time.Duration `conf:"default:1h"`
+			SpoolFolder string
+		}
+		Clicks struct {
+			QueueSize     int           `conf:"default:10000"`
+			BatchSize     int           `conf:"default:500"`
+			Workers       int           `conf:"default:2"`
+			FlushInterval time.Duration `conf:"default:1s"`
+		}
+		Geo struct {
+			DatabasePath   string
+			ReloadInterval time.Duration `conf:"default:1m"`
+		}
+		Codes struct {
+			Strategy       string `conf:"default:random"`
+			Alphabet       string `conf:"default:0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"`
+			Length         int    `conf:"default:7"`
+			MaxAttempts    int    `conf:"default:5"`
+			ObfuscationKey uint64 `conf:"mask"`
+		}
+		DB struct {
+			User            string        `conf:"default:postgres"`
+			Password        string        `conf:"default:postgres,mask"`
+			Host            string        `conf:"default:localhost"`
+			Name            string        `conf:"default:postgres"`
+			MaxIdleConns    int           `conf:"default:2"`
+			MaxOpenConns    int           `conf:"default:10"`
+			ConnMaxLifetime time.Duration `conf:"default:30m"`
+			DisableTLS      bool          `conf:"default:true"`
 		}
 	}{
 		Version: conf.Version{
@@ -103,6 +238,219 @@ func run(logger *zap.SugaredLogger) error {
 
 	expvar.NewString("build").Set(build)
 
+	if !link.IsRedirectStatus(cfg.Web.RedirectStatus) {
+		return fmt.Errorf("invalid default redirect status: %d", cfg.Web.RedirectStatus)
+	}
+
+	// =============================================================================================
+	// Initialize Storage Support
+	// =============================================================================================
+	logger.Infow("startup", "status", "initializing storage support", "type", cfg.Store.Type)
+
+	// The database is only opened when it backs the stores. The debug readiness check reports on
+	// it whenever it is present.
+	var db *sql.DB
+
+	var (
+		linkStore       link.Storer
+		reservedStore   reserved.Storer
+		clickStore      click.Storer
+		folderStore     folder.Storer
+		policyStore     policy.Storer
+		brandStore      brand.Storer
+		userStore       user.Storer
+		apikeyStore     apikey.Storer
+		sessionStore    session.Storer
+		revocationStore revocation.Storer
+	)
+	switch cfg.Store.Type {
+	case "memory":
+		linkStore = linkmem.NewStore()
+		reservedStore = reservedmem.NewStore()
+		clickStore = clickmem.NewStore()
+		folderStore = foldermem.NewStore()
+		policyStore = policymem.NewStore()
+		brandStore = brandmem.NewStore()
+		userStore = usermem.NewStore()
+		apikeyStore = apikeymem.NewStore()
+		sessionStore = sessionmem.NewStore()
+		revocationStore = revocationmem.NewStore()
+
+	case "file":
+		lStore, err := linkfile.Open(filepath.Join(cfg.Store.DataFolder, "links.log"))
+		if err != nil {
+			return fmt.Errorf("opening link file store: %w", err)
+		}
+		defer func() {
+			logger.Infow("shutdown", "status", "closing link file store", "folder", cfg.Store.DataFolder)
+			lStore.Close()
+		}()
+		linkStore = lStore
+
+		rStore, err := reservedfile.Open(filepath.Join(cfg.Store.DataFolder, "reserved.log"))
+		if err != nil {
+			return fmt.Errorf("opening reserved word file store: %w", err)
+		}
+		defer func() {
+			logger.Infow("shutdown", "status", "closing reserved word file store", "folder", cfg.Store.DataFolder)
+			rStore.Close()
+		}()
+		reservedStore = rStore
+
+		cStore, err := clickfile.Open(filepath.Join(cfg.Store.DataFolder, "clicks.log"))
+		if err != nil {
+			return fmt.Errorf("opening click file store: %w", err)
+		}
+		defer func() {
+			logger.Infow("shutdown", "status", "closing click file store", "folder", cfg.Store.DataFolder)
+			cStore.Close()
+		}()
+		clickStore = cStore
+
+		fStore, err := folderfile.Open(filepath.Join(cfg.Store.DataFolder, "folders.log"))
+		if err != nil {
+			return fmt.Errorf("opening folder file store: %w", err)
+		}
+		defer func() {
+			logger.Infow("shutdown", "status", "closing folder file store", "folder", cfg.Store.DataFolder)
+			fStore.Close()
+		}()
+		folderStore = fStore
+
+		pStore, err := policyfile.Open(filepath.Join(cfg.Store.DataFolder, "blocklist.log"))
+		if err != nil {
+			return fmt.Errorf("opening blocklist file store: %w", err)
+		}
+		defer func() {
+			logger.Infow("shutdown", "status", "closing blocklist file store", "folder", cfg.Store.DataFolder)
+			pStore.Close()
+		}()
+		policyStore = pStore
+
+		bStore, err := brandfile.Open(filepath.Join(cfg.Store.DataFolder, "domains.log"))
+		if err != nil {
+			return fmt.Errorf("opening domain file store: %w", err)
+		}
+		defer func() {
+			logger.Infow("shutdown", "status", "closing domain file store", "folder", cfg.Store.DataFolder)
+			bStore.Close()
+		}()
+		brandStore = bStore
+
+		uStore, err := userfile.Open(filepath.Join(cfg.Store.DataFolder, "users.log"))
+		if err != nil {
+			return fmt.Errorf("opening user file store: %w", err)
+		}
+		defer func() {
+			logger.Infow("shutdown", "status", "closing user file store", "folder", cfg.Store.DataFolder)
+			uStore.Close()
+		}()
+		userStore = uStore
+
+		kStore, err := apikeyfile.Open(filepath.Join(cfg.Store.DataFolder, "apikeys.log"))
+		if err != nil {
+			return fmt.Errorf("opening api key file store: %w", err)
+		}
+		defer func() {
+			logger.Infow("shutdown", "status", "closing api key file store", "folder", cfg.Store.DataFolder)
+			kStore.Close()
+		}()
+		apikeyStore = kStore
+
+		sStore, err := sessionfile.Open(filepath.Join(cfg.Store.DataFolder, "sessions.log"))
+		if err != nil {
+			return fmt.Errorf("opening session file store: %w", err)
+		}
+		defer func() {
+			logger.Infow("shutdown", "status", "closing session file store", "folder", cfg.Store.DataFolder)
+			sStore.Close()
+		}()
+		sessionStore = sStore
+
+		vStore, err := revocationfile.Open(filepath.Join(cfg.Store.DataFolder, "revocations.log"))
+		if err != nil {
+			return fmt.Errorf("opening revocation file store: %w", err)
+		}
+		defer func() {
+			logger.Infow("shutdown", "status", "closing revocation file store", "folder", cfg.Store.DataFolder)
+			vStore.Close()
+		}()
+		revocationStore = vStore
+
+	case "sql":
+		logger.Infow("startup", "status", "initializing database support", "host", cfg.DB.Host)
+
+		db, err = database.Open(database.Config{
+			User:            cfg.DB.User,
+			Password:        cfg.DB.Password,
+			Host:            cfg.DB.Host,
+			Name:            cfg.DB.Name,
+			MaxIdleConns:    cfg.DB.MaxIdleConns,
+			MaxOpenConns:    cfg.DB.MaxOpenConns,
+			ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
+			DisableTLS:      cfg.DB.DisableTLS,
+		})
+		if err != nil {
+			return fmt.Errorf("connecting to database: %w", err)
+		}
+		defer func() {
+			logger.Infow("shutdown", "status", "stopping database support", "host", cfg.DB.Host)
+			db.Close()
+		}()
+		linkStore = linkdb.NewStore(db)
+		reservedStore = reserveddb.NewStore(db)
+		clickStore = clickdb.NewStore(db)
+		folderStore = folderdb.NewStore(db)
+		policyStore = policydb.NewStore(db)
+		brandStore = branddb.NewStore(db)
+		userStore = userdb.NewStore(db)
+		apikeyStore = apikeydb.NewStore(db)
+		sessionStore = sessiondb.NewStore(db)
+		revocationStore = revocationdb.NewStore(db)
+
+	default:
+		return fmt.Errorf("unknown store type: %q", cfg.Store.Type)
+	}
+
+	reservedCore := reserved.NewCore(reservedStore)
+
+	if err := workspace.Validate(cfg.Auth.Operator); err != nil {
+		return fmt.Errorf("invalid operator workspace %q: %w", cfg.Auth.Operator, err)
+	}
+
+	if cfg.Auth.MaxFailures < 1 {
+		return fmt.Errorf("invalid max login failures: %d", cfg.Auth.MaxFailures)
+	}
+
+	userCore, err := user.NewCore(user.Config{
+		Storer:      userStore,
+		MaxFailures: cfg.Auth.MaxFailures,
+		Lockout:     cfg.Auth.Lockout,
+		Reserved:    []string{workspace.Default, cfg.Auth.Operator},
+	})
+	if err != nil {
+		return fmt.Errorf("constructing user core: %w", err)
+	}
+
+	apikeyCore, err := apikey.NewCore(apikeyStore)
+	if err != nil {
+		return fmt.Errorf("constructing api key core: %w", err)
+	}
+
+	revocationCore, err := revocation.NewCore(context.Background(), revocationStore, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("constructing revocation core: %w", err)
+	}
+
+	sessionCore, err := session.NewCore(session.Config{
+		Storer:     sessionStore,
+		Revocation: revocationCore,
+		TTL:        cfg.Auth.RefreshTTL,
+	})
+	if err != nil {
+		return fmt.Errorf("constructing session core: %w", err)
+	}
+
 	// =============================================================================================
 	// Initialize Authentication & Authorization Support
 	// =============================================================================================
@@ -114,11 +462,327 @@ func run(logger *zap.SugaredLogger) error {
 		return fmt.Errorf("reading keys from keys folder: %w", err)
 	}
 
-	auth, err := auth.New(cfg.Auth.ActiveKeyID, ks)
+	// The active key signs everything new. Every other key in the folder keeps verifying what it
+	// signed while it was active, unless it has been retired. Rotating keys without signing anyone
+	// out takes adding the new key to the folder, making it the active key, and retiring the old
+	// one once the tokens it signed have expired.
+	if err := ks.SetState(cfg.Auth.ActiveKeyID, keystore.StateActive); err != nil {
+		return fmt.Errorf("activating key[%s]: %w", cfg.Auth.ActiveKeyID, err)
+	}
+	for _, keyID := range cfg.Auth.RetiredKeys {
+		if keyID == cfg.Auth.ActiveKeyID {
+			return fmt.Errorf("retiring key[%s]: key is active", keyID)
+		}
+		if err := ks.SetState(keyID, keystore.StateRetired); err != nil {
+			return fmt.Errorf("retiring key[%s]: %w", keyID, err)
+		}
+	}
+
+	// Tokens which were revoked before they expired are turned down.
+	auth, err := auth.New(ks, revocationCore)
 	if err != nil {
 		return fmt.Errorf("constructing auth: %w", err)
 	}
 
+	// Passes for password protected links are signed with the same active key.
+	gate, err := access.NewGate(ks, cfg.Links.PassTTL)
+	if err != nil {
+		return fmt.Errorf("constructing access gate: %w", err)
+	}
+
+	// =============================================================================================
+	// Initialize Reputation Checking
+	// =============================================================================================
+
+	// Destinations are only checked for threats when threat lists or a provider are configured.
+	var (
+		checkers    reputation.Multi
+		threatLists *reputation.Local
+	)
+	if len(cfg.Reputation.ListPaths) > 0 {
+		logger.Infow("startup", "status", "loading threat lists", "paths", cfg.Reputation.ListPaths)
+
+		threatLists, err = reputation.OpenLocal(cfg.Reputation.ListPaths)
+		if err != nil {
+			return fmt.Errorf("opening threat lists: %w", err)
+		}
+		checkers = append(checkers, threatLists)
+
+		logger.Infow("startup", "status", "threat lists loaded", "entries", threatLists.Len())
+	}
+	if cfg.Reputation.ProviderURL != "" {
+		if err := validate.URL(cfg.Reputation.ProviderURL); err != nil {
+			return fmt.Errorf("invalid reputation provider url %q: %w", cfg.Reputation.ProviderURL, err)
+		}
+		checkers = append(checkers, reputation.NewHTTP(reputation.HTTPConfig{
+			URL:     cfg.Reputation.ProviderURL,
+			APIKey:  cfg.Reputation.ProviderKey,
+			Timeout: cfg.Reputation.ProviderTimeout,
+		}))
+	}
+
+	var checker reputation.Checker
+	if len(checkers) > 0 {
+		checker = checkers
+	}
+
+	// =============================================================================================
+	// Initialize Short Code Generation
+	// =============================================================================================
+	logger.Infow("startup", "status", "initializing short code generation", "strategy", cfg.Codes.Strategy)
+
+	alphabet, err := codegen.NewAlphabet(cfg.Codes.Alphabet)
+	if err != nil {
+		return fmt.Errorf("constructing code alphabet: %w", err)
+	}
+
+	var generator codegen.Generator
+	switch cfg.Codes.Strategy {
+	case "random":
+		generator, err = codegen.NewRandom(alphabet, cfg.Codes.Length)
+
+	case "sequence":
+		generator = codegen.NewSequence(alphabet, linkStore.NextSequence)
+
+	case "obfuscated":
+		if cfg.Codes.ObfuscationKey == 0 {
+			return errors.New("obfuscation key is required by the obfuscated strategy")
+		}
+		generator, err = codegen.NewObfuscated(alphabet, cfg.Codes.Length, cfg.Codes.ObfuscationKey, linkStore.NextSequence)
+
+	default:
+		err = fmt.Errorf("unknown strategy: %q", cfg.Codes.Strategy)
+	}
+	if err != nil {
+		return fmt.Errorf("constructing code generator: %w", err)
+	}
+
+	if cfg.Codes.MaxAttempts < 1 {
+		return fmt.Errorf("invalid max code generation attempts: %d", cfg.Codes.MaxAttempts)
+	}
+
+	if cfg.Links.RemovedURL != "" {
+		if err := validate.URL(cfg.Links.RemovedURL); err != nil {
+			return fmt.Errorf("invalid removed link page %q: %w", cfg.Links.RemovedURL, err)
+		}
+	}
+
+	// Short links must never point back at the service, which would only send visitors around in
+	// circles, so the domain of the short links counts as one of its own.
+	ownDomains := cfg.Policy.OwnDomains
+	if cfg.Links.BaseURL != "" {
+		if err := validate.URL(cfg.Links.BaseURL); err != nil {
+			return fmt.Errorf("invalid short link base url %q: %w", cfg.Links.BaseURL, err)
+		}
+		base, err := url.Parse(cfg.Links.BaseURL)
+		if err != nil {
+			return fmt.Errorf("parsing short link base url %q: %w", cfg.Links.BaseURL, err)
+		}
+		ownDomains = append(ownDomains, base.Hostname())
+	}
+
+	if cfg.Policy.MaxHops < 0 {
+		return fmt.Errorf("invalid max policy hops: %d", cfg.Policy.MaxHops)
+	}
+
+	// The branded domains are served by the service too, so they count as its own domains.
+	brandCore := brand.NewCore(brandStore)
+
+	policyCore := policy.NewCore(policy.Config{
+		Storer:         policyStore,
+		Domains:        brandCore,
+		Schemes:        cfg.Policy.Schemes,
+		OwnDomains:     ownDomains,
+		Shorteners:     cfg.Policy.Shorteners,
+		MaxHops:        cfg.Policy.MaxHops,
+		ResolveTimeout: cfg.Policy.ResolveTimeout,
+	})
+
+	clickCore := click.NewCore(clickStore)
+	folderCore := folder.NewCore(folderStore)
+
+	linkCore := link.NewCore(link.Config{
+		Storer:         linkStore,
+		Generator:      generator,
+		MaxAttempts:    cfg.Codes.MaxAttempts,
+		Reserved:       reservedCore,
+		Click:          clickCore,
+		Folder:         folderCore,
+		Brand:          brandCore,
+		Policy:         policyCore,
+		Reputation:     checker,
+		TrashRetention: cfg.Links.TrashRetention,
+		Logger:         logger,
+	})
+
+	// =============================================================================================
+	// Build Search Index
+	// =============================================================================================
+	logger.Infow("startup", "status", "building search index")
+
+	indexed, err := linkCore.Reindex(context.Background())
+	if err != nil {
+		return fmt.Errorf("building search index: %w", err)
+	}
+
+	logger.Infow("startup", "status", "search index built", "links", indexed)
+
+	// =============================================================================================
+	// Initialize Bulk Uploads
+	// =============================================================================================
+	logger.Infow("startup", "status", "initializing bulk uploads", "maxjobs", cfg.Bulk.MaxJobs)
+
+	if cfg.Bulk.MaxJobs < 1 {
+		return fmt.Errorf("invalid max concurrent bulk jobs: %d", cfg.Bulk.MaxJobs)
+	}
+
+	// Bulk uploads too large to handle within a request are spooled to disk and run as jobs.
+	bulkCore := bulk.NewCore(bulk.Config{
+		Link:        linkCore,
+		Logger:      logger,
+		MaxRows:     cfg.Bulk.MaxRows,
+		MaxResults:  cfg.Bulk.MaxResults,
+		MaxSize:     cfg.Bulk.MaxSize,
+		MaxJobs:     cfg.Bulk.MaxJobs,
+		Retention:   cfg.Bulk.Retention,
+		SpoolFolder: cfg.Bulk.SpoolFolder,
+	})
+
+	// =============================================================================================
+	// Initialize GeoIP Support
+	// =============================================================================================
+
+	// Clicks are only placed on the map when a database is configured.
+	var geo *geoip.Locator
+	if cfg.Geo.DatabasePath != "" {
+		logger.Infow("startup", "status", "initializing geoip support", "path", cfg.Geo.DatabasePath)
+
+		geo, err = geoip.Open(cfg.Geo.DatabasePath)
+		if err != nil {
+			return fmt.Errorf("opening geoip database: %w", err)
+		}
+
+		md := geo.Metadata()
+		logger.Infow("startup", "status", "geoip database loaded", "type", md.DatabaseType, "built", time.Unix(int64(md.BuildEpoch), 0).UTC())
+	}
+
+	// =============================================================================================
+	// Start Click Capture
+	// =============================================================================================
+	logger.Infow("startup", "status", "starting click capture", "queuesize", cfg.Clicks.QueueSize, "workers", cfg.Clicks.Workers)
+
+	clicks, err := click.NewRecorder(click.RecorderConfig{
+		Core:          clickCore,
+		Logger:        logger,
+		Geo:           geo,
+		QueueSize:     cfg.Clicks.QueueSize,
+		BatchSize:     cfg.Clicks.BatchSize,
+		Workers:       cfg.Clicks.Workers,
+		FlushInterval: cfg.Clicks.FlushInterval,
+	})
+	if err != nil {
+		return fmt.Errorf("constructing click recorder: %w", err)
+	}
+
+	// =============================================================================================
+	// Start Background Jobs
+	// =============================================================================================
+	logger.Infow("startup", "status", "starting background jobs", "sweepinterval", cfg.Links.SweepInterval, "trashretention", cfg.Links.TrashRetention)
+
+	// Periodically archive the links which have expired and purge the ones which have been in the
+	// trash for too long.
+	sweeper := ticker.Start(cfg.Links.SweepInterval, func(ctx context.Context) {
+		archived, err := linkCore.ArchiveExpired(ctx, time.Now().UTC())
+		if err != nil {
+			logger.Errorw("sweeper", "ERROR", err)
+		}
+		if archived > 0 {
+			logger.Infow("sweeper", "status", "archived expired links", "archived", archived)
+		}
+
+		purged, err := linkCore.PurgeDeleted(ctx, time.Now().UTC())
+		if err != nil {
+			logger.Errorw("sweeper", "ERROR", err)
+		}
+		if purged > 0 {
+			logger.Infow("sweeper", "status", "purged deleted links", "purged", purged)
+		}
+	})
+
+	// Reload the revoked tokens now and then to pick up the ones revoked by other instances sharing
+	// the store, and drop the revoked and refresh tokens which have expired.
+	syncer := ticker.Start(cfg.Auth.SyncInterval, func(ctx context.Context) {
+		dropped, err := revocationCore.Reload(ctx, time.Now().UTC())
+		if err != nil {
+			logger.Errorw("syncer", "ERROR", err)
+		}
+		if dropped > 0 {
+			logger.Infow("syncer", "status", "dropped expired revocations", "dropped", dropped)
+		}
+
+		deleted, err := sessionCore.DeleteExpired(ctx, time.Now().UTC())
+		if err != nil {
+			logger.Errorw("syncer", "ERROR", err)
+		}
+		if deleted > 0 {
+			logger.Infow("syncer", "status", "deleted expired refresh tokens", "deleted", deleted)
+		}
+	})
+
+	// Rebuild the search index now and then to pick up changes made by other instances sharing
+	// the store. A zero interval turns this off.
+	var reindexer *ticker.Ticker
+	if cfg.Search.ReindexInterval > 0 {
+		reindexer = ticker.Start(cfg.Search.ReindexInterval, func(ctx context.Context) {
+			if _, err := linkCore.Reindex(ctx); err != nil {
+				logger.Errorw("reindexer", "ERROR", err)
+			}
+		})
+	}
+
+	// Check every link against the threat lists again now and then, since a destination which was
+	// fine when the link was made can turn bad later on. A zero interval turns this off.
+	var rescanner *ticker.Ticker
+	if checker != nil && cfg.Reputation.RescanInterval > 0 {
+		rescanner = ticker.Start(cfg.Reputation.RescanInterval, func(ctx context.Context) {
+			flagged, cleared, err := linkCore.Rescan(ctx, time.Now().UTC())
+			if err != nil {
+				logger.Errorw("rescanner", "ERROR", err)
+			}
+			if flagged > 0 || cleared > 0 {
+				logger.Infow("rescanner", "status", "rescanned links", "flagged", flagged, "cleared", cleared)
+			}
+		})
+	}
+
+	// Swap in the threat lists whenever their files are replaced.
+	var listReloader *ticker.Ticker
+	if threatLists != nil {
+		listReloader = ticker.Start(cfg.Reputation.ReloadInterval, func(ctx context.Context) {
+			reloaded, err := threatLists.Reload()
+			if err != nil {
+				logger.Errorw("threatlists", "ERROR", err)
+			}
+			if reloaded {
+				logger.Infow("threatlists", "status", "reloaded threat lists", "entries", threatLists.Len())
+			}
+		})
+	}
+
+	// Swap in the geoip database whenever its file is replaced.
+	var geoReloader *ticker.Ticker
+	if geo != nil {
+		geoReloader = ticker.Start(cfg.Geo.ReloadInterval, func(ctx context.Context) {
+			reloaded, err := geo.Reload()
+			if err != nil {
+				logger.Errorw("geoip", "ERROR", err)
+			}
+			if reloaded {
+				logger.Infow("geoip", "status", "reloaded database", "path", cfg.Geo.DatabasePath)
+			}
+		})
+	}
+
 	// =============================================================================================
 	// Start Debug Service
 	// =============================================================================================
@@ -129,7 +793,7 @@ func run(logger *zap.SugaredLogger) error {
 	// This includes the standard library endpoints.
 
 	// Construct the mux for the debug calls.
-	debugMux := handlers.DebugMux(build, logger)
+	debugMux := handlers.DebugMux(build, logger, db)
 
 	// Start the service listening for debug requests.
 	// Not concerned with shutting this down with load shedding.
@@ -145,6 +809,12 @@ func run(logger *zap.SugaredLogger) error {
 
 	logger.Infow("startup", "status", "initializing API support")
 
+	// Client addresses are only taken from X-Forwarded-For when a trusted proxy sent the request.
+	realIP, err := realip.NewResolver(cfg.Web.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("constructing client address resolver: %w", err)
+	}
+
 	// Make a channel to listen for an interrupt or terminate signal from the OS. Use a buffered
 	// channel because the signal package requires it.
 	shutdown := make(chan os.Signal, 1)
@@ -152,9 +822,30 @@ func run(logger *zap.SugaredLogger) error {
 
 	// Construct the mux for API calls.
 	apiMux := handlers.APIMux(handlers.APIMuxConfig{
-		Shutdown: shutdown,
-		Logger:   logger,
-		Auth:     auth,
+		Shutdown:       shutdown,
+		Logger:         logger,
+		Auth:           auth,
+		TokenTTL:       cfg.Auth.TokenTTL,
+		User:           userCore,
+		Session:        sessionCore,
+		APIKey:         apikeyCore,
+		Link:           linkCore,
+		Click:          clickCore,
+		Folder:         folderCore,
+		Bulk:           bulkCore,
+		BulkSyncLimit:  cfg.Bulk.SyncLimit,
+		UploadTimeout:  cfg.Bulk.Timeout,
+		Reserved:       reservedCore,
+		Policy:         policyCore,
+		Brand:          brandCore,
+		Operator:       cfg.Auth.Operator,
+		RedirectStatus: cfg.Web.RedirectStatus,
+		RemovedURL:     cfg.Links.RemovedURL,
+		BaseURL:        cfg.Links.BaseURL,
+		Gate:           gate,
+		Limiter:        ratelimit.New(cfg.Links.MaxPasswordFailures, cfg.Links.PasswordLockout),
+		Clicks:         clicks,
+		RealIP:         realIP,
 	})
 
 	// Construct a server to service requests against the mux.
@@ -165,6 +856,7 @@ func run(logger *zap.SugaredLogger) error {
 		WriteTimeout: cfg.Web.WriteTimeout,
 		IdleTimeout:  cfg.Web.IdleTimeout,
 		ErrorLog:     zap.NewStdLog(logger.Desugar()),
+		ConnContext:  web.ConnContext,
 	}
 
 	// Make a channel to listen to errors coming from the listener. Use a buffered channel so the
@@ -194,14 +886,55 @@ func run(logger *zap.SugaredLogger) error {
 		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
 		defer cancel()
 
-		// Asking listener to shut down and shed load.
+		// Asking listener to shut down and shed load. The queued work below is drained even when
+		// this fails, so that nothing accepted before the signal is lost.
+		var serverErr error
 		if err := api.Shutdown(ctx); err != nil {
 			api.Close()
-			return fmt.Errorf("could not stop server gracefully: %w", err)
+			serverErr = fmt.Errorf("could not stop server gracefully: %w", err)
+			logger.Errorw("shutdown", "status", "could not stop server gracefully", "ERROR", err)
+		}
+
+		// Let the bulk jobs in progress finish, cancelling whatever is left when time runs out.
+		if err := bulkCore.Shutdown(ctx); err != nil {
+			logger.Errorw("shutdown", "status", "could not stop bulk jobs gracefully", "ERROR", err)
 		}
-	}
 
-	return nil
+		// Store the clicks still waiting on the queue now that no more can come in.
+		if err := clicks.Shutdown(ctx); err != nil {
+			logger.Errorw("shutdown", "status", "could not drain click events", "ERROR", err)
+		}
+
+		// Stop the background jobs, letting any run in progress finish.
+		if err := sweeper.Shutdown(ctx); err != nil {
+			logger.Errorw("shutdown", "status", "could not stop sweeper gracefully", "ERROR", err)
+		}
+		if err := syncer.Shutdown(ctx); err != nil {
+			logger.Errorw("shutdown", "status", "could not stop token syncer gracefully", "ERROR", err)
+		}
+		if reindexer != nil {
+			if err := reindexer.Shutdown(ctx); err != nil {
+				logger.Errorw("shutdown", "status", "could not stop search reindexer gracefully", "ERROR", err)
+			}
+		}
+		if rescanner != nil {
+			if err := rescanner.Shutdown(ctx); err != nil {
+				logger.Errorw("shutdown", "status", "could not stop threat rescanner gracefully", "ERROR", err)
+			}
+		}
+		if listReloader != nil {
+			if err := listReloader.Shutdown(ctx); err != nil {
+				logger.Errorw("shutdown", "status", "could not stop threat list reloader gracefully", "ERROR", err)
+			}
+		}
+		if geoReloader != nil {
+			if err := geoReloader.Shutdown(ctx); err != nil {
+				logger.Errorw("shutdown", "status", "could not stop geoip reloader gracefully", "ERROR", err)
+			}
+		}
+
+		return serverErr
+	}
 }
 
 func initLogger(service string) (*zap.SugaredLogger, error) {
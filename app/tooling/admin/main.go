@@ -1,41 +1,229 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"time"
 
+	"github.com/yashshah7197/shrt/business/data/schema"
+	"github.com/yashshah7197/shrt/business/sys/database"
+	"github.com/yashshah7197/shrt/business/sys/reputation"
+
+	"github.com/ardanlabs/conf"
+	"github.com/google/uuid"
 	"github.com/lestrrat-go/jwx/jwa"
 	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/lestrrat-go/jwx/jwt"
 )
 
 func main() {
-	// Generate a new private/public key pair.
-	err := genKeyPair()
-	if err != nil {
+	if err := run(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+}
 
-	// Generate a new, signed JSON Web Token.
-	tokenString, err := genToken()
+func run() error {
+	cfg := struct {
+		conf.Version
+		Args conf.Args
+		DB   struct {
+			User       string `conf:"default:postgres"`
+			Password   string `conf:"default:postgres,mask"`
+			Host       string `conf:"default:localhost"`
+			Name       string `conf:"default:postgres"`
+			DisableTLS bool   `conf:"default:true"`
+		}
+	}{
+		Version: conf.Version{
+			SVN:  "develop",
+			Desc: "Copyright Yash Shah, 2022",
+		},
+	}
+
+	const prefix = "SHRT"
+	help, err := conf.ParseOSArgs(prefix, &cfg)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	switch cfg.Args.Num(0) {
+	case "migrate":
+		dbConfig := database.Config{
+			User:         cfg.DB.User,
+			Password:     cfg.DB.Password,
+			Host:         cfg.DB.Host,
+			Name:         cfg.DB.Name,
+			MaxIdleConns: 1,
+			MaxOpenConns: 1,
+			DisableTLS:   cfg.DB.DisableTLS,
+		}
+
+		return migrate(dbConfig)
+
+	case "threathash":
+		return threatHash(cfg.Args.Num(1))
+
+	case "threatserve":
+		var paths []string
+		if len(cfg.Args) > 2 {
+			paths = cfg.Args[2:]
+		}
+		return threatServe(cfg.Args.Num(1), paths)
+
+	case "":
+		// Generate a new private/public key pair.
+		if err := genKeyPair(); err != nil {
+			return err
+		}
+
+		// Generate a new, signed JSON Web Token.
+		tokenString, err := genToken()
+		if err != nil {
+			return err
+		}
+
+		// Verify the token.
+		return verifyToken(tokenString)
+
+	default:
+		fmt.Println("migrate: apply any pending database schema migrations")
+		fmt.Println("threathash <url>: print the threat list hashes of a url's expressions")
+		fmt.Println("threatserve <addr> <list>...: serve threat lists as a stand-in reputation provider")
+		fmt.Println("provide a command to get more help.")
+		return fmt.Errorf("unknown command: %q", cfg.Args.Num(0))
 	}
+}
 
-	// Verify the token.
-	err = verifyToken(tokenString)
+// migrate brings the database schema up to date with the embedded migrations.
+func migrate(cfg database.Config) error {
+	db, err := database.Open(cfg)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := database.StatusCheck(ctx, db); err != nil {
+		return fmt.Errorf("checking database status: %w", err)
+	}
+
+	before, err := schema.Version(ctx, db)
+	if err != nil {
+		return fmt.Errorf("querying schema version: %w", err)
+	}
+
+	if err := schema.Migrate(ctx, db); err != nil {
+		return fmt.Errorf("migrating database: %w", err)
+	}
+
+	after, err := schema.Version(ctx, db)
+	if err != nil {
+		return fmt.Errorf("querying schema version: %w", err)
+	}
+
+	if before == after {
+		fmt.Println("migrations already up to date at version", after)
+		return nil
 	}
+
+	fmt.Printf("migrations complete: version %d -> %d\n", before, after)
+
+	return nil
+}
+
+// threatHash prints every expression of the URL along with the hex encoded SHA-256 hash which
+// would put it on a threat list. Listing the hash of a host with a path of / covers the whole host.
+func threatHash(rawURL string) error {
+	if rawURL == "" {
+		return errors.New("threathash: missing url")
+	}
+
+	exprs, err := reputation.Expressions(rawURL)
+	if err != nil {
+		return fmt.Errorf("breaking down url: %w", err)
+	}
+
+	hashes, err := reputation.Hashes(rawURL)
+	if err != nil {
+		return fmt.Errorf("hashing url: %w", err)
+	}
+
+	for i, expr := range exprs {
+		fmt.Printf("%s %s\n", hex.EncodeToString(hashes[i][:]), expr)
+	}
+
+	return nil
+}
+
+// threatServe serves the threat lists in the given files over the lookup protocol of the HTTP
+// reputation provider, so that the provider can be tried out without a real one.
+func threatServe(addr string, paths []string) error {
+	if addr == "" || len(paths) == 0 {
+		return errors.New("threatserve: missing address or list files")
+	}
+
+	lists, err := reputation.OpenLocal(paths)
+	if err != nil {
+		return fmt.Errorf("opening threat lists: %w", err)
+	}
+
+	lookup := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if _, err := lists.Reload(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var lr reputation.LookupRequest
+		if err := json.NewDecoder(r.Body).Decode(&lr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp := reputation.LookupResponse{
+			Matches: []reputation.Match{},
+		}
+		for _, s := range lr.Prefixes {
+			b, err := hex.DecodeString(s)
+			if err != nil || len(b) != reputation.PrefixLength {
+				http.Error(w, fmt.Sprintf("invalid prefix %q", s), http.StatusBadRequest)
+				return
+			}
+
+			var prefix [reputation.PrefixLength]byte
+			copy(prefix[:], b)
+			resp.Matches = append(resp.Matches, lists.Find(prefix)...)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+
+	fmt.Printf("serving %d threat list entries on %s\n", lists.Len(), addr)
+
+	return http.ListenAndServe(addr, http.HandlerFunc(lookup))
 }
 
 // genKeyPair generates a new x509 private/public keypair for auth tokens.
@@ -127,13 +315,15 @@ func genToken() (string, error) {
 		return "", fmt.Errorf("setting kid header: %w", err)
 	}
 
-	// Generate a new JSON Web Token.
+	// Generate a new JSON Web Token. It carries an ID so that it can be revoked by signing out.
 	token, err := jwt.NewBuilder().
+		JwtID(uuid.NewString()).
 		Issuer("shrt-api").
 		Subject("b0ef2788-614a-47b6-a7ba-c0c7c75f6d7f").
 		IssuedAt(time.Now().UTC()).
 		Expiration(time.Now().Add(8760*time.Hour).UTC()).
-		Claim("roles", []string{"admin"}).
+		Claim("workspace", "default").
+		Claim("role", "OWNER").
 		Build()
 	if err != nil {
 		return "", fmt.Errorf("generating jwt: %w", err)
@@ -0,0 +1,189 @@
+// Package apikey provides the core business API for the long-lived API keys which scripts and CI
+// jobs authenticate with. A key carries the same claims as a JWT of the member who created it, with
+// its role narrowed down to its scopes, and it can be revoked at any time.
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/codegen"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound   = errors.New("api key not found")
+	ErrInvalidKey = errors.New("api key is invalid or expired")
+)
+
+// The shape of the keys handed out. The random part is long enough that keys can't be guessed, and
+// the first few characters of it are kept to recognise a key by.
+const (
+	secretAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	secretLength   = 40
+	prefixLength   = 6
+)
+
+// lastUsedResolution is how stale the recorded last use of a key may get, which saves writing to
+// the store on every single request.
+const lastUsedResolution = time.Minute
+
+// Storer defines the behavior required to persist and retrieve API keys. Implementations must be
+// safe for concurrent use.
+type Storer interface {
+	Create(ctx context.Context, k Key) error
+	Update(ctx context.Context, k Key) error
+	Delete(ctx context.Context, id string) error
+	QueryByID(ctx context.Context, id string) (Key, error)
+	QueryByHash(ctx context.Context, hash string) (Key, error)
+	QueryByOwner(ctx context.Context, workspace string, owner string) ([]Key, error)
+}
+
+// Core manages the set of APIs for API key access.
+type Core struct {
+	storer    Storer
+	generator *codegen.Random
+}
+
+// NewCore constructs a Core for API key access.
+func NewCore(storer Storer) (*Core, error) {
+	alphabet, err := codegen.NewAlphabet(secretAlphabet)
+	if err != nil {
+		return nil, fmt.Errorf("constructing alphabet: %w", err)
+	}
+
+	generator, err := codegen.NewRandom(alphabet, secretLength)
+	if err != nil {
+		return nil, fmt.Errorf("constructing generator: %w", err)
+	}
+
+	c := Core{
+		storer:    storer,
+		generator: generator,
+	}
+
+	return &c, nil
+}
+
+// Create issues a new API key acting for the specified member of the workspace. A key can't be
+// given scopes beyond the role the member holds in the workspace.
+func (c *Core) Create(ctx context.Context, nk NewKey, workspace string, owner string, role string, now time.Time) (IssuedKey, error) {
+	if err := nk.Validate(now); err != nil {
+		return IssuedKey{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	for _, scope := range nk.Scopes {
+		if rank(scopeRole(scope)) > rank(role) {
+			return IssuedKey{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "scopes", Error: fmt.Sprintf("%s is beyond your role in the workspace", scope)}})
+		}
+	}
+
+	random, err := c.generator.Generate(ctx)
+	if err != nil {
+		return IssuedKey{}, fmt.Errorf("generating key: %w", err)
+	}
+	secret := auth.KeyPrefix + random
+
+	k := Key{
+		ID:          uuid.NewString(),
+		Name:        nk.Name,
+		Prefix:      secret[:len(auth.KeyPrefix)+prefixLength],
+		Workspace:   workspace,
+		Owner:       owner,
+		Scopes:      nk.Scopes,
+		Hash:        hash(secret),
+		ExpiresAt:   nk.ExpiresAt,
+		DateCreated: now,
+	}
+
+	if err := c.storer.Create(ctx, k); err != nil {
+		return IssuedKey{}, fmt.Errorf("create: %w", err)
+	}
+
+	return IssuedKey{Key: k, Secret: secret}, nil
+}
+
+// Delete revokes the API key identified by the given ID.
+func (c *Core) Delete(ctx context.Context, id string) error {
+	if err := c.storer.Delete(ctx, id); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByID gets the API key identified by the given ID.
+func (c *Core) QueryByID(ctx context.Context, id string) (Key, error) {
+	k, err := c.storer.QueryByID(ctx, id)
+	if err != nil {
+		return Key{}, fmt.Errorf("query: %w", err)
+	}
+
+	return k, nil
+}
+
+// QueryByOwner gets every API key the specified member of the workspace has created, newest first.
+func (c *Core) QueryByOwner(ctx context.Context, workspace string, owner string) ([]Key, error) {
+	keys, err := c.storer.QueryByOwner(ctx, workspace, owner)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].DateCreated.After(keys[j].DateCreated)
+	})
+
+	return keys, nil
+}
+
+// ValidateKey finds the live API key matching the given key and recreates the claims it acts with.
+// The time the key was last used at is recorded along the way.
+func (c *Core) ValidateKey(ctx context.Context, key string, now time.Time) (auth.Claims, error) {
+	k, err := c.storer.QueryByHash(ctx, hash(key))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return auth.Claims{}, ErrInvalidKey
+		}
+		return auth.Claims{}, fmt.Errorf("query: %w", err)
+	}
+
+	if k.Expired(now) {
+		return auth.Claims{}, ErrInvalidKey
+	}
+
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= lastUsedResolution {
+		k.LastUsedAt = &now
+		if err := c.storer.Update(ctx, k); err != nil {
+			return auth.Claims{}, fmt.Errorf("update: %w", err)
+		}
+	}
+
+	claims := auth.Claims{
+		Issuer:    "shrt-api",
+		Subject:   k.Owner,
+		Workspace: k.Workspace,
+		Role:      k.Role(),
+		IssuedAt:  k.DateCreated,
+	}
+	if k.ExpiresAt != nil {
+		claims.ExpiresAt = *k.ExpiresAt
+	}
+
+	return claims, nil
+}
+
+// hash returns the form in which a key is stored and looked up. Keys are long and random, so a
+// single round of SHA-256 is enough to keep them safe.
+func hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,147 @@
+package apikey
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// These are the expected values for Key.Scopes. Each scope grants what the matching role in the
+// workspace may do: reading grants the viewer role, writing the editor role and managing the
+// owner role.
+const (
+	ScopeRead   = "read"
+	ScopeWrite  = "write"
+	ScopeManage = "manage"
+)
+
+// scopeRoles maps every scope to the role it grants, ordered from the least to the most powerful.
+var scopeRoles = []struct {
+	scope string
+	role  string
+}{
+	{ScopeRead, auth.RoleViewer},
+	{ScopeWrite, auth.RoleEditor},
+	{ScopeManage, auth.RoleOwner},
+}
+
+// nameMaxLength bounds the length of the name of a key.
+const nameMaxLength = 100
+
+// Key represents a long-lived API key which scripts and CI jobs authenticate with instead of a JWT.
+// A key acts for the member of the workspace who created it, but only within its scopes. Only a
+// hash of the key is ever kept; the key itself is shown once when it is created, and afterwards it
+// can only be recognised by its prefix.
+type Key struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Workspace   string     `json:"workspace"`
+	Owner       string     `json:"owner"`
+	Scopes      []string   `json:"scopes"`
+	Hash        string     `json:"-"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	DateCreated time.Time  `json:"date_created"`
+}
+
+// Expired reports whether the key has expired at the given time.
+func (k Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Role returns the role the key acts with, which is the one granted by the most powerful of its
+// scopes.
+func (k Key) Role() string {
+	var role string
+	for _, sr := range scopeRoles {
+		if hasScope(k.Scopes, sr.scope) {
+			role = sr.role
+		}
+	}
+
+	return role
+}
+
+// IssuedKey is a key as it is handed out when it is created, the only time the key itself is ever
+// shown.
+type IssuedKey struct {
+	Key
+	Secret string `json:"key"`
+}
+
+// NewKey contains the information needed to create a new API key. A key without an expiry lasts
+// until it is revoked.
+type NewKey struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Validate checks that the information for a new key is valid at the given time.
+func (nk NewKey) Validate(now time.Time) error {
+	var fields validate.FieldErrors
+
+	switch {
+	case strings.TrimSpace(nk.Name) == "":
+		fields.Add("name", "must not be blank")
+	case len(nk.Name) > nameMaxLength:
+		fields.Add("name", fmt.Sprintf("must be at most %d characters long", nameMaxLength))
+	}
+
+	if len(nk.Scopes) == 0 {
+		fields.Add("scopes", "must not be empty")
+	}
+	for i, scope := range nk.Scopes {
+		if scopeRole(scope) == "" {
+			fields.Add("scopes", fmt.Sprintf("must be %s, %s or %s", ScopeRead, ScopeWrite, ScopeManage))
+			break
+		}
+		if hasScope(nk.Scopes[:i], scope) {
+			fields.Add("scopes", "must not repeat a scope")
+			break
+		}
+	}
+
+	if nk.ExpiresAt != nil && !nk.ExpiresAt.After(now) {
+		fields.Add("expires_at", "must be in the future")
+	}
+
+	return fields.Err()
+}
+
+// scopeRole returns the role granted by the scope, or a blank string for an unknown scope.
+func scopeRole(scope string) string {
+	for _, sr := range scopeRoles {
+		if sr.scope == scope {
+			return sr.role
+		}
+	}
+
+	return ""
+}
+
+// rank returns how powerful the role is, with unknown roles below every known one.
+func rank(role string) int {
+	for i, sr := range scopeRoles {
+		if sr.role == role {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// hasScope reports whether the list holds the scope.
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,222 @@
+// Package apikeydb contains the database/sql implementation of the API key storer.
+package apikeydb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/yashshah7197/shrt/business/core/apikey"
+	"github.com/yashshah7197/shrt/business/sys/database"
+)
+
+// columns lists the API key columns in the order scanKey reads them.
+const columns = `
+		key_id, name, prefix, workspace, owner, scopes, hash, expires_at, last_used_at, date_created`
+
+// Store manages the set of APIs for API key access in the database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore constructs a store for API keys backed by the given database.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create inserts a new API key into the database.
+func (s *Store) Create(ctx context.Context, k apikey.Key) error {
+	const q = `
+	INSERT INTO api_keys (` + columns + `)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	if _, err := s.db.ExecContext(ctx, q,
+		k.ID,
+		k.Name,
+		k.Prefix,
+		k.Workspace,
+		k.Owner,
+		pq.Array(k.Scopes),
+		k.Hash,
+		nullTime(k.ExpiresAt),
+		nullTime(k.LastUsedAt),
+		k.DateCreated.UTC(),
+	); err != nil {
+		return fmt.Errorf("inserting api key: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces an API key in the database. Only the name and the time of last use of a key ever
+// change.
+func (s *Store) Update(ctx context.Context, k apikey.Key) error {
+	const q = `
+	UPDATE
+		api_keys
+	SET
+		name = $2,
+		last_used_at = $3
+	WHERE
+		key_id = $1`
+
+	res, err := s.db.ExecContext(ctx, q, k.ID, k.Name, nullTime(k.LastUsedAt))
+	if err != nil {
+		return fmt.Errorf("updating api key[%s]: %w", k.ID, err)
+	}
+
+	return checkAffected(res)
+}
+
+// Delete removes the API key identified by the given ID from the database.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	const q = `
+	DELETE FROM
+		api_keys
+	WHERE
+		key_id = $1`
+
+	res, err := s.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return fmt.Errorf("deleting api key[%s]: %w", id, err)
+	}
+
+	return checkAffected(res)
+}
+
+// QueryByID gets the API key identified by the given ID from the database.
+func (s *Store) QueryByID(ctx context.Context, id string) (apikey.Key, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		api_keys
+	WHERE
+		key_id = $1`
+
+	k, err := scanKey(s.db.QueryRowContext(ctx, q, id))
+	if err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return apikey.Key{}, apikey.ErrNotFound
+		}
+		return apikey.Key{}, fmt.Errorf("selecting api key[%s]: %w", id, err)
+	}
+
+	return k, nil
+}
+
+// QueryByHash gets the API key with the given hash from the database.
+func (s *Store) QueryByHash(ctx context.Context, hash string) (apikey.Key, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		api_keys
+	WHERE
+		hash = $1`
+
+	k, err := scanKey(s.db.QueryRowContext(ctx, q, hash))
+	if err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return apikey.Key{}, apikey.ErrNotFound
+		}
+		return apikey.Key{}, fmt.Errorf("selecting api key by hash: %w", err)
+	}
+
+	return k, nil
+}
+
+// QueryByOwner gets every API key the specified member of the workspace has created from the
+// database.
+func (s *Store) QueryByOwner(ctx context.Context, workspace string, owner string) ([]apikey.Key, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		api_keys
+	WHERE
+		workspace = $1 AND owner = $2`
+
+	rows, err := s.db.QueryContext(ctx, q, workspace, owner)
+	if err != nil {
+		return nil, fmt.Errorf("selecting api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []apikey.Key{}
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// scanner is implemented by both sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanKey reads a single API key out of a row.
+func scanKey(row scanner) (apikey.Key, error) {
+	var (
+		k          apikey.Key
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+	)
+	if err := row.Scan(
+		&k.ID,
+		&k.Name,
+		&k.Prefix,
+		&k.Workspace,
+		&k.Owner,
+		pq.Array(&k.Scopes),
+		&k.Hash,
+		&expiresAt,
+		&lastUsedAt,
+		&k.DateCreated,
+	); err != nil {
+		return apikey.Key{}, err
+	}
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return k, nil
+}
+
+// nullTime converts an optional time into its database form.
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// checkAffected translates a statement that touched no rows into apikey.ErrNotFound.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking affected rows: %w", err)
+	}
+	if n == 0 {
+		return apikey.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+// Package apikeyfile contains a durable, single-file implementation of the API key storer. Every
+// change is appended to a journal on disk and the keys are kept in memory for reads.
+package apikeyfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/apikey"
+	"github.com/yashshah7197/shrt/business/core/apikey/stores/apikeymem"
+	"github.com/yashshah7197/shrt/foundation/journal"
+)
+
+// The set of operations recorded in the journal.
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// record is the form in which an API key is written to the journal. The hash is left out of the
+// JSON form of a key so that it never reaches API clients, so it is carried alongside it here.
+type record struct {
+	apikey.Key
+	Hash string `json:"hash"`
+}
+
+// Store manages the set of APIs for API key access backed by a journal file.
+type Store struct {
+	mu      sync.Mutex
+	mem     *apikeymem.Store
+	journal *journal.Journal
+}
+
+// Open constructs a store for API keys by replaying the journal at the given path.
+func Open(path string) (*Store, error) {
+	ctx := context.Background()
+	mem := apikeymem.NewStore()
+
+	// Rebuild the in-memory state from the journal records.
+	replay := func(rec journal.Record) error {
+		switch rec.Op {
+		case opPut:
+			var r record
+			if err := json.Unmarshal(rec.Data, &r); err != nil {
+				return err
+			}
+			k := r.Key
+			k.Hash = r.Hash
+
+			// Records hold the full state of a key, so replace whatever was there before.
+			return mem.Create(ctx, k)
+
+		case opDelete:
+			return mem.Delete(ctx, rec.Key)
+
+		default:
+			return fmt.Errorf("unknown operation %q", rec.Op)
+		}
+	}
+
+	jrnl, err := journal.Open(path, replay)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	s := Store{
+		mem:     mem,
+		journal: jrnl,
+	}
+
+	// Every use of a key is recorded now and then, so start off with a compact journal every time.
+	snapshot := func(emit func(op string, key string, data interface{}) error) error {
+		for _, k := range mem.All() {
+			if err := emit(opPut, k.ID, record{Key: k, Hash: k.Hash}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := jrnl.Compact(snapshot); err != nil {
+		jrnl.Close()
+		return nil, fmt.Errorf("compacting journal: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Close closes the underlying journal file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.journal.Close()
+}
+
+// Create inserts a new API key into the store.
+func (s *Store) Create(ctx context.Context, k apikey.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.journal.Append(opPut, k.ID, record{Key: k, Hash: k.Hash}); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Create(ctx, k)
+}
+
+// Update replaces an API key in the store.
+func (s *Store) Update(ctx context.Context, k apikey.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByID(ctx, k.ID); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opPut, k.ID, record{Key: k, Hash: k.Hash}); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Update(ctx, k)
+}
+
+// Delete removes the API key identified by the given ID from the store.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByID(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opDelete, id, nil); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Delete(ctx, id)
+}
+
+// QueryByID gets the API key identified by the given ID from the store.
+func (s *Store) QueryByID(ctx context.Context, id string) (apikey.Key, error) {
+	return s.mem.QueryByID(ctx, id)
+}
+
+// QueryByHash gets the API key with the given hash from the store.
+func (s *Store) QueryByHash(ctx context.Context, hash string) (apikey.Key, error) {
+	return s.mem.QueryByHash(ctx, hash)
+}
+
+// QueryByOwner gets every API key the specified member of the workspace has created.
+func (s *Store) QueryByOwner(ctx context.Context, workspace string, owner string) ([]apikey.Key, error) {
+	return s.mem.QueryByOwner(ctx, workspace, owner)
+}
@@ -0,0 +1,114 @@
+// Package apikeymem contains a concurrency-safe, in-memory implementation of the API key storer. It
+// is intended for tests and local development since nothing survives a restart.
+package apikeymem
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/apikey"
+)
+
+// Store manages the set of APIs for API key access held in memory.
+type Store struct {
+	mu   sync.RWMutex
+	keys map[string]apikey.Key
+}
+
+// NewStore constructs an empty in-memory store for API keys.
+func NewStore() *Store {
+	return &Store{
+		keys: make(map[string]apikey.Key),
+	}
+}
+
+// Create inserts a new API key into the store.
+func (s *Store) Create(ctx context.Context, k apikey.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[k.ID] = k
+
+	return nil
+}
+
+// Update replaces an API key in the store.
+func (s *Store) Update(ctx context.Context, k apikey.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.keys[k.ID]; !exists {
+		return apikey.ErrNotFound
+	}
+	s.keys[k.ID] = k
+
+	return nil
+}
+
+// Delete removes the API key identified by the given ID from the store.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.keys[id]; !exists {
+		return apikey.ErrNotFound
+	}
+	delete(s.keys, id)
+
+	return nil
+}
+
+// QueryByID gets the API key identified by the given ID from the store.
+func (s *Store) QueryByID(ctx context.Context, id string) (apikey.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, exists := s.keys[id]
+	if !exists {
+		return apikey.Key{}, apikey.ErrNotFound
+	}
+
+	return k, nil
+}
+
+// QueryByHash gets the API key with the given hash from the store.
+func (s *Store) QueryByHash(ctx context.Context, hash string) (apikey.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			return k, nil
+		}
+	}
+
+	return apikey.Key{}, apikey.ErrNotFound
+}
+
+// QueryByOwner gets every API key the specified member of the workspace has created.
+func (s *Store) QueryByOwner(ctx context.Context, workspace string, owner string) ([]apikey.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []apikey.Key{}
+	for _, k := range s.keys {
+		if k.Workspace == workspace && k.Owner == owner {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys, nil
+}
+
+// All returns every API key held in the store.
+func (s *Store) All() []apikey.Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]apikey.Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
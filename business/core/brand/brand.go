@@ -0,0 +1,167 @@
+// Package brand provides the core business API for the branded domains the service answers on.
+// Each domain has a namespace of short codes of its own, along with its own redirect status, not
+// found page and set of subjects allowed to create links on it. Links which are not on a branded
+// domain live in the default namespace, which is served on every other hostname.
+package brand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound = errors.New("domain not found")
+	ErrExists   = errors.New("domain already exists")
+	ErrInUse    = errors.New("domain still has links")
+)
+
+// Storer defines the behavior required to persist and retrieve branded domains. Implementations
+// must be safe for concurrent use. Domains are only found, updated and deleted within the given
+// workspace, or the workspace of the domain passed to Update, and any other domain is reported as
+// ErrNotFound. A blank workspace given to QueryByHost matches every workspace, which is how the
+// domain of an incoming request is found.
+type Storer interface {
+	Create(ctx context.Context, d Domain) error
+	Update(ctx context.Context, d Domain) error
+	Delete(ctx context.Context, workspace string, host string) error
+	QueryByHost(ctx context.Context, workspace string, host string) (Domain, error)
+	Query(ctx context.Context) ([]Domain, error)
+}
+
+// Core manages the set of APIs for branded domain access.
+type Core struct {
+	storer Storer
+}
+
+// NewCore constructs a Core for branded domain API access.
+func NewCore(storer Storer) *Core {
+	return &Core{
+		storer: storer,
+	}
+}
+
+// Create adds a new branded domain to the specified workspace.
+func (c *Core) Create(ctx context.Context, nd NewDomain, workspace string, createdBy string, now time.Time) (Domain, error) {
+	if err := nd.Validate(); err != nil {
+		return Domain{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	d := Domain{
+		Host:           Normalize(nd.Host),
+		Workspace:      workspace,
+		RedirectStatus: nd.RedirectStatus,
+		NotFoundURL:    nd.NotFoundURL,
+		Owners:         normalizeOwners(nd.Owners),
+		CreatedBy:      createdBy,
+		DateCreated:    now,
+		DateUpdated:    now,
+	}
+
+	if err := c.storer.Create(ctx, d); err != nil {
+		return Domain{}, fmt.Errorf("create: %w", err)
+	}
+
+	return d, nil
+}
+
+// Update modifies the settings of the branded domain identified by the given host in the specified
+// workspace. A blank workspace finds the domain whichever workspace it belongs to.
+func (c *Core) Update(ctx context.Context, workspace string, host string, ud UpdateDomain, now time.Time) (Domain, error) {
+	if err := ud.Validate(); err != nil {
+		return Domain{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	d, err := c.storer.QueryByHost(ctx, workspace, Normalize(host))
+	if err != nil {
+		return Domain{}, fmt.Errorf("query: %w", err)
+	}
+
+	if ud.RedirectStatus != nil {
+		d.RedirectStatus = *ud.RedirectStatus
+	}
+	if ud.NotFoundURL != nil {
+		d.NotFoundURL = *ud.NotFoundURL
+	}
+	if ud.Owners != nil {
+		d.Owners = normalizeOwners(*ud.Owners)
+	}
+	d.DateUpdated = now
+
+	if err := c.storer.Update(ctx, d); err != nil {
+		return Domain{}, fmt.Errorf("update: %w", err)
+	}
+
+	return d, nil
+}
+
+// Delete removes the branded domain identified by the given host in the specified workspace.
+// Callers are expected to make sure no links are left on it first.
+func (c *Core) Delete(ctx context.Context, workspace string, host string) error {
+	if err := c.storer.Delete(ctx, workspace, Normalize(host)); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByHost gets the branded domain identified by the given host, which may be the value of a
+// Host header, in the specified workspace. A blank workspace finds the domain whichever workspace
+// it belongs to.
+func (c *Core) QueryByHost(ctx context.Context, workspace string, host string) (Domain, error) {
+	d, err := c.storer.QueryByHost(ctx, workspace, Normalize(host))
+	if err != nil {
+		return Domain{}, fmt.Errorf("query: %w", err)
+	}
+
+	return d, nil
+}
+
+// Query returns every branded domain in alphabetical order.
+func (c *Core) Query(ctx context.Context) ([]Domain, error) {
+	domains, err := c.storer.Query(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].Host < domains[j].Host
+	})
+
+	return domains, nil
+}
+
+// QueryByWorkspace returns every branded domain of the specified workspace in alphabetical order.
+func (c *Core) QueryByWorkspace(ctx context.Context, workspace string) ([]Domain, error) {
+	domains, err := c.Query(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := []Domain{}
+	for _, d := range domains {
+		if d.Workspace == workspace {
+			owned = append(owned, d)
+		}
+	}
+
+	return owned, nil
+}
+
+// Hosts returns the hosts of every branded domain.
+func (c *Core) Hosts(ctx context.Context) ([]string, error) {
+	domains, err := c.Query(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]string, len(domains))
+	for i, d := range domains {
+		hosts[i] = d.Host
+	}
+
+	return hosts, nil
+}
@@ -0,0 +1,206 @@
+package brand
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/business/sys/workspace"
+)
+
+// The bounds on the list of subjects allowed to create links on a domain.
+const (
+	maxOwners      = 100
+	ownerMaxLength = 128
+)
+
+// Domain represents a branded hostname the service answers on. Every domain has a namespace of
+// short codes of its own. Visitors of a link on the domain are redirected with its redirect status
+// unless the link says otherwise, and visitors of a code which does not exist are sent to its not
+// found page when it has one. Only the listed owners may create links on the domain, and anyone may
+// when the list is empty. Every domain belongs to a workspace, and only links of that workspace can
+// be created on it, although its hostname is unique across the whole service. Domains are only
+// ever added by the operators of the service, once they have made sure the hostname belongs to
+// the workspace, since every domain counts as one of the own domains of the service.
+type Domain struct {
+	Host           string    `json:"host"`
+	Workspace      string    `json:"workspace"`
+	RedirectStatus int       `json:"redirect_status,omitempty"`
+	NotFoundURL    string    `json:"not_found_url,omitempty"`
+	Owners         []string  `json:"owners,omitempty"`
+	CreatedBy      string    `json:"created_by"`
+	DateCreated    time.Time `json:"date_created"`
+	DateUpdated    time.Time `json:"date_updated"`
+}
+
+// Allows reports whether the given subject may create links on the domain.
+func (d Domain) Allows(subject string) bool {
+	if len(d.Owners) == 0 {
+		return true
+	}
+
+	for _, owner := range d.Owners {
+		if owner == subject {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewDomain contains the information needed to add a branded domain. The domain is added to the
+// workspace of the operator adding it unless another one is given.
+type NewDomain struct {
+	Host           string   `json:"host"`
+	Workspace      string   `json:"workspace"`
+	RedirectStatus int      `json:"redirect_status"`
+	NotFoundURL    string   `json:"not_found_url"`
+	Owners         []string `json:"owners"`
+}
+
+// Validate checks that the information for a new domain is valid.
+func (nd NewDomain) Validate() error {
+	var fields validate.FieldErrors
+
+	if !isHost(Normalize(nd.Host)) {
+		fields.Add("host", "must be a domain name")
+	}
+
+	if nd.Workspace != "" {
+		if err := workspace.Validate(nd.Workspace); err != nil {
+			fields.Add("workspace", err.Error())
+		}
+	}
+
+	if nd.RedirectStatus != 0 && !isRedirectStatus(nd.RedirectStatus) {
+		fields.Add("redirect_status", "must be one of 301, 302, 307 or 308")
+	}
+
+	if nd.NotFoundURL != "" {
+		if err := validate.URL(nd.NotFoundURL); err != nil {
+			fields.Add("not_found_url", err.Error())
+		}
+	}
+
+	if err := validateOwners(nd.Owners); err != nil {
+		fields.Add("owners", err.Error())
+	}
+
+	return fields.Err()
+}
+
+// UpdateDomain defines what information may be provided to modify an existing domain. All fields
+// are optional so clients can send just the fields they want changed. A zero redirect status falls
+// back to the service default, a blank not found page to the built in one, and an empty list of
+// owners opens the domain to everyone.
+type UpdateDomain struct {
+	RedirectStatus *int      `json:"redirect_status"`
+	NotFoundURL    *string   `json:"not_found_url"`
+	Owners         *[]string `json:"owners"`
+}
+
+// Validate checks that the information for updating a domain is valid.
+func (ud UpdateDomain) Validate() error {
+	var fields validate.FieldErrors
+
+	if ud.RedirectStatus != nil && *ud.RedirectStatus != 0 && !isRedirectStatus(*ud.RedirectStatus) {
+		fields.Add("redirect_status", "must be one of 301, 302, 307 or 308")
+	}
+
+	if ud.NotFoundURL != nil && *ud.NotFoundURL != "" {
+		if err := validate.URL(*ud.NotFoundURL); err != nil {
+			fields.Add("not_found_url", err.Error())
+		}
+	}
+
+	if ud.Owners != nil {
+		if err := validateOwners(*ud.Owners); err != nil {
+			fields.Add("owners", err.Error())
+		}
+	}
+
+	return fields.Err()
+}
+
+// validateOwners checks that there are not too many owners and that none of them is blank.
+func validateOwners(owners []string) error {
+	if len(owners) > maxOwners {
+		return fmt.Errorf("must have at most %d entries", maxOwners)
+	}
+
+	for _, owner := range owners {
+		if strings.TrimSpace(owner) == "" || len(owner) > ownerMaxLength {
+			return fmt.Errorf("must be subjects of at most %d characters", ownerMaxLength)
+		}
+	}
+
+	return nil
+}
+
+// normalizeOwners drops duplicate owners, so that each subject is listed once.
+func normalizeOwners(owners []string) []string {
+	if len(owners) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(owners))
+	normalized := make([]string, 0, len(owners))
+	for _, owner := range owners {
+		if !seen[owner] {
+			seen[owner] = true
+			normalized = append(normalized, owner)
+		}
+	}
+
+	return normalized
+}
+
+// isRedirectStatus reports whether the given HTTP status code can be used to redirect a visitor to
+// the destination of a short link.
+func isRedirectStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+
+	return false
+}
+
+// isHost reports whether the string is a domain name of at least two labels.
+func isHost(s string) bool {
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+
+	labels := strings.Split(s, ".")
+	if len(labels) < 2 {
+		return false
+	}
+
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Normalize returns the form hosts are compared and stored in. It accepts the value of a Host
+// header, so any port is dropped.
+func Normalize(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+
+	return strings.TrimSuffix(host, ".")
+}
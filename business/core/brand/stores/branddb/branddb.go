@@ -0,0 +1,194 @@
+// Package branddb contains the database/sql implementation of the branded domain storer.
+package branddb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/yashshah7197/shrt/business/core/brand"
+	"github.com/yashshah7197/shrt/business/sys/database"
+)
+
+// columns lists the domain columns in the order scanDomain reads them.
+const columns = `
+		host, redirect_status, not_found_url, owners, created_by, date_created, date_updated, workspace`
+
+// Store manages the set of APIs for branded domain access in the database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore constructs a store for branded domains backed by the given database.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create inserts a new domain into the database.
+func (s *Store) Create(ctx context.Context, d brand.Domain) error {
+	const q = `
+	INSERT INTO domains (` + columns + `)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	if _, err := s.db.ExecContext(ctx, q,
+		d.Host,
+		d.RedirectStatus,
+		d.NotFoundURL,
+		pq.Array(owners(d.Owners)),
+		d.CreatedBy,
+		d.DateCreated.UTC(),
+		d.DateUpdated.UTC(),
+		d.Workspace,
+	); err != nil {
+		if database.IsDuplicatedEntry(err) {
+			return brand.ErrExists
+		}
+		return fmt.Errorf("inserting domain: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a domain in the database.
+func (s *Store) Update(ctx context.Context, d brand.Domain) error {
+	const q = `
+	UPDATE
+		domains
+	SET
+		redirect_status = $2,
+		not_found_url = $3,
+		owners = $4,
+		date_updated = $5
+	WHERE
+		host = $1 AND workspace = $6`
+
+	res, err := s.db.ExecContext(ctx, q, d.Host, d.RedirectStatus, d.NotFoundURL, pq.Array(owners(d.Owners)), d.DateUpdated.UTC(), d.Workspace)
+	if err != nil {
+		return fmt.Errorf("updating domain[%s]: %w", d.Host, err)
+	}
+
+	return checkAffected(res)
+}
+
+// Delete removes the domain identified by the given host in the specified workspace from the
+// database.
+func (s *Store) Delete(ctx context.Context, workspace string, host string) error {
+	const q = `
+	DELETE FROM
+		domains
+	WHERE
+		host = $1 AND workspace = $2`
+
+	res, err := s.db.ExecContext(ctx, q, host, workspace)
+	if err != nil {
+		return fmt.Errorf("deleting domain[%s]: %w", host, err)
+	}
+
+	return checkAffected(res)
+}
+
+// QueryByHost gets the domain identified by the given host in the specified workspace from the
+// database. A blank workspace matches every workspace.
+func (s *Store) QueryByHost(ctx context.Context, workspace string, host string) (brand.Domain, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		domains
+	WHERE
+		host = $1 AND ($2 = '' OR workspace = $2)`
+
+	d, err := scanDomain(s.db.QueryRowContext(ctx, q, host, workspace))
+	if err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return brand.Domain{}, brand.ErrNotFound
+		}
+		return brand.Domain{}, fmt.Errorf("selecting domain[%s]: %w", host, err)
+	}
+
+	return d, nil
+}
+
+// Query returns every domain held in the database.
+func (s *Store) Query(ctx context.Context) ([]brand.Domain, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		domains`
+
+	rows, err := s.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("selecting domains: %w", err)
+	}
+	defer rows.Close()
+
+	domains := []brand.Domain{}
+	for rows.Next() {
+		d, err := scanDomain(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning domain: %w", err)
+		}
+		domains = append(domains, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating domains: %w", err)
+	}
+
+	return domains, nil
+}
+
+// scanner is implemented by both sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanDomain reads a single domain out of a row.
+func scanDomain(row scanner) (brand.Domain, error) {
+	var d brand.Domain
+	if err := row.Scan(
+		&d.Host,
+		&d.RedirectStatus,
+		&d.NotFoundURL,
+		pq.Array(&d.Owners),
+		&d.CreatedBy,
+		&d.DateCreated,
+		&d.DateUpdated,
+		&d.Workspace,
+	); err != nil {
+		return brand.Domain{}, err
+	}
+	if len(d.Owners) == 0 {
+		d.Owners = nil
+	}
+
+	return d, nil
+}
+
+// owners converts the owners of a domain into their database form, which is never null.
+func owners(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+
+	return list
+}
+
+// checkAffected translates a statement that touched no rows into brand.ErrNotFound.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return brand.ErrNotFound
+	}
+
+	return nil
+}
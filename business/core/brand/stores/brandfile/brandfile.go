@@ -0,0 +1,159 @@
+// Package brandfile contains a durable, single-file implementation of the branded domain storer.
+// Every change is appended to a journal on disk and the domains are kept in memory for reads.
+package brandfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/brand"
+	"github.com/yashshah7197/shrt/business/core/brand/stores/brandmem"
+	"github.com/yashshah7197/shrt/business/sys/workspace"
+	"github.com/yashshah7197/shrt/foundation/journal"
+)
+
+// The set of operations recorded in the journal.
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// Store manages the set of APIs for branded domain access backed by a journal file.
+type Store struct {
+	mu      sync.Mutex
+	mem     *brandmem.Store
+	journal *journal.Journal
+}
+
+// Open constructs a store for branded domains by replaying the journal at the given path.
+func Open(path string) (*Store, error) {
+	ctx := context.Background()
+	mem := brandmem.NewStore()
+
+	// Rebuild the in-memory state from the journal records.
+	replay := func(rec journal.Record) error {
+		switch rec.Op {
+		case opPut:
+			var d brand.Domain
+			if err := json.Unmarshal(rec.Data, &d); err != nil {
+				return err
+			}
+
+			// Domains recorded before workspaces existed belong to the default one.
+			if d.Workspace == "" {
+				d.Workspace = workspace.Default
+			}
+
+			// Records hold the full state of a domain, so replace whatever was there before.
+			mem.Remove(d.Host)
+			return mem.Create(ctx, d)
+
+		case opDelete:
+			mem.Remove(rec.Key)
+			return nil
+
+		default:
+			return fmt.Errorf("unknown operation %q", rec.Op)
+		}
+	}
+
+	jrnl, err := journal.Open(path, replay)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	s := Store{
+		mem:     mem,
+		journal: jrnl,
+	}
+
+	// Domains change rarely, so simply start off with a compact journal every time.
+	snapshot := func(emit func(op string, key string, data interface{}) error) error {
+		domains, err := mem.Query(ctx)
+		if err != nil {
+			return err
+		}
+		for _, d := range domains {
+			if err := emit(opPut, d.Host, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := jrnl.Compact(snapshot); err != nil {
+		jrnl.Close()
+		return nil, fmt.Errorf("compacting journal: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Close closes the underlying journal file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.journal.Close()
+}
+
+// Create inserts a new domain into the store.
+func (s *Store) Create(ctx context.Context, d brand.Domain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByHost(ctx, "", d.Host); err == nil {
+		return brand.ErrExists
+	}
+
+	if err := s.journal.Append(opPut, d.Host, d); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Create(ctx, d)
+}
+
+// Update replaces a domain in the store.
+func (s *Store) Update(ctx context.Context, d brand.Domain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByHost(ctx, d.Workspace, d.Host); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opPut, d.Host, d); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Update(ctx, d)
+}
+
+// Delete removes the domain identified by the given host in the specified workspace from the store.
+func (s *Store) Delete(ctx context.Context, workspace string, host string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByHost(ctx, workspace, host); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opDelete, host, nil); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Delete(ctx, workspace, host)
+}
+
+// QueryByHost gets the domain identified by the given host in the specified workspace from the
+// store. A blank workspace matches every workspace.
+func (s *Store) QueryByHost(ctx context.Context, workspace string, host string) (brand.Domain, error) {
+	return s.mem.QueryByHost(ctx, workspace, host)
+}
+
+// Query returns every domain held in the store.
+func (s *Store) Query(ctx context.Context) ([]brand.Domain, error) {
+	return s.mem.Query(ctx)
+}
@@ -0,0 +1,98 @@
+// Package brandmem contains a concurrency-safe, in-memory implementation of the branded domain
+// storer. It is intended for tests and local development since nothing survives a restart.
+package brandmem
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/brand"
+)
+
+// Store manages the set of APIs for branded domain access held in memory.
+type Store struct {
+	mu      sync.RWMutex
+	domains map[string]brand.Domain
+}
+
+// NewStore constructs an empty in-memory store for branded domains.
+func NewStore() *Store {
+	return &Store{
+		domains: make(map[string]brand.Domain),
+	}
+}
+
+// Create inserts a new domain into the store.
+func (s *Store) Create(ctx context.Context, d brand.Domain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.domains[d.Host]; exists {
+		return brand.ErrExists
+	}
+	s.domains[d.Host] = d
+
+	return nil
+}
+
+// Update replaces a domain in the store.
+func (s *Store) Update(ctx context.Context, d brand.Domain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, exists := s.domains[d.Host]; !exists || existing.Workspace != d.Workspace {
+		return brand.ErrNotFound
+	}
+	s.domains[d.Host] = d
+
+	return nil
+}
+
+// Delete removes the domain identified by the given host in the specified workspace from the store.
+func (s *Store) Delete(ctx context.Context, workspace string, host string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d, exists := s.domains[host]; !exists || d.Workspace != workspace {
+		return brand.ErrNotFound
+	}
+	delete(s.domains, host)
+
+	return nil
+}
+
+// QueryByHost gets the domain identified by the given host in the specified workspace from the
+// store. A blank workspace matches every workspace.
+func (s *Store) QueryByHost(ctx context.Context, workspace string, host string) (brand.Domain, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, exists := s.domains[host]
+	if !exists || workspace != "" && d.Workspace != workspace {
+		return brand.Domain{}, brand.ErrNotFound
+	}
+
+	return d, nil
+}
+
+// Remove removes the domain identified by the given host from the store, whichever workspace it
+// belongs to. It is meant for replaying a journal.
+func (s *Store) Remove(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.domains, host)
+}
+
+// Query returns every domain held in the store.
+func (s *Store) Query(ctx context.Context) ([]brand.Domain, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	domains := make([]brand.Domain, 0, len(s.domains))
+	for _, d := range s.domains {
+		domains = append(domains, d)
+	}
+
+	return domains, nil
+}
@@ -0,0 +1,352 @@
+// Package bulk provides the core business API for creating short links in bulk from CSV and NDJSON
+// uploads. Uploads are parsed as a stream, one row at a time, so their size doesn't dictate how
+// much memory they take.
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/link"
+	"github.com/yashshah7197/shrt/business/sys/metrics"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Set of error variables for bulk operations.
+var (
+	ErrNotFound      = errors.New("job not found")
+	ErrInvalidUpload = errors.New("invalid upload")
+	ErrShuttingDown  = errors.New("bulk uploads are shutting down")
+	ErrTooLarge      = errors.New("upload is too large")
+)
+
+// Config represents the dependencies and settings required by the Core.
+type Config struct {
+	Link        *link.Core
+	Logger      *zap.SugaredLogger
+	MaxRows     int
+	MaxResults  int
+	MaxSize     int64
+	MaxJobs     int
+	Retention   time.Duration
+	SpoolFolder string
+}
+
+// Core manages the set of APIs for bulk uploads. Uploads can be processed right away, or spooled
+// to disk and processed in the background as a job which is polled for its outcome. Jobs are only
+// kept in memory, for the retention period once they have finished, and keep the outcome of at
+// most MaxResults rows each.
+type Core struct {
+	link        *link.Core
+	logger      *zap.SugaredLogger
+	maxRows     int
+	maxResults  int
+	maxSize     int64
+	retention   time.Duration
+	spoolFolder string
+	slots       chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+
+	mu       sync.RWMutex
+	jobs     map[string]*Job
+	shutdown bool
+}
+
+// NewCore constructs a Core for bulk upload API access. At most MaxJobs background jobs run at
+// the same time; the rest wait for their turn.
+func NewCore(cfg Config) *Core {
+	// Background jobs run outside of any request, so they carry their own metrics.
+	ctx, cancel := context.WithCancel(metrics.Set(context.Background()))
+
+	return &Core{
+		link:        cfg.Link,
+		logger:      cfg.Logger,
+		maxRows:     cfg.MaxRows,
+		maxResults:  cfg.MaxResults,
+		maxSize:     cfg.MaxSize,
+		retention:   cfg.Retention,
+		spoolFolder: cfg.SpoolFolder,
+		slots:       make(chan struct{}, cfg.MaxJobs),
+		ctx:         ctx,
+		cancel:      cancel,
+		jobs:        make(map[string]*Job),
+	}
+}
+
+// Run processes an upload straight from the given reader into the given workspace on behalf of the
+// given owner and returns the finished job. It is meant for uploads small enough to be handled
+// within a single request.
+func (c *Core) Run(ctx context.Context, workspace string, owner string, format string, r io.Reader, now time.Time) (Job, error) {
+	job := newJob(workspace, owner, format, now)
+
+	if err := c.process(ctx, &job, r, func() time.Time { return now }); err != nil {
+		return Job{}, err
+	}
+
+	return job, nil
+}
+
+// Start spools an upload to disk on behalf of the given owner and processes it into the given
+// workspace in the background. The returned job is still pending; its progress can be followed
+// through QueryByID. Uploads of more than MaxSize bytes are turned down with ErrTooLarge.
+func (c *Core) Start(ctx context.Context, workspace string, owner string, format string, r io.Reader, now time.Time) (Job, error) {
+	file, err := os.CreateTemp(c.spoolFolder, "shrt-bulk-*")
+	if err != nil {
+		return Job{}, fmt.Errorf("creating spool file: %w", err)
+	}
+
+	discard := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	n, err := io.Copy(file, io.LimitReader(r, c.maxSize+1))
+	if err != nil {
+		discard()
+		return Job{}, fmt.Errorf("spooling upload: %w", err)
+	}
+	if n > c.maxSize {
+		discard()
+		return Job{}, ErrTooLarge
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		discard()
+		return Job{}, fmt.Errorf("rewinding spool file: %w", err)
+	}
+
+	job := newJob(workspace, owner, format, now)
+
+	c.mu.Lock()
+	if c.shutdown {
+		c.mu.Unlock()
+		discard()
+		return Job{}, ErrShuttingDown
+	}
+	c.prune(now)
+	c.jobs[job.ID] = &job
+	c.wg.Add(1)
+	c.mu.Unlock()
+
+	go func() {
+		defer c.wg.Done()
+		defer discard()
+
+		// Wait for a free slot, unless shutdown gives up on the job first.
+		select {
+		case c.slots <- struct{}{}:
+			defer func() { <-c.slots }()
+		case <-c.ctx.Done():
+			c.fail(&job, "cancelled by shutdown")
+			return
+		}
+
+		if err := c.process(c.ctx, &job, file, func() time.Time { return time.Now().UTC() }); err != nil {
+			c.logger.Errorw("bulk", "status", "job failed", "id", job.ID, "ERROR", err)
+		}
+	}()
+
+	return c.snapshot(&job), nil
+}
+
+// QueryByID gets the job identified by the given id.
+func (c *Core) QueryByID(ctx context.Context, id string, now time.Time) (Job, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.prune(now)
+
+	job, exists := c.jobs[id]
+	if !exists {
+		return Job{}, ErrNotFound
+	}
+
+	return copyJob(job), nil
+}
+
+// Shutdown stops accepting new jobs and waits for the running ones to finish. Jobs still running
+// when the context is done are cancelled, leaving the rows processed so far in place.
+func (c *Core) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	c.shutdown = true
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+
+	case <-ctx.Done():
+		c.cancel()
+		<-done
+		return fmt.Errorf("waiting for bulk jobs: %w", ctx.Err())
+	}
+}
+
+// process creates a short link for every row read from the upload, recording the outcome of each
+// row in the job. Rows which are turned down don't stop the upload; an upload which can't be read
+// any further, or too many rows, fail the job at that point.
+func (c *Core) process(ctx context.Context, job *Job, r io.Reader, now func() time.Time) error {
+	c.update(job, func(j *Job) { j.Status = StatusRunning })
+
+	rows, err := newRowReader(job.Format, r)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidUpload, err)
+		c.fail(job, err.Error())
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			c.fail(job, "cancelled")
+			return err
+		}
+
+		rw, err := rows.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %s", ErrInvalidUpload, err)
+			c.fail(job, err.Error())
+			return err
+		}
+
+		if job.Rows >= c.maxRows {
+			err := fmt.Errorf("%w: more than %d rows", ErrInvalidUpload, c.maxRows)
+			c.fail(job, err.Error())
+			return err
+		}
+
+		result, err := c.createRow(ctx, job.Workspace, job.Owner, rw, now())
+		if err != nil {
+			c.fail(job, "unable to create links")
+			return fmt.Errorf("row %d: %w", rw.line, err)
+		}
+
+		c.update(job, func(j *Job) {
+			j.Rows++
+			if len(result.Errors) == 0 {
+				j.Created++
+			} else {
+				j.Failed++
+			}
+			if len(j.Results) < c.maxResults {
+				j.Results = append(j.Results, result)
+			} else {
+				j.Truncated = true
+			}
+		})
+	}
+
+	finished := time.Now().UTC()
+	c.update(job, func(j *Job) {
+		j.Status = StatusDone
+		j.DateFinished = &finished
+	})
+
+	return nil
+}
+
+// createRow creates the short link of a single row. Rows which are turned down are reported in the
+// result; only unexpected failures are returned as errors.
+func (c *Core) createRow(ctx context.Context, workspace string, owner string, rw row, now time.Time) (RowResult, error) {
+	result := RowResult{
+		Row:    rw.line,
+		Errors: rw.errors,
+	}
+	if len(rw.errors) > 0 {
+		return result, nil
+	}
+
+	lnk, err := c.link.Create(ctx, rw.nl, workspace, owner, now)
+	if err != nil {
+		var fields validate.FieldErrors
+		switch {
+		case errors.As(err, &fields):
+			result.Errors = fields
+		case errors.Is(err, link.ErrCodeExhausted):
+			result.Errors.Add("row", err.Error())
+		default:
+			return RowResult{}, err
+		}
+		return result, nil
+	}
+
+	result.Code = lnk.Code
+	result.Host = lnk.Host
+
+	return result, nil
+}
+
+// update applies a change to a job while holding the lock, so that readers see it whole.
+func (c *Core) update(job *Job, fn func(j *Job)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fn(job)
+}
+
+// fail marks a job as failed with the given reason.
+func (c *Core) fail(job *Job, reason string) {
+	finished := time.Now().UTC()
+	c.update(job, func(j *Job) {
+		j.Status = StatusFailed
+		j.Error = reason
+		j.DateFinished = &finished
+	})
+}
+
+// snapshot returns a copy of the job taken under the lock.
+func (c *Core) snapshot(job *Job) Job {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return copyJob(job)
+}
+
+// prune forgets the jobs which finished longer than the retention period ago. The caller must hold
+// the lock.
+func (c *Core) prune(now time.Time) {
+	for id, job := range c.jobs {
+		if job.finished() && job.DateFinished != nil && now.Sub(*job.DateFinished) > c.retention {
+			delete(c.jobs, id)
+		}
+	}
+}
+
+// newJob constructs a pending job.
+func newJob(workspace string, owner string, format string, now time.Time) Job {
+	return Job{
+		ID:          uuid.NewString(),
+		Workspace:   workspace,
+		Owner:       owner,
+		Format:      format,
+		Status:      StatusPending,
+		Results:     []RowResult{},
+		DateCreated: now,
+	}
+}
+
+// copyJob returns a copy of the job which doesn't share its results with the original.
+func copyJob(job *Job) Job {
+	cp := *job
+	cp.Results = append([]RowResult{}, job.Results...)
+
+	return cp
+}
@@ -0,0 +1,55 @@
+package bulk
+
+import (
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// The set of upload formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// These are the expected values for Job.Status.
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Job represents a single bulk upload of short links and the outcome of the rows in it. The
+// outcome of rows past the limit on results is left out, which Truncated reports, although every
+// row is counted.
+type Job struct {
+	ID           string      `json:"id"`
+	Workspace    string      `json:"workspace"`
+	Owner        string      `json:"owner"`
+	Format       string      `json:"format"`
+	Status       string      `json:"status"`
+	Error        string      `json:"error,omitempty"`
+	Rows         int         `json:"rows"`
+	Created      int         `json:"created"`
+	Failed       int         `json:"failed"`
+	Results      []RowResult `json:"results"`
+	Truncated    bool        `json:"truncated,omitempty"`
+	DateCreated  time.Time   `json:"date_created"`
+	DateFinished *time.Time  `json:"date_finished,omitempty"`
+}
+
+// RowResult represents the outcome of a single row of an upload. The row is the line number of the
+// row within the upload. Rows which were turned down carry the reasons in the same shape as the
+// field errors of the single link API.
+type RowResult struct {
+	Row    int                  `json:"row"`
+	Code   string               `json:"code,omitempty"`
+	Host   string               `json:"host,omitempty"`
+	Errors validate.FieldErrors `json:"errors,omitempty"`
+}
+
+// finished reports whether the job has run to its end.
+func (j Job) finished() bool {
+	return j.Status == StatusDone || j.Status == StatusFailed
+}
@@ -0,0 +1,229 @@
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/link"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// maxLineLength bounds the length of a single NDJSON line.
+const maxLineLength = 64 << 10
+
+// row represents a single parsed row of an upload. Rows which could not be parsed carry the field
+// errors describing why.
+type row struct {
+	line   int
+	nl     link.NewLink
+	errors validate.FieldErrors
+}
+
+// rowReader reads the rows of an upload one at a time. It returns io.EOF once the upload is
+// exhausted. Any other error means the upload can't be read any further.
+type rowReader interface {
+	next() (row, error)
+}
+
+// newRowReader constructs a reader for an upload in the given format.
+func newRowReader(format string, r io.Reader) (rowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 4096), maxLineLength)
+		return &ndjsonReader{scanner: scanner}, nil
+	}
+
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// =================================================================================================
+
+// csvColumns lists the columns a CSV upload may carry. Only the destination is required. Tags are
+// separated by commas or spaces within their field.
+var csvColumns = map[string]bool{
+	"destination":     true,
+	"alias":           true,
+	"host":            true,
+	"title":           true,
+	"notes":           true,
+	"tags":            true,
+	"folder_id":       true,
+	"redirect_status": true,
+	"activates_at":    true,
+	"expires_at":      true,
+	"max_clicks":      true,
+	"fallback_url":    true,
+	"password":        true,
+}
+
+// csvReader reads rows from a CSV upload whose first record names the columns.
+type csvReader struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+// newCSVReader constructs a csvReader, reading and checking the header record.
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("upload is empty")
+		}
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	columns := make([]string, len(header))
+	var hasDestination bool
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !csvColumns[name] {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if name == "destination" {
+			hasDestination = true
+		}
+		columns[i] = name
+	}
+
+	if !hasDestination {
+		return nil, errors.New("missing destination column")
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+// next reads the following row of the upload.
+func (cr *csvReader) next() (row, error) {
+	record, err := cr.reader.Read()
+	if err != nil {
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			r := row{line: perr.StartLine}
+			r.errors.Add("row", perr.Err.Error())
+			return r, nil
+		}
+		return row{}, err
+	}
+
+	line, _ := cr.reader.FieldPos(0)
+	r := row{line: line}
+
+	if len(record) != len(cr.columns) {
+		r.errors.Add("row", fmt.Sprintf("has %d fields, expected %d", len(record), len(cr.columns)))
+		return r, nil
+	}
+
+	for i, value := range record {
+		if value == "" {
+			continue
+		}
+
+		switch cr.columns[i] {
+		case "destination":
+			r.nl.Destination = value
+		case "alias":
+			r.nl.Alias = value
+		case "host":
+			r.nl.Host = value
+		case "title":
+			r.nl.Title = value
+		case "notes":
+			r.nl.Notes = value
+		case "folder_id":
+			r.nl.FolderID = value
+		case "tags":
+			r.nl.Tags = strings.FieldsFunc(value, func(c rune) bool { return c == ',' || c == ' ' })
+		case "fallback_url":
+			r.nl.FallbackURL = value
+		case "password":
+			r.nl.Password = value
+
+		case "redirect_status":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				r.errors.Add("redirect_status", "must be a number")
+			}
+			r.nl.RedirectStatus = n
+
+		case "max_clicks":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				r.errors.Add("max_clicks", "must be a number")
+			}
+			r.nl.MaxClicks = n
+
+		case "activates_at":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				r.errors.Add("activates_at", "must be an RFC 3339 timestamp")
+			}
+			r.nl.ActivatesAt = &t
+
+		case "expires_at":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				r.errors.Add("expires_at", "must be an RFC 3339 timestamp")
+			}
+			r.nl.ExpiresAt = &t
+		}
+	}
+
+	return r, nil
+}
+
+// =================================================================================================
+
+// ndjsonReader reads rows from an upload holding one JSON encoded link per line. Blank lines are
+// skipped.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// next reads the following row of the upload.
+func (nr *ndjsonReader) next() (row, error) {
+	for nr.scanner.Scan() {
+		nr.line++
+
+		data := bytes.TrimSpace(nr.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		r := row{line: nr.line}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&r.nl); err != nil {
+			r.errors.Add("row", fmt.Sprintf("invalid json: %s", err))
+		}
+
+		return r, nil
+	}
+
+	if err := nr.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return row{}, fmt.Errorf("line %d is longer than %d bytes", nr.line+1, maxLineLength)
+		}
+		return row{}, err
+	}
+
+	return row{}, io.EOF
+}
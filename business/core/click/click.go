@@ -0,0 +1,55 @@
+// Package click provides the core business API for capturing and storing the visits to short
+// links.
+package click
+
+import (
+	"context"
+	"fmt"
+)
+
+// Storer defines the behavior required to persist and retrieve click events. Implementations must
+// be safe for concurrent use.
+type Storer interface {
+	Create(ctx context.Context, events []Event) error
+	QueryStats(ctx context.Context, host string, code string, sq StatsQuery) (Tally, error)
+	Delete(ctx context.Context, host string, codes []string) error
+}
+
+// Core manages the set of APIs for click event access.
+type Core struct {
+	storer Storer
+}
+
+// NewCore constructs a Core for click event API access.
+func NewCore(storer Storer) *Core {
+	return &Core{
+		storer: storer,
+	}
+}
+
+// Create stores a batch of click events.
+func (c *Core) Create(ctx context.Context, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if err := c.storer.Create(ctx, events); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes every click event of the short links identified by the given codes on the given
+// host.
+func (c *Core) Delete(ctx context.Context, host string, codes []string) error {
+	if len(codes) == 0 {
+		return nil
+	}
+
+	if err := c.storer.Delete(ctx, host, codes); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
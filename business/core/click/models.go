@@ -0,0 +1,138 @@
+package click
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// maxFieldLength bounds the length of the free-form fields of an event, which come straight from
+// request headers.
+const maxFieldLength = 1024
+
+// Event represents a single visit to a short link. The country is an ISO 3166-1 code, the region an
+// ISO 3166-2 code and the city an English name; they are left blank when the location of the
+// visitor is not known. The host is blank for visits to the default namespace.
+type Event struct {
+	Code       string    `json:"code"`
+	Host       string    `json:"host,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Referrer   string    `json:"referrer,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	ClientIP   string    `json:"client_ip,omitempty"`
+	Country    string    `json:"country,omitempty"`
+	Region     string    `json:"region,omitempty"`
+	City       string    `json:"city,omitempty"`
+	TraceID    string    `json:"trace_id,omitempty"`
+}
+
+// truncated returns a copy of the event with its free-form fields cut down to size.
+func (e Event) truncated() Event {
+	e.Referrer = truncate(e.Referrer)
+	e.UserAgent = truncate(e.UserAgent)
+
+	return e
+}
+
+// truncate cuts the given string down to the maximum field length.
+func truncate(s string) string {
+	if len(s) > maxFieldLength {
+		return s[:maxFieldLength]
+	}
+
+	return s
+}
+
+// The set of intervals the clicks of a short link can be bucketed by.
+const (
+	IntervalHour  = "hour"
+	IntervalDay   = "day"
+	IntervalMonth = "month"
+)
+
+// The bounds on the number of entries in a breakdown.
+const (
+	defaultTop = 10
+	maxTop     = 100
+)
+
+// maxBuckets bounds the number of buckets in a time series, so a wide range at a fine interval
+// can't produce an enormous response.
+const maxBuckets = 10000
+
+// StatsQuery defines the window and shape of the statistics of a short link. The zero value of
+// every field picks a default: daily buckets in UTC over the last 30 days with the top 10 entries
+// of every breakdown.
+type StatsQuery struct {
+	Interval string
+	Location *time.Location
+	From     time.Time
+	To       time.Time
+	Top      int
+}
+
+// Validate checks that the statistics query is valid.
+func (sq StatsQuery) Validate() error {
+	var fields validate.FieldErrors
+
+	switch sq.Interval {
+	case "", IntervalHour, IntervalDay, IntervalMonth:
+	default:
+		fields.Add("interval", "must be one of hour, day or month")
+	}
+
+	if !sq.From.IsZero() && !sq.To.IsZero() && !sq.To.After(sq.From) {
+		fields.Add("to", "must be after from")
+	}
+
+	if sq.Top < 0 || sq.Top > maxTop {
+		fields.Add("top", fmt.Sprintf("must be between 1 and %d", maxTop))
+	}
+
+	return fields.Err()
+}
+
+// Stats represents the clicks on a short link within a window of time.
+type Stats struct {
+	Code             string    `json:"code"`
+	Host             string    `json:"host,omitempty"`
+	Interval         string    `json:"interval"`
+	Timezone         string    `json:"timezone"`
+	From             time.Time `json:"from"`
+	To               time.Time `json:"to"`
+	Total            int       `json:"total"`
+	Series           []Bucket  `json:"series"`
+	Referrers        []Count   `json:"referrers"`
+	Browsers         []Count   `json:"browsers"`
+	OperatingSystems []Count   `json:"operating_systems"`
+	Devices          []Count   `json:"devices"`
+	Countries        []Count   `json:"countries"`
+	Regions          []Count   `json:"regions"`
+	Cities           []Count   `json:"cities"`
+}
+
+// Tally represents the clicks on a short link within the window of a statistics query as counted by
+// a store. The series only holds the buckets with clicks in them. User agents are counted as they
+// were sent, all of them, so that they can be sorted into browsers, operating systems and devices.
+// Every other breakdown holds the entries with the most clicks, at most the top of the query.
+type Tally struct {
+	Series     []Bucket
+	UserAgents []Count
+	Referrers  []Count
+	Countries  []Count
+	Regions    []Count
+	Cities     []Count
+}
+
+// Bucket represents the number of clicks within a single interval of a time series.
+type Bucket struct {
+	Start  time.Time `json:"start"`
+	Clicks int       `json:"clicks"`
+}
+
+// Count represents the number of clicks sharing a single value of a breakdown.
+type Count struct {
+	Value  string `json:"value"`
+	Clicks int    `json:"clicks"`
+}
@@ -0,0 +1,188 @@
+package click
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/geoip"
+	"github.com/yashshah7197/shrt/business/sys/metrics"
+
+	"go.uber.org/zap"
+)
+
+// RecorderConfig represents the dependencies and settings required by the Recorder.
+type RecorderConfig struct {
+	Core          *Core
+	Logger        *zap.SugaredLogger
+	Geo           *geoip.Locator
+	QueueSize     int
+	BatchSize     int
+	Workers       int
+	FlushInterval time.Duration
+}
+
+// Recorder captures click events off the redirect path. Events are put on a bounded queue and
+// written to storage in batches by a set of worker goroutines. Recording never blocks: when the
+// queue is full the event is dropped and counted instead. Events are placed on the map by the
+// workers when a GeoIP database is configured, which keeps the lookups off the redirect path.
+type Recorder struct {
+	core          *Core
+	logger        *zap.SugaredLogger
+	geo           *geoip.Locator
+	batchSize     int
+	flushInterval time.Duration
+	queue         chan Event
+	wg            sync.WaitGroup
+
+	// ctx bounds the writes of the workers. It is cancelled once the context given to Shutdown is
+	// done, so a slow store can't hold up shutdown past its deadline.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewRecorder constructs a Recorder and starts its workers.
+func NewRecorder(cfg RecorderConfig) (*Recorder, error) {
+	switch {
+	case cfg.QueueSize < 1:
+		return nil, fmt.Errorf("invalid queue size: %d", cfg.QueueSize)
+	case cfg.BatchSize < 1:
+		return nil, fmt.Errorf("invalid batch size: %d", cfg.BatchSize)
+	case cfg.Workers < 1:
+		return nil, fmt.Errorf("invalid number of workers: %d", cfg.Workers)
+	case cfg.FlushInterval <= 0:
+		return nil, fmt.Errorf("invalid flush interval: %s", cfg.FlushInterval)
+	}
+
+	// Workers run outside of any request, so they carry their own metrics.
+	ctx, cancel := context.WithCancel(metrics.Set(context.Background()))
+
+	r := Recorder{
+		core:          cfg.Core,
+		logger:        cfg.Logger,
+		geo:           cfg.Geo,
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		queue:         make(chan Event, cfg.QueueSize),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+
+	r.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go r.work()
+	}
+
+	return &r, nil
+}
+
+// Record queues a click event to be stored. The event is dropped if the queue is full or the
+// recorder has been shut down.
+func (r *Recorder) Record(ctx context.Context, ev Event) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		metrics.AddClickDrops(ctx, 1)
+		return
+	}
+
+	select {
+	case r.queue <- ev.truncated():
+	default:
+		metrics.AddClickDrops(ctx, 1)
+	}
+}
+
+// Shutdown stops accepting events and waits for the workers to store everything left on the
+// queue, or for the context to be done, whichever happens first. Writes still in flight when the
+// context is done are cancelled.
+func (r *Recorder) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.cancel()
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		return fmt.Errorf("draining %d click events: %w", len(r.queue), ctx.Err())
+	}
+}
+
+// work collects events off the queue into batches and stores them, either when a batch is full or
+// on every flush interval. It returns once the queue is closed and drained.
+func (r *Recorder) work() {
+	defer r.wg.Done()
+
+	ctx := r.ctx
+
+	batch := make([]Event, 0, r.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := r.core.Create(ctx, batch); err != nil {
+			r.logger.Errorw("click recorder", "status", "dropping batch", "events", len(batch), "ERROR", err)
+			metrics.AddClickDrops(ctx, len(batch))
+		}
+		batch = make([]Event, 0, r.batchSize)
+	}
+
+	tick := time.NewTicker(r.flushInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case ev, ok := <-r.queue:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, r.locate(ev))
+			if len(batch) >= r.batchSize {
+				flush()
+			}
+
+		case <-tick.C:
+			flush()
+		}
+	}
+}
+
+// locate fills in the location of the client of the event, if it can be found.
+func (r *Recorder) locate(ev Event) Event {
+	if r.geo == nil || ev.ClientIP == "" {
+		return ev
+	}
+
+	loc, err := r.geo.Locate(net.ParseIP(ev.ClientIP))
+	if err != nil {
+		r.logger.Debugw("click recorder", "status", "locating client", "ip", ev.ClientIP, "ERROR", err)
+		return ev
+	}
+
+	ev.Country = loc.Country
+	ev.Region = loc.Region
+	ev.City = loc.City
+
+	return ev
+}
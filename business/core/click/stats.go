@@ -0,0 +1,234 @@
+package click
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/foundation/useragent"
+)
+
+// unknown is reported in a breakdown for clicks whose value is not known.
+const unknown = "unknown"
+
+// Stats summarises the clicks on the short link identified by the given host and code. Clicks are
+// counted into buckets of the requested interval, aligned to the requested location, and broken
+// down by referrer domain, browser, operating system, device class, country, region and city. The
+// counting is left to the store, so the events themselves are never loaded.
+func (c *Core) Stats(ctx context.Context, host string, code string, sq StatsQuery, now time.Time) (Stats, error) {
+	if err := sq.Validate(); err != nil {
+		return Stats{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	// Fill in the defaults for everything left out.
+	if sq.Interval == "" {
+		sq.Interval = IntervalDay
+	}
+	if sq.Location == nil {
+		sq.Location = time.UTC
+	}
+	if sq.Top == 0 {
+		sq.Top = defaultTop
+	}
+	if sq.To.IsZero() {
+		sq.To = now
+	}
+	if sq.From.IsZero() {
+		switch sq.Interval {
+		case IntervalHour:
+			sq.From = sq.To.Add(-48 * time.Hour)
+		case IntervalDay:
+			sq.From = sq.To.AddDate(0, 0, -30)
+		case IntervalMonth:
+			sq.From = sq.To.AddDate(-1, 0, 0)
+		}
+	}
+
+	// Align the start of the window to a whole bucket and lay out every bucket up front, so that
+	// intervals without clicks show up in the series as well.
+	from := bucketStart(sq.From.In(sq.Location), sq.Interval)
+	series := []Bucket{}
+	index := make(map[int64]int)
+	for start := from; start.Before(sq.To); start = nextBucket(start, sq.Interval) {
+		if len(series) == maxBuckets {
+			return Stats{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "from", Error: fmt.Sprintf("window covers more than %d buckets", maxBuckets)}})
+		}
+		index[start.Unix()] = len(series)
+		series = append(series, Bucket{Start: start})
+	}
+
+	sq.From = from
+	tally, err := c.storer.QueryStats(ctx, host, code, sq)
+	if err != nil {
+		return Stats{}, fmt.Errorf("query: %w", err)
+	}
+
+	var total int
+	for _, b := range tally.Series {
+		i, exists := index[bucketStart(b.Start.In(sq.Location), sq.Interval).Unix()]
+		if !exists {
+			continue
+		}
+		series[i].Clicks += b.Clicks
+		total += b.Clicks
+	}
+
+	browsers := make(map[string]int)
+	systems := make(map[string]int)
+	devices := make(map[string]int)
+	for _, cnt := range tally.UserAgents {
+		ua := useragent.Parse(cnt.Value)
+		browsers[ua.Browser] += cnt.Clicks
+		systems[ua.OS] += cnt.Clicks
+		devices[ua.Device] += cnt.Clicks
+	}
+
+	stats := Stats{
+		Code:             code,
+		Host:             host,
+		Interval:         sq.Interval,
+		Timezone:         sq.Location.String(),
+		From:             from,
+		To:               sq.To.In(sq.Location),
+		Total:            total,
+		Series:           series,
+		Referrers:        tally.Referrers,
+		Browsers:         top(browsers, sq.Top),
+		OperatingSystems: top(systems, sq.Top),
+		Devices:          top(devices, sq.Top),
+		Countries:        orUnknown(tally.Countries),
+		Regions:          orUnknown(tally.Regions),
+		Cities:           orUnknown(tally.Cities),
+	}
+
+	return stats, nil
+}
+
+// TallyEvents counts the given click events into a tally the way a store is expected to, for the
+// stores which hold their events in memory. Events outside the window of the query are skipped.
+func TallyEvents(events []Event, sq StatsQuery) Tally {
+	buckets := make(map[int64]int)
+	userAgents := make(map[string]int)
+	referrers := make(map[string]int)
+	countries := make(map[string]int)
+	regions := make(map[string]int)
+	cities := make(map[string]int)
+
+	for _, ev := range events {
+		if ev.OccurredAt.Before(sq.From) || !ev.OccurredAt.Before(sq.To) {
+			continue
+		}
+
+		buckets[bucketStart(ev.OccurredAt.In(sq.Location), sq.Interval).Unix()]++
+		userAgents[ev.UserAgent]++
+		referrers[referrerDomain(ev.Referrer)]++
+		countries[ev.Country]++
+		regions[ev.Region]++
+		cities[ev.City]++
+	}
+
+	series := make([]Bucket, 0, len(buckets))
+	for start, clicks := range buckets {
+		series = append(series, Bucket{Start: time.Unix(start, 0).In(sq.Location), Clicks: clicks})
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].Start.Before(series[j].Start)
+	})
+
+	tally := Tally{
+		Series:     series,
+		UserAgents: top(userAgents, len(userAgents)),
+		Referrers:  top(referrers, sq.Top),
+		Countries:  top(countries, sq.Top),
+		Regions:    top(regions, sq.Top),
+		Cities:     top(cities, sq.Top),
+	}
+
+	return tally
+}
+
+// bucketStart returns the start of the bucket of the given interval which contains the given time,
+// in the location of the time.
+func bucketStart(t time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalHour:
+		// Truncate on the wall clock rather than on the calendar, so that the repeated hour at the
+		// end of daylight saving time and zones which are offset by half an hour both come out
+		// right.
+		_, offset := t.Zone()
+		shift := time.Duration(offset) * time.Second
+		return t.Add(shift).Truncate(time.Hour).Add(-shift)
+
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+// nextBucket returns the start of the bucket which follows the one starting at the given time.
+func nextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalHour:
+		return bucketStart(start.Add(time.Hour), interval)
+
+	case IntervalMonth:
+		return time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, start.Location())
+
+	default:
+		return time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+	}
+}
+
+// top returns the entries of a breakdown with the most clicks, most clicked first. Ties are broken
+// by value so the order is stable.
+func top(counts map[string]int, n int) []Count {
+	entries := make([]Count, 0, len(counts))
+	for value, clicks := range counts {
+		entries = append(entries, Count{Value: value, Clicks: clicks})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Clicks != entries[j].Clicks {
+			return entries[i].Clicks > entries[j].Clicks
+		}
+		return entries[i].Value < entries[j].Value
+	})
+
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+
+	return entries
+}
+
+// referrerDomain returns the domain of the page the visitor came from. Visits without a referrer
+// are reported as direct.
+func referrerDomain(referrer string) string {
+	if referrer == "" {
+		return "direct"
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return unknown
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// orUnknown reports the entries of a breakdown with a blank value as unknown.
+func orUnknown(counts []Count) []Count {
+	for i := range counts {
+		if counts[i].Value == "" {
+			counts[i].Value = unknown
+		}
+	}
+
+	return counts
+}
@@ -0,0 +1,202 @@
+// Package clickdb contains the database/sql implementation of the click event storer.
+package clickdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/yashshah7197/shrt/business/core/click"
+)
+
+// Store manages the set of APIs for click event access in the database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore constructs a store for click events backed by the given database.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create inserts a batch of click events into the database within a single transaction.
+func (s *Store) Create(ctx context.Context, events []click.Event) error {
+	const q = `
+	INSERT INTO click_events
+		(code, host, occurred_at, referrer, user_agent, client_ip, country, region, city, trace_id)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, q)
+	if err != nil {
+		return fmt.Errorf("preparing insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, ev := range events {
+		if _, err := stmt.ExecContext(ctx,
+			ev.Code,
+			ev.Host,
+			ev.OccurredAt.UTC(),
+			ev.Referrer,
+			ev.UserAgent,
+			ev.ClientIP,
+			ev.Country,
+			ev.Region,
+			ev.City,
+			ev.TraceID,
+		); err != nil {
+			return fmt.Errorf("inserting click event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// The expressions the click events are grouped by for every breakdown. A referrer is reduced to its
+// domain the same way the core does it: visits without one are direct and referrers without a
+// domain are unknown.
+const (
+	byUserAgent = `user_agent`
+	byReferrer  = `
+		CASE
+			WHEN referrer = '' THEN 'direct'
+			ELSE coalesce(nullif(regexp_replace(lower(substring(referrer FROM '^(?:[a-zA-Z][a-zA-Z0-9+.-]*:)?//(?:[^/?#]*@)?([^:/?#]*)')), '^www\.', ''), ''), 'unknown')
+		END`
+	byCountry = `country`
+	byRegion  = `region`
+	byCity    = `city`
+)
+
+// QueryStats counts the click events of the short link identified by the given host and code
+// within the window of the given statistics query. The events are counted into buckets and
+// breakdowns by the database, so only the counts are sent back.
+func (s *Store) QueryStats(ctx context.Context, host string, code string, sq click.StatsQuery) (click.Tally, error) {
+	const q = `
+	SELECT
+		date_trunc($5, occurred_at AT TIME ZONE 'UTC' AT TIME ZONE $6) AS start, count(*) AS clicks
+	FROM
+		click_events
+	WHERE
+		host = $1 AND code = $2 AND occurred_at >= $3 AND occurred_at < $4
+	GROUP BY
+		start
+	ORDER BY
+		start`
+
+	rows, err := s.db.QueryContext(ctx, q, host, code, sq.From.UTC(), sq.To.UTC(), sq.Interval, sq.Location.String())
+	if err != nil {
+		return click.Tally{}, fmt.Errorf("selecting click series for link[%s]: %w", code, err)
+	}
+	defer rows.Close()
+
+	var tally click.Tally
+	for rows.Next() {
+		var start time.Time
+		var clicks int
+		if err := rows.Scan(&start, &clicks); err != nil {
+			return click.Tally{}, fmt.Errorf("scanning click bucket: %w", err)
+		}
+
+		// The start comes back as a wall clock time in the location of the query.
+		start = time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, sq.Location)
+		tally.Series = append(tally.Series, click.Bucket{Start: start, Clicks: clicks})
+	}
+
+	if err := rows.Err(); err != nil {
+		return click.Tally{}, fmt.Errorf("iterating click series: %w", err)
+	}
+
+	// Every user agent is needed to sort the clicks into browsers, operating systems and devices,
+	// so they are not limited.
+	breakdowns := []struct {
+		expr   string
+		limit  interface{}
+		counts *[]click.Count
+	}{
+		{byUserAgent, nil, &tally.UserAgents},
+		{byReferrer, sq.Top, &tally.Referrers},
+		{byCountry, sq.Top, &tally.Countries},
+		{byRegion, sq.Top, &tally.Regions},
+		{byCity, sq.Top, &tally.Cities},
+	}
+
+	for _, bd := range breakdowns {
+		counts, err := s.breakdown(ctx, bd.expr, host, code, sq, bd.limit)
+		if err != nil {
+			return click.Tally{}, err
+		}
+		*bd.counts = counts
+	}
+
+	return tally, nil
+}
+
+// breakdown counts the click events of a short link within the window of the given statistics
+// query by the value of the given expression, most clicked first. A nil limit returns every value.
+func (s *Store) breakdown(ctx context.Context, expr string, host string, code string, sq click.StatsQuery, limit interface{}) ([]click.Count, error) {
+	q := `
+	SELECT
+		` + expr + ` AS value, count(*) AS clicks
+	FROM
+		click_events
+	WHERE
+		host = $1 AND code = $2 AND occurred_at >= $3 AND occurred_at < $4
+	GROUP BY
+		value
+	ORDER BY
+		clicks DESC, value
+	LIMIT $5`
+
+	rows, err := s.db.QueryContext(ctx, q, host, code, sq.From.UTC(), sq.To.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("selecting click breakdown for link[%s]: %w", code, err)
+	}
+	defer rows.Close()
+
+	counts := []click.Count{}
+	for rows.Next() {
+		var cnt click.Count
+		if err := rows.Scan(&cnt.Value, &cnt.Clicks); err != nil {
+			return nil, fmt.Errorf("scanning click count: %w", err)
+		}
+		counts = append(counts, cnt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating click breakdown: %w", err)
+	}
+
+	return counts, nil
+}
+
+// Delete removes every click event of the short links identified by the given codes on the given
+// host.
+func (s *Store) Delete(ctx context.Context, host string, codes []string) error {
+	const q = `
+	DELETE FROM
+		click_events
+	WHERE
+		host = $1 AND code = ANY($2)`
+
+	if _, err := s.db.ExecContext(ctx, q, host, pq.Array(codes)); err != nil {
+		return fmt.Errorf("deleting click events: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+// Package clickfile contains a durable, single-file implementation of the click event storer. Every
+// batch of events is appended to a journal on disk and the events are kept in memory for reads.
+// Deleting events rewrites the journal without them, so they don't linger on disk.
+package clickfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/click"
+	"github.com/yashshah7197/shrt/business/core/click/stores/clickmem"
+	"github.com/yashshah7197/shrt/foundation/journal"
+)
+
+// The set of operations recorded in the journal.
+const (
+	opClick = "click"
+)
+
+// Store manages the set of APIs for click event access backed by a journal file.
+type Store struct {
+	mu      sync.Mutex
+	mem     *clickmem.Store
+	journal *journal.Journal
+}
+
+// Open constructs a store for click events by replaying the journal at the given path.
+func Open(path string) (*Store, error) {
+	ctx := context.Background()
+	mem := clickmem.NewStore()
+
+	// Rebuild the in-memory state from the journal records.
+	replay := func(rec journal.Record) error {
+		switch rec.Op {
+		case opClick:
+			var ev click.Event
+			if err := json.Unmarshal(rec.Data, &ev); err != nil {
+				return err
+			}
+			return mem.Create(ctx, []click.Event{ev})
+
+		default:
+			return fmt.Errorf("unknown operation %q", rec.Op)
+		}
+	}
+
+	jrnl, err := journal.Open(path, replay)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	s := Store{
+		mem:     mem,
+		journal: jrnl,
+	}
+
+	return &s, nil
+}
+
+// Close closes the underlying journal file.
+func (s *Store) Close() error {
+	return s.journal.Close()
+}
+
+// Create appends a batch of click events to the store. The whole batch is flushed to disk at once.
+func (s *Store) Create(ctx context.Context, events []click.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := func(emit func(op string, key string, data interface{}) error) error {
+		for _, ev := range events {
+			if err := emit(opClick, ev.Code, ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := s.journal.AppendBatch(batch); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Create(ctx, events)
+}
+
+// QueryStats counts the click events of the short link identified by the given host and code
+// within the window of the given statistics query.
+func (s *Store) QueryStats(ctx context.Context, host string, code string, sq click.StatsQuery) (click.Tally, error) {
+	return s.mem.QueryStats(ctx, host, code, sq)
+}
+
+// Delete removes every click event of the short links identified by the given codes on the given
+// host. The journal is compacted down to the events that are kept before they are dropped from
+// memory.
+func (s *Store) Delete(ctx context.Context, host string, codes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	drop := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		drop[code] = true
+	}
+
+	snapshot := func(emit func(op string, key string, data interface{}) error) error {
+		for _, ev := range s.mem.All() {
+			if ev.Host == host && drop[ev.Code] {
+				continue
+			}
+			if err := emit(opClick, ev.Code, ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := s.journal.Compact(snapshot); err != nil {
+		return fmt.Errorf("compacting journal: %w", err)
+	}
+
+	return s.mem.Delete(ctx, host, codes)
+}
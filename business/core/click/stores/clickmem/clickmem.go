@@ -0,0 +1,94 @@
+// Package clickmem contains a concurrency-safe, in-memory implementation of the click event storer.
+// It is intended for tests and local development since nothing survives a restart.
+package clickmem
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/click"
+)
+
+// Store manages the set of APIs for click event access held in memory.
+type Store struct {
+	mu     sync.RWMutex
+	events map[string][]click.Event
+}
+
+// NewStore constructs an empty in-memory store for click events.
+func NewStore() *Store {
+	return &Store{
+		events: make(map[string][]click.Event),
+	}
+}
+
+// Create appends a batch of click events to the store.
+func (s *Store) Create(ctx context.Context, events []click.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ev := range events {
+		k := key(ev.Host, ev.Code)
+		s.events[k] = append(s.events[k], ev)
+	}
+
+	return nil
+}
+
+// Delete removes every click event of the short links identified by the given codes on the given
+// host.
+func (s *Store) Delete(ctx context.Context, host string, codes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range codes {
+		delete(s.events, key(host, code))
+	}
+
+	return nil
+}
+
+// QueryStats counts the click events of the short link identified by the given host and code
+// within the window of the given statistics query.
+func (s *Store) QueryStats(ctx context.Context, host string, code string, sq click.StatsQuery) (click.Tally, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return click.TallyEvents(s.events[key(host, code)], sq), nil
+}
+
+// All returns every click event held in the store.
+func (s *Store) All() []click.Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []click.Event
+	for _, evs := range s.events {
+		events = append(events, evs...)
+	}
+
+	return events
+}
+
+// Len returns the number of click events in the store.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int
+	for _, events := range s.events {
+		n += len(events)
+	}
+
+	return n
+}
+
+// key returns the key the events of a short link are held under, which is the same one the link
+// itself is held under.
+func key(host string, code string) string {
+	if host == "" {
+		return code
+	}
+
+	return host + "/" + code
+}
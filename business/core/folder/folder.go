@@ -0,0 +1,301 @@
+// Package folder provides the core business API for the per workspace hierarchy of folders which
+// short links can be filed in.
+package folder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound  = errors.New("folder not found")
+	ErrNameTaken = errors.New("name is already taken")
+	ErrNotEmpty  = errors.New("folder is not empty")
+)
+
+// Storer defines the behavior required to persist and retrieve folders. Implementations must be
+// safe for concurrent use, and must return ErrNameTaken when two folders of a workspace with the
+// same parent would share a name. Folders are only found, updated and deleted within the given
+// workspace, or the workspace of the folder passed to Update, and any other folder is reported as
+// ErrNotFound.
+type Storer interface {
+	Create(ctx context.Context, f Folder) error
+	Update(ctx context.Context, f Folder) error
+	Delete(ctx context.Context, workspace string, id string) error
+	QueryByID(ctx context.Context, workspace string, id string) (Folder, error)
+	QueryByWorkspace(ctx context.Context, workspace string) ([]Folder, error)
+}
+
+// Core manages the set of APIs for folder access.
+type Core struct {
+	storer Storer
+}
+
+// NewCore constructs a Core for folder API access.
+func NewCore(storer Storer) *Core {
+	return &Core{
+		storer: storer,
+	}
+}
+
+// Create adds a new folder to the specified workspace, created by the specified subject.
+func (c *Core) Create(ctx context.Context, nf NewFolder, workspace string, owner string, now time.Time) (Folder, error) {
+	if err := nf.Validate(); err != nil {
+		return Folder{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	t, err := c.tree(ctx, workspace)
+	if err != nil {
+		return Folder{}, err
+	}
+
+	if nf.ParentID != "" {
+		if _, exists := t[nf.ParentID]; !exists {
+			return Folder{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "parent_id", Error: "does not exist"}})
+		}
+		if t.depth(nf.ParentID)+1 > maxDepth {
+			return Folder{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "parent_id", Error: fmt.Sprintf("folders can be at most %d deep", maxDepth)}})
+		}
+	}
+
+	f := Folder{
+		ID:          uuid.NewString(),
+		Workspace:   workspace,
+		Owner:       owner,
+		Name:        nf.Name,
+		ParentID:    nf.ParentID,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := c.storer.Create(ctx, f); err != nil {
+		if errors.Is(err, ErrNameTaken) {
+			return Folder{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "name", Error: "is already taken"}})
+		}
+		return Folder{}, fmt.Errorf("create: %w", err)
+	}
+
+	t[f.ID] = f
+	f.Path = t.path(f.ID)
+
+	return f, nil
+}
+
+// Update renames the folder identified by the given ID in the specified workspace or moves it to
+// another parent. A folder can't be moved into itself or any of its subfolders.
+func (c *Core) Update(ctx context.Context, workspace string, id string, uf UpdateFolder, now time.Time) (Folder, error) {
+	if err := uf.Validate(); err != nil {
+		return Folder{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	f, err := c.storer.QueryByID(ctx, workspace, id)
+	if err != nil {
+		return Folder{}, fmt.Errorf("query: %w", err)
+	}
+
+	t, err := c.tree(ctx, f.Workspace)
+	if err != nil {
+		return Folder{}, err
+	}
+
+	if uf.Name != nil {
+		f.Name = *uf.Name
+	}
+
+	if uf.ParentID != nil && *uf.ParentID != f.ParentID {
+		parentID := *uf.ParentID
+		if parentID != "" {
+			if _, exists := t[parentID]; !exists {
+				return Folder{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "parent_id", Error: "does not exist"}})
+			}
+			if t.within(parentID, id) {
+				return Folder{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "parent_id", Error: "must not be the folder or one of its subfolders"}})
+			}
+			if t.depth(parentID)+1+t.height(id) > maxDepth {
+				return Folder{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "parent_id", Error: fmt.Sprintf("folders can be at most %d deep", maxDepth)}})
+			}
+		}
+		f.ParentID = parentID
+	}
+	f.DateUpdated = now
+
+	if err := c.storer.Update(ctx, f); err != nil {
+		if errors.Is(err, ErrNameTaken) {
+			return Folder{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "name", Error: "is already taken"}})
+		}
+		return Folder{}, fmt.Errorf("update: %w", err)
+	}
+
+	t[f.ID] = f
+	f.Path = t.path(f.ID)
+
+	return f, nil
+}
+
+// Delete removes the folder identified by the given ID in the specified workspace. Only folders
+// without subfolders can be removed; the caller is responsible for making sure no links are filed
+// in it.
+func (c *Core) Delete(ctx context.Context, workspace string, id string) error {
+	folders, err := c.storer.QueryByWorkspace(ctx, workspace)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	for _, child := range folders {
+		if child.ParentID == id {
+			return ErrNotEmpty
+		}
+	}
+
+	if err := c.storer.Delete(ctx, workspace, id); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByID gets the folder identified by the given ID in the specified workspace.
+func (c *Core) QueryByID(ctx context.Context, workspace string, id string) (Folder, error) {
+	f, err := c.storer.QueryByID(ctx, workspace, id)
+	if err != nil {
+		return Folder{}, fmt.Errorf("query: %w", err)
+	}
+
+	t, err := c.tree(ctx, f.Workspace)
+	if err != nil {
+		return Folder{}, err
+	}
+	f.Path = t.path(f.ID)
+
+	return f, nil
+}
+
+// QueryByWorkspace gets every folder of the specified workspace, ordered by path.
+func (c *Core) QueryByWorkspace(ctx context.Context, workspace string) ([]Folder, error) {
+	t, err := c.tree(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make([]Folder, 0, len(t))
+	for id, f := range t {
+		f.Path = t.path(id)
+		folders = append(folders, f)
+	}
+
+	sort.Slice(folders, func(i, j int) bool {
+		return folders[i].Path < folders[j].Path
+	})
+
+	return folders, nil
+}
+
+// Subtree returns the ID of the folder identified by the given ID in the specified workspace along
+// with the IDs of all of its subfolders, however deep.
+func (c *Core) Subtree(ctx context.Context, workspace string, id string) ([]string, error) {
+	f, err := c.storer.QueryByID(ctx, workspace, id)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	t, err := c.tree(ctx, f.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{id}
+	for other := range t {
+		if other != id && t.within(other, id) {
+			ids = append(ids, other)
+		}
+	}
+
+	return ids, nil
+}
+
+// =================================================================================================
+
+// tree holds every folder of a workspace by ID.
+type tree map[string]Folder
+
+// tree loads every folder of the given workspace.
+func (c *Core) tree(ctx context.Context, workspace string) (tree, error) {
+	folders, err := c.storer.QueryByWorkspace(ctx, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	t := make(tree, len(folders))
+	for _, f := range folders {
+		t[f.ID] = f
+	}
+
+	return t, nil
+}
+
+// ancestry returns the IDs from the given folder up to its root folder. The walk is bounded so a
+// corrupt hierarchy can't loop forever.
+func (t tree) ancestry(id string) []string {
+	var ids []string
+	for id != "" && len(ids) <= len(t) {
+		f, exists := t[id]
+		if !exists {
+			break
+		}
+		ids = append(ids, id)
+		id = f.ParentID
+	}
+
+	return ids
+}
+
+// path returns the names leading from the root to the given folder, separated by slashes.
+func (t tree) path(id string) string {
+	ids := t.ancestry(id)
+
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[len(ids)-1-i] = t[id].Name
+	}
+
+	return strings.Join(names, "/")
+}
+
+// depth returns how many folders deep the given folder is, counting a root folder as one.
+func (t tree) depth(id string) int {
+	return len(t.ancestry(id))
+}
+
+// within reports whether the given folder is the ancestor folder or sits anywhere below it.
+func (t tree) within(id string, ancestor string) bool {
+	for _, other := range t.ancestry(id) {
+		if other == ancestor {
+			return true
+		}
+	}
+
+	return false
+}
+
+// height returns how many levels of subfolders sit below the given folder.
+func (t tree) height(id string) int {
+	var height int
+	for other := range t {
+		if other != id && t.within(other, id) {
+			if d := t.depth(other) - t.depth(id); d > height {
+				height = d
+			}
+		}
+	}
+
+	return height
+}
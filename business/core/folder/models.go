@@ -0,0 +1,84 @@
+package folder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// Bounds on the names of folders and on how deep the hierarchy may grow.
+const (
+	nameMaxLength = 64
+	maxDepth      = 8
+)
+
+// Folder represents a folder in the hierarchy of a single workspace. Folders without a parent sit
+// at the root. The path lists the names of the folders leading to it, separated by slashes. The
+// owner is the member of the workspace who created the folder.
+type Folder struct {
+	ID          string    `json:"id"`
+	Workspace   string    `json:"workspace"`
+	Owner       string    `json:"owner"`
+	Name        string    `json:"name"`
+	ParentID    string    `json:"parent_id,omitempty"`
+	Path        string    `json:"path"`
+	DateCreated time.Time `json:"date_created"`
+	DateUpdated time.Time `json:"date_updated"`
+}
+
+// NewFolder contains the information needed to create a new folder. A blank parent creates the
+// folder at the root.
+type NewFolder struct {
+	Name     string `json:"name"`
+	ParentID string `json:"parent_id"`
+}
+
+// Validate checks that the information for a new folder is valid.
+func (nf NewFolder) Validate() error {
+	var fields validate.FieldErrors
+
+	if err := validateName(nf.Name); err != nil {
+		fields.Add("name", err.Error())
+	}
+
+	return fields.Err()
+}
+
+// UpdateFolder defines what information may be provided to rename or move a folder. A blank
+// parent moves the folder to the root.
+type UpdateFolder struct {
+	Name     *string `json:"name"`
+	ParentID *string `json:"parent_id"`
+}
+
+// Validate checks that the information for updating a folder is valid.
+func (uf UpdateFolder) Validate() error {
+	var fields validate.FieldErrors
+
+	if uf.Name != nil {
+		if err := validateName(*uf.Name); err != nil {
+			fields.Add("name", err.Error())
+		}
+	}
+
+	return fields.Err()
+}
+
+// validateName checks that a folder name is not blank, is not too long and can be told apart in a
+// path.
+func validateName(name string) error {
+	switch {
+	case strings.TrimSpace(name) != name:
+		return fmt.Errorf("must not start or end with spaces")
+	case name == "":
+		return fmt.Errorf("must not be blank")
+	case len(name) > nameMaxLength:
+		return fmt.Errorf("must be at most %d characters long", nameMaxLength)
+	case strings.Contains(name, "/"):
+		return fmt.Errorf("must not contain a slash")
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+// Package folderdb contains the database/sql implementation of the folder storer.
+package folderdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/yashshah7197/shrt/business/core/folder"
+	"github.com/yashshah7197/shrt/business/sys/database"
+)
+
+// Store manages the set of APIs for folder access in the database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore constructs a store for folders backed by the given database.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create inserts a new folder into the database.
+func (s *Store) Create(ctx context.Context, f folder.Folder) error {
+	const q = `
+	INSERT INTO folders
+		(folder_id, workspace, owner, name, parent_id, date_created, date_updated)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7)`
+
+	if _, err := s.db.ExecContext(ctx, q,
+		f.ID,
+		f.Workspace,
+		f.Owner,
+		f.Name,
+		f.ParentID,
+		f.DateCreated.UTC(),
+		f.DateUpdated.UTC(),
+	); err != nil {
+		if database.IsDuplicatedEntry(err) {
+			return folder.ErrNameTaken
+		}
+		return fmt.Errorf("inserting folder: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a folder in the database.
+func (s *Store) Update(ctx context.Context, f folder.Folder) error {
+	const q = `
+	UPDATE
+		folders
+	SET
+		name = $2,
+		parent_id = $3,
+		date_updated = $4
+	WHERE
+		folder_id = $1 AND workspace = $5`
+
+	res, err := s.db.ExecContext(ctx, q, f.ID, f.Name, f.ParentID, f.DateUpdated.UTC(), f.Workspace)
+	if err != nil {
+		if database.IsDuplicatedEntry(err) {
+			return folder.ErrNameTaken
+		}
+		return fmt.Errorf("updating folder[%s]: %w", f.ID, err)
+	}
+
+	return checkAffected(res)
+}
+
+// Delete removes the folder identified by the given ID in the specified workspace from the
+// database.
+func (s *Store) Delete(ctx context.Context, workspace string, id string) error {
+	const q = `
+	DELETE FROM
+		folders
+	WHERE
+		folder_id = $1 AND workspace = $2`
+
+	res, err := s.db.ExecContext(ctx, q, id, workspace)
+	if err != nil {
+		return fmt.Errorf("deleting folder[%s]: %w", id, err)
+	}
+
+	return checkAffected(res)
+}
+
+// QueryByID gets the folder identified by the given ID in the specified workspace from the
+// database.
+func (s *Store) QueryByID(ctx context.Context, workspace string, id string) (folder.Folder, error) {
+	const q = `
+	SELECT
+		folder_id, workspace, owner, name, parent_id, date_created, date_updated
+	FROM
+		folders
+	WHERE
+		folder_id = $1 AND workspace = $2`
+
+	f, err := scanFolder(s.db.QueryRowContext(ctx, q, id, workspace))
+	if err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return folder.Folder{}, folder.ErrNotFound
+		}
+		return folder.Folder{}, fmt.Errorf("selecting folder[%s]: %w", id, err)
+	}
+
+	return f, nil
+}
+
+// QueryByWorkspace gets every folder of the specified workspace.
+func (s *Store) QueryByWorkspace(ctx context.Context, workspace string) ([]folder.Folder, error) {
+	const q = `
+	SELECT
+		folder_id, workspace, owner, name, parent_id, date_created, date_updated
+	FROM
+		folders
+	WHERE
+		workspace = $1`
+
+	rows, err := s.db.QueryContext(ctx, q, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("selecting folders: %w", err)
+	}
+	defer rows.Close()
+
+	folders := []folder.Folder{}
+	for rows.Next() {
+		f, err := scanFolder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning folder: %w", err)
+		}
+		folders = append(folders, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating folders: %w", err)
+	}
+
+	return folders, nil
+}
+
+// scanner is implemented by both sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanFolder reads a single folder out of a row.
+func scanFolder(row scanner) (folder.Folder, error) {
+	var f folder.Folder
+	if err := row.Scan(&f.ID, &f.Workspace, &f.Owner, &f.Name, &f.ParentID, &f.DateCreated, &f.DateUpdated); err != nil {
+		return folder.Folder{}, err
+	}
+
+	return f, nil
+}
+
+// checkAffected translates a statement that touched no rows into folder.ErrNotFound.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return folder.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+// Package folderfile contains a durable, single-file implementation of the folder storer. Every
+// change is appended to a journal on disk and the folders are kept in memory for reads.
+package folderfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/folder"
+	"github.com/yashshah7197/shrt/business/core/folder/stores/foldermem"
+	"github.com/yashshah7197/shrt/business/sys/workspace"
+	"github.com/yashshah7197/shrt/foundation/journal"
+)
+
+// The set of operations recorded in the journal.
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// Store manages the set of APIs for folder access backed by a journal file.
+type Store struct {
+	mu      sync.Mutex
+	mem     *foldermem.Store
+	journal *journal.Journal
+}
+
+// Open constructs a store for folders by replaying the journal at the given path.
+func Open(path string) (*Store, error) {
+	ctx := context.Background()
+	mem := foldermem.NewStore()
+
+	// Rebuild the in-memory state from the journal records.
+	replay := func(rec journal.Record) error {
+		switch rec.Op {
+		case opPut:
+			var f folder.Folder
+			if err := json.Unmarshal(rec.Data, &f); err != nil {
+				return err
+			}
+
+			// Folders recorded before workspaces existed belong to the default one.
+			if f.Workspace == "" {
+				f.Workspace = workspace.Default
+			}
+
+			// Records hold the full state of a folder, so replace whatever was there before.
+			mem.Remove(f.ID)
+			return mem.Create(ctx, f)
+
+		case opDelete:
+			mem.Remove(rec.Key)
+			return nil
+
+		default:
+			return fmt.Errorf("unknown operation %q", rec.Op)
+		}
+	}
+
+	jrnl, err := journal.Open(path, replay)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	s := Store{
+		mem:     mem,
+		journal: jrnl,
+	}
+
+	// Folders change rarely, so simply start off with a compact journal every time.
+	snapshot := func(emit func(op string, key string, data interface{}) error) error {
+		for _, f := range mem.All() {
+			if err := emit(opPut, f.ID, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := jrnl.Compact(snapshot); err != nil {
+		jrnl.Close()
+		return nil, fmt.Errorf("compacting journal: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Close closes the underlying journal file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.journal.Close()
+}
+
+// Create inserts a new folder into the store.
+func (s *Store) Create(ctx context.Context, f folder.Folder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkName(ctx, f); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opPut, f.ID, f); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Create(ctx, f)
+}
+
+// Update replaces a folder in the store.
+func (s *Store) Update(ctx context.Context, f folder.Folder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByID(ctx, f.Workspace, f.ID); err != nil {
+		return err
+	}
+	if err := s.checkName(ctx, f); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opPut, f.ID, f); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Update(ctx, f)
+}
+
+// Delete removes the folder identified by the given ID in the specified workspace from the store.
+func (s *Store) Delete(ctx context.Context, workspace string, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByID(ctx, workspace, id); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opDelete, id, nil); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Delete(ctx, workspace, id)
+}
+
+// QueryByID gets the folder identified by the given ID in the specified workspace from the store.
+func (s *Store) QueryByID(ctx context.Context, workspace string, id string) (folder.Folder, error) {
+	return s.mem.QueryByID(ctx, workspace, id)
+}
+
+// QueryByWorkspace gets every folder of the specified workspace.
+func (s *Store) QueryByWorkspace(ctx context.Context, workspace string) ([]folder.Folder, error) {
+	return s.mem.QueryByWorkspace(ctx, workspace)
+}
+
+// checkName makes sure no other folder of the workspace with the same parent has the name of the
+// given folder before it reaches the journal, so the journal never holds a change the in-memory
+// store would turn down. The caller must hold the store lock.
+func (s *Store) checkName(ctx context.Context, f folder.Folder) error {
+	folders, err := s.mem.QueryByWorkspace(ctx, f.Workspace)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range folders {
+		if other.ID != f.ID && other.ParentID == f.ParentID && other.Name == f.Name {
+			return folder.ErrNameTaken
+		}
+	}
+
+	return nil
+}
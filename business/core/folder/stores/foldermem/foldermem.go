@@ -0,0 +1,127 @@
+// Package foldermem contains a concurrency-safe, in-memory implementation of the folder storer. It
+// is intended for tests and local development since nothing survives a restart.
+package foldermem
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/folder"
+)
+
+// Store manages the set of APIs for folder access held in memory.
+type Store struct {
+	mu      sync.RWMutex
+	folders map[string]folder.Folder
+}
+
+// NewStore constructs an empty in-memory store for folders.
+func NewStore() *Store {
+	return &Store{
+		folders: make(map[string]folder.Folder),
+	}
+}
+
+// Create inserts a new folder into the store.
+func (s *Store) Create(ctx context.Context, f folder.Folder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.nameTaken(f) {
+		return folder.ErrNameTaken
+	}
+	s.folders[f.ID] = f
+
+	return nil
+}
+
+// Update replaces a folder in the store.
+func (s *Store) Update(ctx context.Context, f folder.Folder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, exists := s.folders[f.ID]; !exists || existing.Workspace != f.Workspace {
+		return folder.ErrNotFound
+	}
+	if s.nameTaken(f) {
+		return folder.ErrNameTaken
+	}
+	s.folders[f.ID] = f
+
+	return nil
+}
+
+// Delete removes the folder identified by the given ID in the specified workspace from the store.
+func (s *Store) Delete(ctx context.Context, workspace string, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, exists := s.folders[id]; !exists || f.Workspace != workspace {
+		return folder.ErrNotFound
+	}
+	delete(s.folders, id)
+
+	return nil
+}
+
+// QueryByID gets the folder identified by the given ID in the specified workspace from the store.
+func (s *Store) QueryByID(ctx context.Context, workspace string, id string) (folder.Folder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, exists := s.folders[id]
+	if !exists || f.Workspace != workspace {
+		return folder.Folder{}, folder.ErrNotFound
+	}
+
+	return f, nil
+}
+
+// QueryByWorkspace gets every folder of the specified workspace.
+func (s *Store) QueryByWorkspace(ctx context.Context, workspace string) ([]folder.Folder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	folders := []folder.Folder{}
+	for _, f := range s.folders {
+		if f.Workspace == workspace {
+			folders = append(folders, f)
+		}
+	}
+
+	return folders, nil
+}
+
+// All returns every folder held in the store.
+func (s *Store) All() []folder.Folder {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	folders := make([]folder.Folder, 0, len(s.folders))
+	for _, f := range s.folders {
+		folders = append(folders, f)
+	}
+
+	return folders
+}
+
+// Remove removes the folder identified by the given ID from the store, whichever workspace it
+// belongs to. It is meant for replaying a journal.
+func (s *Store) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.folders, id)
+}
+
+// nameTaken reports whether another folder with the same workspace and parent already has the name
+// of the given folder. The caller must hold the lock.
+func (s *Store) nameTaken(f folder.Folder) bool {
+	for _, other := range s.folders {
+		if other.ID != f.ID && other.Workspace == f.Workspace && other.ParentID == f.ParentID && other.Name == f.Name {
+			return true
+		}
+	}
+
+	return false
+}
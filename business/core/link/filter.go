@@ -0,0 +1,231 @@
+package link
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// These are the expected values for QueryFilter.State.
+const (
+	StateActive    = "active"
+	StateScheduled = "scheduled"
+	StateExpired   = "expired"
+)
+
+// ErrInvalidCursor is returned when a cursor was not handed out by a previous query.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// QueryFilter holds the available fields a query of short links can be filtered on. Blank fields
+// are not filtered on. A link must carry every one of the tags, sit in any one of the folders, live
+// on the host and point at the domain or one of its subdomains. The state of a link is judged at
+// the time given as now. Deleted picks links in the trash or links outside of it, and both when it
+// is nil.
+type QueryFilter struct {
+	Workspace   string
+	Owner       string
+	Tags        []string
+	FolderIDs   []string
+	Host        string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Domain      string
+	State       string
+	Deleted     *bool
+	Now         time.Time
+}
+
+// Validate checks that the fields of the filter are valid.
+func (qf QueryFilter) Validate() error {
+	var fields validate.FieldErrors
+
+	for _, tag := range qf.Tags {
+		if err := validate.Slug(tag, 1, tagMaxLength); err != nil {
+			fields.Add("tag", err.Error())
+			break
+		}
+	}
+
+	if qf.CreatedFrom != nil && qf.CreatedTo != nil && !qf.CreatedTo.After(*qf.CreatedFrom) {
+		fields.Add("created_to", "must be after created_from")
+	}
+
+	if qf.Domain != "" && !isDomain(qf.Domain) {
+		fields.Add("domain", "must be a domain name")
+	}
+
+	switch qf.State {
+	case "", StateActive, StateScheduled, StateExpired:
+	default:
+		fields.Add("state", "must be one of active, scheduled or expired")
+	}
+
+	return fields.Err()
+}
+
+// Match reports whether the given link passes the filter. Stores which can't filter natively use
+// it to filter in memory.
+func (qf QueryFilter) Match(lnk Link) bool {
+	if qf.Workspace != "" && lnk.Workspace != qf.Workspace {
+		return false
+	}
+
+	if qf.Owner != "" && lnk.Owner != qf.Owner {
+		return false
+	}
+
+	if qf.Deleted != nil && lnk.Deleted() != *qf.Deleted {
+		return false
+	}
+
+	for _, tag := range qf.Tags {
+		if !contains(lnk.Tags, tag) {
+			return false
+		}
+	}
+
+	if len(qf.FolderIDs) > 0 && !contains(qf.FolderIDs, lnk.FolderID) {
+		return false
+	}
+
+	if qf.Host != "" && lnk.Host != qf.Host {
+		return false
+	}
+
+	if qf.CreatedFrom != nil && lnk.DateCreated.Before(*qf.CreatedFrom) {
+		return false
+	}
+
+	if qf.CreatedTo != nil && !lnk.DateCreated.Before(*qf.CreatedTo) {
+		return false
+	}
+
+	if qf.Domain != "" && !InDomain(lnk.Destination, qf.Domain) {
+		return false
+	}
+
+	switch qf.State {
+	case StateActive:
+		return lnk.Active(qf.Now)
+	case StateScheduled:
+		return lnk.DateArchived == nil && lnk.ActivatesAt != nil && qf.Now.Before(*lnk.ActivatesAt) && !lnk.Expired(qf.Now)
+	case StateExpired:
+		return lnk.DateArchived != nil || lnk.Expired(qf.Now)
+	}
+
+	return true
+}
+
+// InDomain reports whether the given URL points at the domain or one of its subdomains.
+func InDomain(rawURL string, domain string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// isDomain reports whether the string looks like a lowercase domain name.
+func isDomain(domain string) bool {
+	if domain == "" || len(domain) > 253 || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return false
+	}
+
+	for _, r := range domain {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// contains reports whether the list holds the value.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// =================================================================================================
+
+// Cursor marks the position of a short link in the order queries return links in, which is newest
+// first with ties broken by code and then host. A page of links starts right after its cursor.
+type Cursor struct {
+	DateCreated time.Time
+	Code        string
+	Host        string
+}
+
+// CursorOf returns the cursor which marks the position of the given link.
+func CursorOf(lnk Link) Cursor {
+	return Cursor{
+		DateCreated: lnk.DateCreated,
+		Code:        lnk.Code,
+		Host:        lnk.Host,
+	}
+}
+
+// After reports whether the given link comes after the cursor.
+func (c Cursor) After(lnk Link) bool {
+	if lnk.DateCreated.Equal(c.DateCreated) {
+		if lnk.Code == c.Code {
+			return lnk.Host > c.Host
+		}
+		return lnk.Code > c.Code
+	}
+
+	return lnk.DateCreated.Before(c.DateCreated)
+}
+
+// String returns the opaque form of the cursor handed out to clients.
+func (c Cursor) String() string {
+	raw := strconv.FormatInt(c.DateCreated.UnixNano(), 10) + "." + Key(c.Host, c.Code)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor parses the opaque form of a cursor.
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ".", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	host, code := SplitKey(parts[1])
+	if code == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	c := Cursor{
+		DateCreated: time.Unix(0, nanos).UTC(),
+		Code:        code,
+		Host:        host,
+	}
+
+	return c, nil
+}
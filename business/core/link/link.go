@@ -0,0 +1,1077 @@
+// Package link provides the core business API for creating, resolving and managing short links.
+package link
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/brand"
+	"github.com/yashshah7197/shrt/business/core/click"
+	"github.com/yashshah7197/shrt/business/core/folder"
+	"github.com/yashshah7197/shrt/business/core/policy"
+	"github.com/yashshah7197/shrt/business/core/reserved"
+	"github.com/yashshah7197/shrt/business/sys/codegen"
+	"github.com/yashshah7197/shrt/business/sys/metrics"
+	"github.com/yashshah7197/shrt/business/sys/reputation"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/foundation/search"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound      = errors.New("link not found")
+	ErrCodeTaken     = errors.New("code is already in use")
+	ErrCodeExhausted = errors.New("unable to generate a unique code")
+	ErrInactive      = errors.New("link is not active")
+	ErrWrongPassword = errors.New("password does not match")
+	ErrNoRevision    = errors.New("revision not found")
+	ErrDeleted       = errors.New("link is deleted")
+	ErrNotDeleted    = errors.New("link is not deleted")
+	ErrPastRestore   = errors.New("link can no longer be restored")
+	ErrFlagged       = errors.New("link is flagged as a threat")
+)
+
+// searchWeights sets how much a match within each field of a link counts towards its rank in
+// search results.
+var searchWeights = map[string]float64{
+	"code":        2,
+	"title":       3,
+	"notes":       1,
+	"destination": 1.5,
+}
+
+// Storer defines the behavior required to persist and retrieve short links. Implementations must
+// be safe for concurrent use. Changes made by users go through Create and Revise, which store the
+// link together with its revision; the store numbers the revisions after the first in order.
+// Changes made by the system are not revisions and only ever touch the fields they are about:
+// Archive archives a link only while it is still expired at the given time, and UpdateThreats
+// writes just the threats of a link only while its destination and fallback URL are the ones it
+// was given with, both returning ErrNotFound otherwise. Query returns the links passing the filter
+// which come after the cursor, in cursor order, and all of them when the limit is zero. Links are
+// identified by their host and code together, with a blank host for the default namespace. Links
+// are only found, revised and deleted within the given workspace, or the workspace of the link
+// passed to Revise, and any other link is reported as ErrNotFound. Just like in a filter, a blank
+// workspace given to QueryByCode matches every workspace, which is how visitors find links.
+type Storer interface {
+	Create(ctx context.Context, lnk Link, rev Revision) error
+	Revise(ctx context.Context, lnk Link, rev Revision) error
+	Delete(ctx context.Context, workspace string, host string, code string) error
+	QueryByCode(ctx context.Context, workspace string, host string, code string) (Link, error)
+	Query(ctx context.Context, filter QueryFilter, after *Cursor, limit int) ([]Link, error)
+	QueryRevisions(ctx context.Context, workspace string, host string, code string) ([]Revision, error)
+	QueryExpired(ctx context.Context, now time.Time) ([]Link, error)
+	QueryDeleted(ctx context.Context, before time.Time) ([]Link, error)
+	IncrementClicks(ctx context.Context, host string, code string) (Link, error)
+	Archive(ctx context.Context, host string, code string, now time.Time) error
+	UpdateThreats(ctx context.Context, lnk Link) error
+	NextSequence(ctx context.Context) (uint64, error)
+}
+
+// Config represents the dependencies and settings required by the Core. URLs are not checked for
+// threats when Reputation is nil.
+type Config struct {
+	Storer         Storer
+	Generator      codegen.Generator
+	MaxAttempts    int
+	Reserved       *reserved.Core
+	Click          *click.Core
+	Folder         *folder.Core
+	Brand          *brand.Core
+	Policy         *policy.Core
+	Reputation     reputation.Checker
+	TrashRetention time.Duration
+	Logger         *zap.SugaredLogger
+}
+
+// Core manages the set of APIs for short link access.
+type Core struct {
+	storer         Storer
+	generator      codegen.Generator
+	maxAttempts    int
+	reserved       *reserved.Core
+	click          *click.Core
+	folder         *folder.Core
+	brand          *brand.Core
+	policy         *policy.Core
+	reputation     reputation.Checker
+	index          *search.Index
+	trashRetention time.Duration
+	logger         *zap.SugaredLogger
+}
+
+// NewCore constructs a Core for short link API access. Its search index starts out empty until
+// Reindex is called.
+func NewCore(cfg Config) *Core {
+	return &Core{
+		storer:         cfg.Storer,
+		generator:      cfg.Generator,
+		maxAttempts:    cfg.MaxAttempts,
+		reserved:       cfg.Reserved,
+		click:          cfg.Click,
+		folder:         cfg.Folder,
+		brand:          cfg.Brand,
+		policy:         cfg.Policy,
+		reputation:     cfg.Reputation,
+		index:          search.NewIndex(searchWeights),
+		trashRetention: cfg.TrashRetention,
+		logger:         cfg.Logger,
+	}
+}
+
+// Create inserts a new short link into the specified workspace, owned by the specified subject. The
+// link uses the requested alias as its code if one is provided, otherwise a unique code is
+// generated. Either way the code only has to be unique on the host of the link.
+func (c *Core) Create(ctx context.Context, nl NewLink, workspace string, owner string, now time.Time) (Link, error) {
+	if err := nl.Validate(); err != nil {
+		return Link{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	if err := c.checkPolicy(ctx, nl.Destination, nl.FallbackURL); err != nil {
+		return Link{}, err
+	}
+
+	lnk := Link{
+		Host:           brand.Normalize(nl.Host),
+		Destination:    nl.Destination,
+		Workspace:      workspace,
+		Owner:          owner,
+		Title:          nl.Title,
+		Notes:          nl.Notes,
+		Tags:           normalizeTags(nl.Tags),
+		FolderID:       nl.FolderID,
+		RedirectStatus: nl.RedirectStatus,
+		ActivatesAt:    nl.ActivatesAt,
+		ExpiresAt:      nl.ExpiresAt,
+		MaxClicks:      nl.MaxClicks,
+		FallbackURL:    nl.FallbackURL,
+		DateCreated:    now,
+		DateUpdated:    now,
+	}
+
+	if err := c.checkFolder(ctx, lnk.FolderID, workspace); err != nil {
+		return Link{}, err
+	}
+
+	if err := c.checkHost(ctx, lnk.Host, workspace, owner); err != nil {
+		return Link{}, err
+	}
+
+	if nl.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(nl.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return Link{}, fmt.Errorf("generating password hash: %w", err)
+		}
+		lnk.Protected = true
+		lnk.PasswordHash = hash
+	}
+
+	if nl.Alias != "" {
+		return c.createAlias(ctx, lnk, nl.Alias)
+	}
+
+	// Keep generating codes until we find one that is not taken, up to a bounded number of
+	// attempts. Codes which happen to be reserved are skipped as well.
+	for i := 0; i < c.maxAttempts; i++ {
+		code, err := c.generator.Generate(ctx)
+		if err != nil {
+			return Link{}, fmt.Errorf("generating code: %w", err)
+		}
+
+		if err := c.reserved.Check(ctx, code); err != nil {
+			if errors.Is(err, reserved.ErrReserved) || errors.Is(err, reserved.ErrBlocked) {
+				continue
+			}
+			return Link{}, fmt.Errorf("checking code: %w", err)
+		}
+
+		lnk.Code = code
+		if err := c.storer.Create(ctx, lnk, creation(lnk)); err != nil {
+			if errors.Is(err, ErrCodeTaken) {
+				metrics.AddCollision(ctx)
+				continue
+			}
+			return Link{}, fmt.Errorf("create: %w", err)
+		}
+		c.index.Put(document(lnk))
+
+		return lnk, nil
+	}
+
+	return Link{}, ErrCodeExhausted
+}
+
+// createAlias inserts a new short link using the requested alias as its code. Aliases which are
+// reserved, blocked or already taken are turned down as field errors.
+func (c *Core) createAlias(ctx context.Context, lnk Link, alias string) (Link, error) {
+	if err := c.reserved.Check(ctx, alias); err != nil {
+		if errors.Is(err, reserved.ErrReserved) || errors.Is(err, reserved.ErrBlocked) {
+			return Link{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "alias", Error: err.Error()}})
+		}
+		return Link{}, fmt.Errorf("checking alias: %w", err)
+	}
+
+	lnk.Code = alias
+	if err := c.storer.Create(ctx, lnk, creation(lnk)); err != nil {
+		if errors.Is(err, ErrCodeTaken) {
+			return Link{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "alias", Error: "is already taken"}})
+		}
+		return Link{}, fmt.Errorf("create: %w", err)
+	}
+	c.index.Put(document(lnk))
+
+	return lnk, nil
+}
+
+// Update changes the modifiable fields of the short link identified by the given host and code in
+// the specified workspace on behalf of the given author. Every update that changes anything is kept
+// as a revision.
+func (c *Core) Update(ctx context.Context, workspace string, host string, code string, ul UpdateLink, author string, now time.Time) (Link, error) {
+	if err := ul.Validate(); err != nil {
+		return Link{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	lnk, err := c.storer.QueryByCode(ctx, workspace, host, code)
+	if err != nil {
+		return Link{}, fmt.Errorf("query: %w", err)
+	}
+	if lnk.Deleted() {
+		return Link{}, ErrDeleted
+	}
+	before := settingsOf(lnk)
+
+	var destination, fallbackURL string
+	if ul.Destination != nil && *ul.Destination != lnk.Destination {
+		destination = *ul.Destination
+	}
+	if ul.FallbackURL != nil && *ul.FallbackURL != lnk.FallbackURL {
+		fallbackURL = *ul.FallbackURL
+	}
+	if err := c.checkPolicy(ctx, destination, fallbackURL); err != nil {
+		return Link{}, err
+	}
+
+	if ul.Destination != nil {
+		lnk.Destination = *ul.Destination
+	}
+	if ul.Title != nil {
+		lnk.Title = *ul.Title
+	}
+	if ul.Notes != nil {
+		lnk.Notes = *ul.Notes
+	}
+	if ul.Tags != nil {
+		lnk.Tags = normalizeTags(*ul.Tags)
+	}
+	if ul.RedirectStatus != nil {
+		lnk.RedirectStatus = *ul.RedirectStatus
+	}
+	if ul.ActivatesAt != nil {
+		lnk.ActivatesAt = nonZero(*ul.ActivatesAt)
+	}
+	if ul.ExpiresAt != nil {
+		lnk.ExpiresAt = nonZero(*ul.ExpiresAt)
+	}
+	if ul.MaxClicks != nil {
+		lnk.MaxClicks = *ul.MaxClicks
+	}
+	if ul.FallbackURL != nil {
+		lnk.FallbackURL = *ul.FallbackURL
+	}
+
+	changes := before.diff(settingsOf(lnk))
+
+	// The folder only files the link away, so it is not part of the settings which can be rolled
+	// back. Moving the link is still recorded.
+	if ul.FolderID != nil && *ul.FolderID != lnk.FolderID {
+		if err := c.checkFolder(ctx, *ul.FolderID, lnk.Workspace); err != nil {
+			return Link{}, err
+		}
+		lnk.FolderID = *ul.FolderID
+		changes = append(changes, "folder")
+	}
+
+	if ul.Password != nil {
+		lnk.Protected = false
+		lnk.PasswordHash = nil
+		if *ul.Password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(*ul.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return Link{}, fmt.Errorf("generating password hash: %w", err)
+			}
+			lnk.Protected = true
+			lnk.PasswordHash = hash
+		}
+		changes = append(changes, "password")
+	}
+
+	if len(changes) == 0 {
+		return lnk, nil
+	}
+
+	rev := Revision{
+		Changes: changes,
+	}
+
+	return c.revise(ctx, lnk, rev, author, now)
+}
+
+// Rollback restores the settings of the short link identified by the given host and code in the
+// specified workspace to those recorded in the given revision, on behalf of the given author. The
+// rollback is itself kept as a new revision, so it can be undone in turn. The password of the link
+// is left as it is.
+func (c *Core) Rollback(ctx context.Context, workspace string, host string, code string, number int, author string, now time.Time) (Link, error) {
+	lnk, err := c.storer.QueryByCode(ctx, workspace, host, code)
+	if err != nil {
+		return Link{}, fmt.Errorf("query: %w", err)
+	}
+	if lnk.Deleted() {
+		return Link{}, ErrDeleted
+	}
+
+	revs, err := c.storer.QueryRevisions(ctx, workspace, host, code)
+	if err != nil {
+		return Link{}, fmt.Errorf("query revisions: %w", err)
+	}
+
+	var target *Revision
+	for i := range revs {
+		if revs[i].Number == number {
+			target = &revs[i]
+			break
+		}
+	}
+	if target == nil {
+		return Link{}, ErrNoRevision
+	}
+
+	changes := settingsOf(lnk).diff(target.Settings)
+	if len(changes) == 0 {
+		return lnk, nil
+	}
+
+	// The policy may have changed since the revision was made, so the URLs it brings back are
+	// checked again.
+	var destination, fallbackURL string
+	if target.Settings.Destination != lnk.Destination {
+		destination = target.Settings.Destination
+	}
+	if target.Settings.FallbackURL != lnk.FallbackURL {
+		fallbackURL = target.Settings.FallbackURL
+	}
+	if err := c.checkPolicy(ctx, destination, fallbackURL); err != nil {
+		return Link{}, err
+	}
+	target.Settings.apply(&lnk)
+
+	rev := Revision{
+		Changes:    changes,
+		RollbackOf: number,
+	}
+
+	return c.revise(ctx, lnk, rev, author, now)
+}
+
+// revise stores the changed link along with its revision.
+func (c *Core) revise(ctx context.Context, lnk Link, rev Revision, author string, now time.Time) (Link, error) {
+	if lnk.ActivatesAt != nil && lnk.ExpiresAt != nil && !lnk.ExpiresAt.After(*lnk.ActivatesAt) {
+		return Link{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "expires_at", Error: "must be after activates_at"}})
+	}
+
+	// Changing the window or the budget of an archived link brings it back if it is no longer
+	// expired. The sweeper archives it again otherwise.
+	if lnk.DateArchived != nil && changesWindow(rev.Changes) && !lnk.Expired(now) {
+		lnk.DateArchived = nil
+	}
+
+	// The threats found were those of the old URLs, so they no longer apply. The next rescan looks
+	// at the new ones.
+	if changesURL(rev.Changes) {
+		lnk.Threats = nil
+		lnk.DateFlagged = nil
+	}
+	lnk.DateUpdated = now
+
+	rev.Code = lnk.Code
+	rev.Host = lnk.Host
+	rev.Author = author
+	rev.Settings = settingsOf(lnk)
+	rev.DateCreated = now
+
+	if err := c.storer.Revise(ctx, lnk, rev); err != nil {
+		return Link{}, fmt.Errorf("revise: %w", err)
+	}
+	c.index.Put(document(lnk))
+
+	return lnk, nil
+}
+
+// QueryRevisions gets every revision of the short link identified by the given host and code in
+// the specified workspace, newest first.
+func (c *Core) QueryRevisions(ctx context.Context, workspace string, host string, code string) ([]Revision, error) {
+	revs, err := c.storer.QueryRevisions(ctx, workspace, host, code)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return revs, nil
+}
+
+// Delete moves the short link identified by the given host and code in the specified workspace to
+// the trash on behalf of the given author. Visitors of a deleted link are told it was removed, and
+// it can be restored until the trash retention has passed. Deleting a link which is already in the
+// trash does nothing.
+func (c *Core) Delete(ctx context.Context, workspace string, host string, code string, author string, now time.Time) error {
+	lnk, err := c.storer.QueryByCode(ctx, workspace, host, code)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	if lnk.Deleted() {
+		return nil
+	}
+
+	lnk.DateDeleted = &now
+	lnk.DateUpdated = now
+
+	rev := Revision{
+		Code:        code,
+		Host:        host,
+		Author:      author,
+		Changes:     []string{"deleted"},
+		Settings:    settingsOf(lnk),
+		DateCreated: now,
+	}
+
+	if err := c.storer.Revise(ctx, lnk, rev); err != nil {
+		return fmt.Errorf("revise: %w", err)
+	}
+	c.index.Remove(lnk.Key())
+
+	return nil
+}
+
+// Restore takes the short link identified by the given host and code in the specified workspace
+// back out of the trash on behalf of the given author. ErrPastRestore is returned once the trash
+// retention has passed, even if the link has not been purged yet.
+func (c *Core) Restore(ctx context.Context, workspace string, host string, code string, author string, now time.Time) (Link, error) {
+	lnk, err := c.storer.QueryByCode(ctx, workspace, host, code)
+	if err != nil {
+		return Link{}, fmt.Errorf("query: %w", err)
+	}
+	if !lnk.Deleted() {
+		return Link{}, ErrNotDeleted
+	}
+	if !now.Before(c.PurgeAt(lnk)) {
+		return Link{}, ErrPastRestore
+	}
+
+	lnk.DateDeleted = nil
+	lnk.DateUpdated = now
+
+	rev := Revision{
+		Code:        code,
+		Host:        host,
+		Author:      author,
+		Changes:     []string{"restored"},
+		Settings:    settingsOf(lnk),
+		DateCreated: now,
+	}
+
+	if err := c.storer.Revise(ctx, lnk, rev); err != nil {
+		return Link{}, fmt.Errorf("revise: %w", err)
+	}
+	c.index.Put(document(lnk))
+
+	return lnk, nil
+}
+
+// PurgeAt returns the time at which the given deleted link is due to be purged.
+func (c *Core) PurgeAt(lnk Link) time.Time {
+	if lnk.DateDeleted == nil {
+		return time.Time{}
+	}
+
+	return lnk.DateDeleted.Add(c.trashRetention)
+}
+
+// PurgeDeleted removes every short link which has been in the trash for longer than the trash
+// retention at the given time for good, along with its clicks and revisions, and returns how many
+// were purged. The clicks go first, so a failed purge never leaves clicks behind without a link.
+func (c *Core) PurgeDeleted(ctx context.Context, now time.Time) (int, error) {
+	links, err := c.storer.QueryDeleted(ctx, now.Add(-c.trashRetention))
+	if err != nil {
+		return 0, fmt.Errorf("query deleted: %w", err)
+	}
+	if len(links) == 0 {
+		return 0, nil
+	}
+
+	codes := make(map[string][]string)
+	for _, lnk := range links {
+		codes[lnk.Host] = append(codes[lnk.Host], lnk.Code)
+	}
+
+	for host, hostCodes := range codes {
+		if err := c.click.Delete(ctx, host, hostCodes); err != nil {
+			return 0, fmt.Errorf("deleting clicks: %w", err)
+		}
+	}
+
+	var purged int
+	for _, lnk := range links {
+		c.index.Remove(lnk.Key())
+		if err := c.storer.Delete(ctx, lnk.Workspace, lnk.Host, lnk.Code); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return purged, fmt.Errorf("purging link[%s]: %w", lnk.Key(), err)
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// QueryByCode gets the short link identified by the given host and code in the specified
+// workspace. A blank workspace finds the link whichever workspace it belongs to, as visitors do.
+func (c *Core) QueryByCode(ctx context.Context, workspace string, host string, code string) (Link, error) {
+	lnk, err := c.storer.QueryByCode(ctx, workspace, host, code)
+	if err != nil {
+		return Link{}, fmt.Errorf("query: %w", err)
+	}
+
+	return lnk, nil
+}
+
+// Unlock checks the given password against the protected short link identified by the given host
+// and code. ErrWrongPassword is returned when it doesn't match. Links without a password are always
+// unlocked.
+func (c *Core) Unlock(ctx context.Context, host string, code string, password string) (Link, error) {
+	lnk, err := c.storer.QueryByCode(ctx, "", host, code)
+	if err != nil {
+		return Link{}, fmt.Errorf("query: %w", err)
+	}
+
+	if lnk.Deleted() {
+		return Link{}, ErrDeleted
+	}
+
+	if !lnk.Protected {
+		return lnk, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword(lnk.PasswordHash, []byte(password)); err != nil {
+		return Link{}, ErrWrongPassword
+	}
+
+	return lnk, nil
+}
+
+// Visit records a click on the given short link, as the caller has just looked it up, and returns
+// it. Links without a click budget are handed straight back, so the common path stays free of
+// store access. ErrInactive is returned together with the link when it can't be followed at the
+// given time, so callers can still make use of its fallback URL.
+func (c *Core) Visit(ctx context.Context, lnk Link, now time.Time) (Link, error) {
+	if err := followable(lnk, now); err != nil {
+		return lnk, err
+	}
+
+	if lnk.MaxClicks == 0 {
+		return lnk, nil
+	}
+
+	// Spend a click from the budget. Concurrent visitors race for the last clicks, so only those
+	// who got in within the budget are let through.
+	lnk, err := c.storer.IncrementClicks(ctx, lnk.Host, lnk.Code)
+	if err != nil {
+		return Link{}, fmt.Errorf("increment clicks: %w", err)
+	}
+
+	// The link may have changed since the caller looked it up, so check it again as it was just
+	// before this click was spent.
+	before := lnk
+	before.Clicks--
+	if err := followable(before, now); err != nil {
+		return lnk, err
+	}
+
+	return lnk, nil
+}
+
+// followable returns the error a visitor of the given link is turned away with at the given time,
+// if any.
+func followable(lnk Link, now time.Time) error {
+	switch {
+	case lnk.Deleted():
+		return ErrDeleted
+	case lnk.Flagged():
+		return ErrFlagged
+	case !lnk.Active(now):
+		return ErrInactive
+	}
+
+	return nil
+}
+
+// ArchiveExpired archives every short link which has expired at the given time and returns how
+// many were archived. Links which are changed in the meantime so that they no longer expire are
+// left alone.
+func (c *Core) ArchiveExpired(ctx context.Context, now time.Time) (int, error) {
+	links, err := c.storer.QueryExpired(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("query expired: %w", err)
+	}
+
+	var archived int
+	for _, lnk := range links {
+		if err := c.storer.Archive(ctx, lnk.Host, lnk.Code, now); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return archived, fmt.Errorf("archiving link[%s]: %w", lnk.Code, err)
+		}
+		archived++
+	}
+
+	return archived, nil
+}
+
+// Rescan checks the destination and fallback URL of every short link outside of the trash against
+// the threat lists again, as they change over time. Links found to point at a threat are flagged at
+// the given time, and flagged links which no longer do are cleared. Only the threats of a link are
+// written, and only if its URLs are still the ones which were checked, so that changes made in the
+// meantime are kept. Links which can't be checked are logged and left for the next pass. It returns
+// how many links were flagged and cleared.
+func (c *Core) Rescan(ctx context.Context, now time.Time) (flagged int, cleared int, err error) {
+	if c.reputation == nil {
+		return 0, 0, nil
+	}
+
+	deleted := false
+	links, err := c.storer.Query(ctx, QueryFilter{Deleted: &deleted}, nil, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("query: %w", err)
+	}
+
+	for _, lnk := range links {
+		threats, err := c.threats(ctx, lnk.Destination, lnk.FallbackURL)
+		if err != nil {
+			c.logger.Errorw("rescan", "status", "skipping link", "link", lnk.Key(), "ERROR", err)
+			continue
+		}
+
+		var flag, clear bool
+		switch {
+		case len(threats) > 0 && !lnk.Flagged():
+			lnk.DateFlagged = &now
+			flag = true
+		case len(threats) == 0 && lnk.Flagged():
+			lnk.DateFlagged = nil
+			clear = true
+		case strings.Join(threats, ",") == strings.Join(lnk.Threats, ","):
+			continue
+		}
+		lnk.Threats = threats
+
+		if err := c.storer.UpdateThreats(ctx, lnk); err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				c.logger.Errorw("rescan", "status", "skipping link", "link", lnk.Key(), "ERROR", err)
+			}
+			continue
+		}
+
+		if flag {
+			flagged++
+		}
+		if clear {
+			cleared++
+		}
+	}
+
+	return flagged, cleared, nil
+}
+
+// Query gets a page of at most limit short links which pass the filter, newest first, starting
+// right after the given cursor. A nil cursor starts at the first page. The cursor of the next page
+// is returned along with the links, and is nil on the last page.
+func (c *Core) Query(ctx context.Context, filter QueryFilter, after *Cursor, limit int) ([]Link, *Cursor, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("validating data: %w", err)
+	}
+
+	if limit < 1 {
+		return nil, nil, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "limit", Error: "must be a positive number"}})
+	}
+
+	// Ask for one more than a page, to tell whether there is another page after it.
+	links, err := c.storer.Query(ctx, filter, after, limit+1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query: %w", err)
+	}
+
+	var next *Cursor
+	if len(links) > limit {
+		links = links[:limit]
+		cur := CursorOf(links[limit-1])
+		next = &cur
+	}
+
+	return links, next, nil
+}
+
+// QueryTags gets every tag carried by the links of the specified workspace outside of the trash,
+// along with how many links carry it, in alphabetical order.
+func (c *Core) QueryTags(ctx context.Context, workspace string) ([]TagCount, error) {
+	deleted := false
+	links, err := c.storer.Query(ctx, QueryFilter{Workspace: workspace, Deleted: &deleted}, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for _, lnk := range links {
+		for _, tag := range lnk.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]TagCount, 0, len(counts))
+	for tag, n := range counts {
+		tags = append(tags, TagCount{Tag: tag, Links: n})
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	return tags, nil
+}
+
+// Search finds the short links outside of the trash whose code, title, notes or destination match
+// the query, best match first. The words of the query match words of the link which start with
+// them, so part of a URL is enough to find the links pointing at it. Only the links of the
+// specified workspace are searched.
+func (c *Core) Search(ctx context.Context, query string, workspace string, limit int) ([]SearchResult, error) {
+	var fields validate.FieldErrors
+	if strings.TrimSpace(query) == "" {
+		fields.Add("q", "must not be blank")
+	} else if len(query) > queryMaxLength {
+		fields.Add("q", fmt.Sprintf("must be at most %d characters long", queryMaxLength))
+	}
+	if limit < 1 {
+		fields.Add("limit", "must be a positive number")
+	}
+	if err := fields.Err(); err != nil {
+		return nil, fmt.Errorf("validating data: %w", err)
+	}
+
+	hits := c.index.Search(query, workspace, limit)
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		host, code := SplitKey(hit.ID)
+		lnk, err := c.storer.QueryByCode(ctx, workspace, host, code)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("query link[%s]: %w", hit.ID, err)
+		}
+
+		// The index may trail the store when the link was changed by another instance.
+		if lnk.Deleted() {
+			continue
+		}
+
+		results = append(results, SearchResult{
+			Link:       lnk,
+			Score:      hit.Score,
+			Highlights: hit.Highlights,
+		})
+	}
+
+	return results, nil
+}
+
+// Reindex rebuilds the search index from every short link outside of the trash and returns how many
+// links were indexed. Changes made through the Core keep the index up to date as they happen, so
+// this is only needed at startup and to pick up changes made by other instances.
+func (c *Core) Reindex(ctx context.Context) (int, error) {
+	deleted := false
+	links, err := c.storer.Query(ctx, QueryFilter{Deleted: &deleted}, nil, 0)
+	if err != nil {
+		return 0, fmt.Errorf("query: %w", err)
+	}
+
+	docs := make([]search.Document, len(links))
+	for i, lnk := range links {
+		docs[i] = document(lnk)
+	}
+	c.index.Replace(docs)
+
+	return len(docs), nil
+}
+
+// RenameTag renames a tag across every link of the specified workspace, on behalf of the given
+// author, and returns how many links were changed. Links which already carry the new name simply
+// lose the old one.
+func (c *Core) RenameTag(ctx context.Context, workspace string, tag string, rt RenameTag, author string, now time.Time) (int, error) {
+	if err := rt.Validate(); err != nil {
+		return 0, fmt.Errorf("validating data: %w", err)
+	}
+
+	return c.retag(ctx, workspace, []string{tag}, rt.Name, author, now)
+}
+
+// MergeTags replaces a set of tags with a single one across every link of the specified workspace,
+// on behalf of the given author, and returns how many links were changed.
+func (c *Core) MergeTags(ctx context.Context, workspace string, mt MergeTags, author string, now time.Time) (int, error) {
+	if err := mt.Validate(); err != nil {
+		return 0, fmt.Errorf("validating data: %w", err)
+	}
+
+	return c.retag(ctx, workspace, mt.Tags, mt.Into, author, now)
+}
+
+// retag replaces the given tags with the target tag on every link of the workspace which carries
+// any of them, including links in the trash. Every changed link gets a revision.
+func (c *Core) retag(ctx context.Context, workspace string, from []string, to string, author string, now time.Time) (int, error) {
+	to = strings.ToLower(to)
+	replace := make(map[string]bool, len(from))
+	for _, tag := range from {
+		replace[strings.ToLower(tag)] = true
+	}
+
+	links, err := c.storer.Query(ctx, QueryFilter{Workspace: workspace}, nil, 0)
+	if err != nil {
+		return 0, fmt.Errorf("query: %w", err)
+	}
+
+	var changed int
+	for _, lnk := range links {
+		tags := make([]string, 0, len(lnk.Tags))
+		var found bool
+		for _, tag := range lnk.Tags {
+			if replace[tag] {
+				tag = to
+				found = true
+			}
+			tags = append(tags, tag)
+		}
+		if !found {
+			continue
+		}
+
+		before := settingsOf(lnk)
+		lnk.Tags = normalizeTags(tags)
+		changes := before.diff(settingsOf(lnk))
+		if len(changes) == 0 {
+			continue
+		}
+		lnk.DateUpdated = now
+
+		rev := Revision{
+			Code:        lnk.Code,
+			Host:        lnk.Host,
+			Author:      author,
+			Changes:     changes,
+			Settings:    settingsOf(lnk),
+			DateCreated: now,
+		}
+
+		if err := c.storer.Revise(ctx, lnk, rev); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return changed, fmt.Errorf("retagging link[%s]: %w", lnk.Code, err)
+		}
+		if !lnk.Deleted() {
+			c.index.Put(document(lnk))
+		}
+		changed++
+	}
+
+	return changed, nil
+}
+
+// checkFolder makes sure that the folder a link is filed in exists and belongs to the workspace of
+// the link. A blank folder files the link at the root.
+func (c *Core) checkFolder(ctx context.Context, folderID string, workspace string) error {
+	if folderID == "" {
+		return nil
+	}
+
+	if _, err := c.folder.QueryByID(ctx, workspace, folderID); err != nil {
+		if !errors.Is(err, folder.ErrNotFound) {
+			return fmt.Errorf("query folder: %w", err)
+		}
+		return fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "folder_id", Error: "does not exist"}})
+	}
+
+	return nil
+}
+
+// checkHost makes sure that the branded domain a link is created on exists in the workspace of the
+// link and lets the owner of the link create links on it. A blank host puts the link in the
+// default namespace.
+func (c *Core) checkHost(ctx context.Context, host string, workspace string, owner string) error {
+	if host == "" {
+		return nil
+	}
+
+	d, err := c.brand.QueryByHost(ctx, workspace, host)
+	if err != nil && !errors.Is(err, brand.ErrNotFound) {
+		return fmt.Errorf("query domain: %w", err)
+	}
+
+	switch {
+	case err != nil:
+		return fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "host", Error: "is not a known domain"}})
+	case !d.Allows(owner):
+		return fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "host", Error: "does not allow you to create links"}})
+	}
+
+	return nil
+}
+
+// checkPolicy runs the destination and fallback URL of a link through the destination policy and
+// the reputation checker, turning the URLs which break the policy or are known threats into field
+// errors. Blank URLs are not checked.
+func (c *Core) checkPolicy(ctx context.Context, destination string, fallbackURL string) error {
+	var fields validate.FieldErrors
+
+	check := func(field string, rawURL string) error {
+		if rawURL == "" {
+			return nil
+		}
+
+		err := c.policy.Check(ctx, rawURL)
+		switch {
+		case err == nil:
+		case policy.IsViolation(err):
+			fields.Add(field, err.Error())
+			return nil
+		default:
+			return fmt.Errorf("checking %s: %w", field, err)
+		}
+
+		threats, err := c.threats(ctx, rawURL)
+		if err != nil {
+			return fmt.Errorf("checking %s: %w", field, err)
+		}
+		if len(threats) > 0 {
+			fields.Add(field, "is flagged as "+strings.Join(threats, ", "))
+		}
+
+		return nil
+	}
+
+	if err := check("destination", destination); err != nil {
+		return err
+	}
+	if err := check("fallback_url", fallbackURL); err != nil {
+		return err
+	}
+
+	if err := fields.Err(); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	return nil
+}
+
+// threats returns the threat lists any of the URLs is on, in alphabetical order. Blank URLs are
+// skipped, and nothing is checked when there is no reputation checker.
+func (c *Core) threats(ctx context.Context, rawURLs ...string) ([]string, error) {
+	if c.reputation == nil {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var threats []string
+	for _, rawURL := range rawURLs {
+		if rawURL == "" {
+			continue
+		}
+
+		found, err := c.reputation.Check(ctx, rawURL)
+		if err != nil {
+			return nil, err
+		}
+		for _, threat := range found {
+			if !seen[threat] {
+				seen[threat] = true
+				threats = append(threats, threat)
+			}
+		}
+	}
+	sort.Strings(threats)
+
+	return threats, nil
+}
+
+// creation returns the first revision of a newly created link.
+func creation(lnk Link) Revision {
+	return Revision{
+		Code:        lnk.Code,
+		Host:        lnk.Host,
+		Number:      1,
+		Author:      lnk.Owner,
+		Changes:     []string{"created"},
+		Settings:    settingsOf(lnk),
+		DateCreated: lnk.DateCreated,
+	}
+}
+
+// document returns the searchable text of the given link, scoped to its workspace.
+func document(lnk Link) search.Document {
+	return search.Document{
+		ID:    lnk.Key(),
+		Scope: lnk.Workspace,
+		Fields: map[string]string{
+			"code":        lnk.Code,
+			"title":       lnk.Title,
+			"notes":       lnk.Notes,
+			"destination": lnk.Destination,
+		},
+	}
+}
+
+// changesWindow reports whether any of the changes touch the activation window or click budget.
+func changesWindow(changes []string) bool {
+	for _, change := range changes {
+		switch change {
+		case "activates_at", "expires_at", "max_clicks":
+			return true
+		}
+	}
+
+	return false
+}
+
+// changesURL reports whether any of the changes touch the destination or the fallback URL.
+func changesURL(changes []string) bool {
+	for _, change := range changes {
+		switch change {
+		case "destination", "fallback_url":
+			return true
+		}
+	}
+
+	return false
+}
+
+// nonZero returns a pointer to the given time, or nil if it is the zero time.
+func nonZero(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,483 @@
+package link
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// The bounds on the length of a custom alias.
+const (
+	aliasMinLength = 3
+	aliasMaxLength = 64
+)
+
+// The bounds on the title, notes and tags of a link.
+const (
+	titleMaxLength = 200
+	notesMaxLength = 2000
+	tagMaxLength   = 32
+	maxTags        = 20
+)
+
+// queryMaxLength bounds the length of a search query.
+const queryMaxLength = 200
+
+// The bounds on the length of a link password. Passwords are hashed with bcrypt, which only looks
+// at the first 72 bytes.
+const (
+	passwordMinLength = 6
+	passwordMaxLength = 72
+)
+
+// Link represents a short link and the destination it points to. A link can only be followed
+// within its activation window and while its click budget lasts. Outside of that, visitors are
+// sent to the fallback URL if there is one. A link with a password asks visitors for it before
+// letting them through; only the hash of the password is ever kept. A deleted link sits in the
+// trash, where it can be restored until it is purged for good. A link whose destination or fallback
+// URL turns up on a threat list is flagged, which stops it from being followed at all. Links on a
+// branded domain carry its host, and their codes only need to be unique on that host. Every link
+// belongs to a workspace, and its owner is the member of the workspace who created it.
+type Link struct {
+	Code           string     `json:"code"`
+	Host           string     `json:"host,omitempty"`
+	Destination    string     `json:"destination"`
+	Workspace      string     `json:"workspace"`
+	Owner          string     `json:"owner"`
+	Title          string     `json:"title,omitempty"`
+	Notes          string     `json:"notes,omitempty"`
+	Tags           []string   `json:"tags,omitempty"`
+	FolderID       string     `json:"folder_id,omitempty"`
+	RedirectStatus int        `json:"redirect_status,omitempty"`
+	ActivatesAt    *time.Time `json:"activates_at,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	MaxClicks      int        `json:"max_clicks,omitempty"`
+	Clicks         int        `json:"clicks"`
+	FallbackURL    string     `json:"fallback_url,omitempty"`
+	Protected      bool       `json:"protected"`
+	PasswordHash   []byte     `json:"-"`
+	DateCreated    time.Time  `json:"date_created"`
+	DateUpdated    time.Time  `json:"date_updated"`
+	DateArchived   *time.Time `json:"date_archived,omitempty"`
+	DateDeleted    *time.Time `json:"date_deleted,omitempty"`
+	Threats        []string   `json:"threats,omitempty"`
+	DateFlagged    *time.Time `json:"date_flagged,omitempty"`
+}
+
+// Key returns the string which identifies the link across every host.
+func (l Link) Key() string {
+	return Key(l.Host, l.Code)
+}
+
+// Deleted reports whether the link is in the trash.
+func (l Link) Deleted() bool {
+	return l.DateDeleted != nil
+}
+
+// Flagged reports whether the link has been found to point at a threat.
+func (l Link) Flagged() bool {
+	return l.DateFlagged != nil
+}
+
+// Active reports whether the link can be followed at the given time.
+func (l Link) Active(now time.Time) bool {
+	switch {
+	case l.DateArchived != nil:
+		return false
+	case l.ActivatesAt != nil && now.Before(*l.ActivatesAt):
+		return false
+	case l.Expired(now):
+		return false
+	}
+
+	return true
+}
+
+// Expired reports whether the link has run past its expiration time or used up its click budget
+// at the given time. Unlike a link which is not active yet, an expired link stays expired.
+func (l Link) Expired(now time.Time) bool {
+	switch {
+	case l.ExpiresAt != nil && !now.Before(*l.ExpiresAt):
+		return true
+	case l.MaxClicks > 0 && l.Clicks >= l.MaxClicks:
+		return true
+	}
+
+	return false
+}
+
+// NewLink contains the information needed to create a new short link. A blank host creates the
+// link in the default namespace rather than on a branded domain.
+type NewLink struct {
+	Destination    string     `json:"destination"`
+	Host           string     `json:"host"`
+	Alias          string     `json:"alias"`
+	Title          string     `json:"title"`
+	Notes          string     `json:"notes"`
+	Tags           []string   `json:"tags"`
+	FolderID       string     `json:"folder_id"`
+	RedirectStatus int        `json:"redirect_status"`
+	ActivatesAt    *time.Time `json:"activates_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	MaxClicks      int        `json:"max_clicks"`
+	FallbackURL    string     `json:"fallback_url"`
+	Password       string     `json:"password"`
+}
+
+// Validate checks that the information for a new short link is valid.
+func (nl NewLink) Validate() error {
+	var fields validate.FieldErrors
+
+	if err := validate.URL(nl.Destination); err != nil {
+		fields.Add("destination", err.Error())
+	}
+
+	if nl.Alias != "" {
+		if err := validate.Slug(nl.Alias, aliasMinLength, aliasMaxLength); err != nil {
+			fields.Add("alias", err.Error())
+		}
+	}
+
+	if len(nl.Title) > titleMaxLength {
+		fields.Add("title", fmt.Sprintf("must be at most %d characters long", titleMaxLength))
+	}
+
+	if len(nl.Notes) > notesMaxLength {
+		fields.Add("notes", fmt.Sprintf("must be at most %d characters long", notesMaxLength))
+	}
+
+	if err := validateTags(nl.Tags); err != nil {
+		fields.Add("tags", err.Error())
+	}
+
+	if nl.RedirectStatus != 0 && !IsRedirectStatus(nl.RedirectStatus) {
+		fields.Add("redirect_status", "must be one of 301, 302, 307 or 308")
+	}
+
+	if nl.ActivatesAt != nil && nl.ExpiresAt != nil && !nl.ExpiresAt.After(*nl.ActivatesAt) {
+		fields.Add("expires_at", "must be after activates_at")
+	}
+
+	if nl.MaxClicks < 0 {
+		fields.Add("max_clicks", "must not be negative")
+	}
+
+	if nl.FallbackURL != "" {
+		if err := validate.URL(nl.FallbackURL); err != nil {
+			fields.Add("fallback_url", err.Error())
+		}
+	}
+
+	if nl.Password != "" {
+		if err := validatePassword(nl.Password); err != nil {
+			fields.Add("password", err.Error())
+		}
+	}
+
+	return fields.Err()
+}
+
+// UpdateLink defines what information may be provided to modify an existing short link. All fields
+// are optional so clients can send just the fields they want changed. It uses pointer fields so we
+// can differentiate between a field that was not provided and a field that was provided as
+// explicitly blank. A zero time clears the activation or expiration time and a blank fallback URL
+// clears the fallback. A blank password removes the password from the link and a blank folder moves
+// it out of its folder.
+type UpdateLink struct {
+	Destination    *string    `json:"destination"`
+	Title          *string    `json:"title"`
+	Notes          *string    `json:"notes"`
+	Tags           *[]string  `json:"tags"`
+	FolderID       *string    `json:"folder_id"`
+	RedirectStatus *int       `json:"redirect_status"`
+	ActivatesAt    *time.Time `json:"activates_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	MaxClicks      *int       `json:"max_clicks"`
+	FallbackURL    *string    `json:"fallback_url"`
+	Password       *string    `json:"password"`
+}
+
+// Validate checks that the information for updating a short link is valid.
+func (ul UpdateLink) Validate() error {
+	var fields validate.FieldErrors
+
+	if ul.Destination != nil {
+		if err := validate.URL(*ul.Destination); err != nil {
+			fields.Add("destination", err.Error())
+		}
+	}
+
+	if ul.Title != nil && len(*ul.Title) > titleMaxLength {
+		fields.Add("title", fmt.Sprintf("must be at most %d characters long", titleMaxLength))
+	}
+
+	if ul.Notes != nil && len(*ul.Notes) > notesMaxLength {
+		fields.Add("notes", fmt.Sprintf("must be at most %d characters long", notesMaxLength))
+	}
+
+	if ul.Tags != nil {
+		if err := validateTags(*ul.Tags); err != nil {
+			fields.Add("tags", err.Error())
+		}
+	}
+
+	if ul.RedirectStatus != nil && *ul.RedirectStatus != 0 && !IsRedirectStatus(*ul.RedirectStatus) {
+		fields.Add("redirect_status", "must be one of 301, 302, 307 or 308")
+	}
+
+	if ul.MaxClicks != nil && *ul.MaxClicks < 0 {
+		fields.Add("max_clicks", "must not be negative")
+	}
+
+	if ul.FallbackURL != nil && *ul.FallbackURL != "" {
+		if err := validate.URL(*ul.FallbackURL); err != nil {
+			fields.Add("fallback_url", err.Error())
+		}
+	}
+
+	if ul.Password != nil && *ul.Password != "" {
+		if err := validatePassword(*ul.Password); err != nil {
+			fields.Add("password", err.Error())
+		}
+	}
+
+	return fields.Err()
+}
+
+// validateTags checks that there are not too many tags and that every tag is a short slug.
+func validateTags(tags []string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("must have at most %d tags", maxTags)
+	}
+
+	for _, tag := range tags {
+		if err := validate.Slug(tag, 1, tagMaxLength); err != nil {
+			return fmt.Errorf("tag %q %s", tag, err)
+		}
+	}
+
+	return nil
+}
+
+// normalizeTags lowercases the tags, drops duplicates and sorts them, so that the same set of tags
+// is always stored the same way.
+func normalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(tag)
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+
+	return normalized
+}
+
+// TagCount represents a tag in use by an owner along with how many of their links carry it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Links int    `json:"links"`
+}
+
+// SearchResult represents a short link found by a search. Highlights hold the fields of the link
+// which matched, as HTML escaped text with the matching words wrapped in <mark> elements.
+type SearchResult struct {
+	Link       Link              `json:"link"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// RenameTag contains the new name for a tag.
+type RenameTag struct {
+	Name string `json:"name"`
+}
+
+// Validate checks that the new name for a tag is valid.
+func (rt RenameTag) Validate() error {
+	var fields validate.FieldErrors
+
+	if err := validate.Slug(rt.Name, 1, tagMaxLength); err != nil {
+		fields.Add("name", err.Error())
+	}
+
+	return fields.Err()
+}
+
+// MergeTags contains the tags to fold into a single one.
+type MergeTags struct {
+	Tags []string `json:"tags"`
+	Into string   `json:"into"`
+}
+
+// Validate checks that the tags to merge are valid.
+func (mt MergeTags) Validate() error {
+	var fields validate.FieldErrors
+
+	if len(mt.Tags) == 0 {
+		fields.Add("tags", "must not be empty")
+	}
+	for _, tag := range mt.Tags {
+		if err := validate.Slug(tag, 1, tagMaxLength); err != nil {
+			fields.Add("tags", fmt.Sprintf("tag %q %s", tag, err))
+			break
+		}
+	}
+
+	if err := validate.Slug(mt.Into, 1, tagMaxLength); err != nil {
+		fields.Add("into", err.Error())
+	}
+
+	return fields.Err()
+}
+
+// validatePassword checks that a link password is within the supported length.
+func validatePassword(password string) error {
+	if len(password) < passwordMinLength || len(password) > passwordMaxLength {
+		return fmt.Errorf("must be between %d and %d bytes long", passwordMinLength, passwordMaxLength)
+	}
+
+	return nil
+}
+
+// Key returns the string which identifies the short link with the given code on the given host.
+// Links in the default namespace are identified by their code alone, and links on a branded domain
+// by their host and code joined by a slash, which never appears in a code.
+func Key(host string, code string) string {
+	if host == "" {
+		return code
+	}
+
+	return host + "/" + code
+}
+
+// SplitKey splits a key back into the host and code of the link it identifies.
+func SplitKey(key string) (host string, code string) {
+	if i := strings.LastIndexByte(key, '/'); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+
+	return "", key
+}
+
+// IsRedirectStatus reports whether the given HTTP status code can be used to redirect a visitor
+// to the destination of a short link.
+func IsRedirectStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+
+	return false
+}
+
+// =================================================================================================
+
+// Revision represents an immutable record of a change made to a short link. It holds who made the
+// change and when, which fields it touched, and the settings of the link right after it. The first
+// revision of every link records its creation. Passwords are never kept in revisions, so only the
+// fact that the password changed is recorded.
+type Revision struct {
+	Code        string    `json:"code"`
+	Host        string    `json:"host,omitempty"`
+	Number      int       `json:"number"`
+	Author      string    `json:"author"`
+	Changes     []string  `json:"changes"`
+	RollbackOf  int       `json:"rollback_of,omitempty"`
+	Settings    Settings  `json:"settings"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// Settings represents the part of a short link that its owner can edit and roll back.
+type Settings struct {
+	Destination    string     `json:"destination"`
+	Title          string     `json:"title,omitempty"`
+	Notes          string     `json:"notes,omitempty"`
+	Tags           []string   `json:"tags,omitempty"`
+	RedirectStatus int        `json:"redirect_status,omitempty"`
+	ActivatesAt    *time.Time `json:"activates_at,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	MaxClicks      int        `json:"max_clicks,omitempty"`
+	FallbackURL    string     `json:"fallback_url,omitempty"`
+}
+
+// settingsOf returns the editable settings of the given link.
+func settingsOf(lnk Link) Settings {
+	return Settings{
+		Destination:    lnk.Destination,
+		Title:          lnk.Title,
+		Notes:          lnk.Notes,
+		Tags:           lnk.Tags,
+		RedirectStatus: lnk.RedirectStatus,
+		ActivatesAt:    lnk.ActivatesAt,
+		ExpiresAt:      lnk.ExpiresAt,
+		MaxClicks:      lnk.MaxClicks,
+		FallbackURL:    lnk.FallbackURL,
+	}
+}
+
+// apply overwrites the editable settings of the given link.
+func (s Settings) apply(lnk *Link) {
+	lnk.Destination = s.Destination
+	lnk.Title = s.Title
+	lnk.Notes = s.Notes
+	lnk.Tags = s.Tags
+	lnk.RedirectStatus = s.RedirectStatus
+	lnk.ActivatesAt = s.ActivatesAt
+	lnk.ExpiresAt = s.ExpiresAt
+	lnk.MaxClicks = s.MaxClicks
+	lnk.FallbackURL = s.FallbackURL
+}
+
+// diff returns the names of the settings which differ between the two.
+func (s Settings) diff(other Settings) []string {
+	changes := []string{}
+	if s.Destination != other.Destination {
+		changes = append(changes, "destination")
+	}
+	if s.Title != other.Title {
+		changes = append(changes, "title")
+	}
+	if s.Notes != other.Notes {
+		changes = append(changes, "notes")
+	}
+	if strings.Join(s.Tags, ",") != strings.Join(other.Tags, ",") {
+		changes = append(changes, "tags")
+	}
+	if s.RedirectStatus != other.RedirectStatus {
+		changes = append(changes, "redirect_status")
+	}
+	if !equalTime(s.ActivatesAt, other.ActivatesAt) {
+		changes = append(changes, "activates_at")
+	}
+	if !equalTime(s.ExpiresAt, other.ExpiresAt) {
+		changes = append(changes, "expires_at")
+	}
+	if s.MaxClicks != other.MaxClicks {
+		changes = append(changes, "max_clicks")
+	}
+	if s.FallbackURL != other.FallbackURL {
+		changes = append(changes, "fallback_url")
+	}
+
+	return changes
+}
+
+// equalTime reports whether two optional times are the same.
+func equalTime(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return a.Equal(*b)
+}
@@ -0,0 +1,635 @@
+// Package linkdb contains the database/sql implementation of the link storer.
+package linkdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/yashshah7197/shrt/business/core/link"
+	"github.com/yashshah7197/shrt/business/sys/database"
+)
+
+// hostExpr pulls the lowercased host out of the destination of a link.
+const hostExpr = `lower(substring(destination from '^[^:]+://(?:[^@/?#]*@)?([^:/?#]+)'))`
+
+// columns lists the link columns in the order scanLink reads them.
+const columns = `
+		code, destination, owner, redirect_status, activates_at, expires_at, max_clicks, clicks,
+		fallback_url, date_created, date_updated, date_archived, password_hash, title, tags,
+		date_deleted, folder_id, notes, threats, date_flagged, host, workspace`
+
+// Store manages the set of APIs for short link access in the database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore constructs a store for short links backed by the given database.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create inserts a new short link into the database along with its first revision.
+func (s *Store) Create(ctx context.Context, lnk link.Link, rev link.Revision) error {
+	const q = `
+	INSERT INTO links (` + columns + `)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, q,
+		lnk.Code,
+		lnk.Destination,
+		lnk.Owner,
+		lnk.RedirectStatus,
+		nullTime(lnk.ActivatesAt),
+		nullTime(lnk.ExpiresAt),
+		lnk.MaxClicks,
+		lnk.Clicks,
+		lnk.FallbackURL,
+		lnk.DateCreated.UTC(),
+		lnk.DateUpdated.UTC(),
+		nullTime(lnk.DateArchived),
+		lnk.PasswordHash,
+		lnk.Title,
+		pq.Array(textArray(lnk.Tags)),
+		nullTime(lnk.DateDeleted),
+		lnk.FolderID,
+		lnk.Notes,
+		pq.Array(textArray(lnk.Threats)),
+		nullTime(lnk.DateFlagged),
+		lnk.Host,
+		lnk.Workspace,
+	); err != nil {
+		if database.IsDuplicatedEntry(err) {
+			return link.ErrCodeTaken
+		}
+		return fmt.Errorf("inserting link: %w", err)
+	}
+
+	if err := insertRevision(ctx, tx, rev); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Revise replaces a short link in the database and appends the revision which describes the
+// change, numbering it after the latest one. The click count is left untouched since it is only
+// ever changed by IncrementClicks.
+func (s *Store) Revise(ctx context.Context, lnk link.Link, rev link.Revision) error {
+	const q = `
+	SELECT
+		code
+	FROM
+		links
+	WHERE
+		host = $1 AND code = $2 AND workspace = $3
+	FOR UPDATE`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the link so that concurrent revisions are numbered one after the other.
+	var code string
+	if err := tx.QueryRowContext(ctx, q, lnk.Host, lnk.Code, lnk.Workspace).Scan(&code); err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return link.ErrNotFound
+		}
+		return fmt.Errorf("locking link[%s]: %w", lnk.Key(), err)
+	}
+
+	if err := update(ctx, tx, lnk); err != nil {
+		return err
+	}
+
+	if err := insertRevision(ctx, tx, rev); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// IncrementClicks adds one to the click count of the short link identified by the given host and
+// code and returns the updated link.
+func (s *Store) IncrementClicks(ctx context.Context, host string, code string) (link.Link, error) {
+	const q = `
+	UPDATE
+		links
+	SET
+		clicks = clicks + 1
+	WHERE
+		host = $1 AND code = $2
+	RETURNING` + columns
+
+	lnk, err := scanLink(s.db.QueryRowContext(ctx, q, host, code))
+	if err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return link.Link{}, link.ErrNotFound
+		}
+		return link.Link{}, fmt.Errorf("incrementing clicks for link[%s]: %w", link.Key(host, code), err)
+	}
+
+	return lnk, nil
+}
+
+// Archive archives the short link identified by the given host and code, provided it is expired
+// at the given time and is neither archived nor in the trash already.
+func (s *Store) Archive(ctx context.Context, host string, code string, now time.Time) error {
+	const q = `
+	UPDATE
+		links
+	SET
+		date_archived = $3
+	WHERE
+		host = $1 AND code = $2 AND date_archived IS NULL AND date_deleted IS NULL AND
+		(expires_at <= $3 OR (max_clicks > 0 AND clicks >= max_clicks))`
+
+	res, err := s.db.ExecContext(ctx, q, host, code, now.UTC())
+	if err != nil {
+		return fmt.Errorf("archiving link[%s]: %w", link.Key(host, code), err)
+	}
+
+	return checkAffected(res)
+}
+
+// UpdateThreats writes the threats of a short link and when it was flagged, provided its URLs have
+// not changed since.
+func (s *Store) UpdateThreats(ctx context.Context, lnk link.Link) error {
+	const q = `
+	UPDATE
+		links
+	SET
+		threats = $3,
+		date_flagged = $4
+	WHERE
+		host = $1 AND code = $2 AND destination = $5 AND fallback_url = $6`
+
+	res, err := s.db.ExecContext(ctx, q,
+		lnk.Host,
+		lnk.Code,
+		pq.Array(textArray(lnk.Threats)),
+		nullTime(lnk.DateFlagged),
+		lnk.Destination,
+		lnk.FallbackURL,
+	)
+	if err != nil {
+		return fmt.Errorf("updating threats of link[%s]: %w", lnk.Key(), err)
+	}
+
+	return checkAffected(res)
+}
+
+// Delete removes the short link identified by the given host and code in the specified workspace
+// from the database.
+func (s *Store) Delete(ctx context.Context, workspace string, host string, code string) error {
+	const q = `
+	DELETE FROM
+		links
+	WHERE
+		host = $1 AND code = $2 AND workspace = $3`
+
+	res, err := s.db.ExecContext(ctx, q, host, code, workspace)
+	if err != nil {
+		return fmt.Errorf("deleting link[%s]: %w", link.Key(host, code), err)
+	}
+
+	return checkAffected(res)
+}
+
+// QueryByCode gets the short link identified by the given host and code in the specified
+// workspace from the database. A blank workspace matches every workspace.
+func (s *Store) QueryByCode(ctx context.Context, workspace string, host string, code string) (link.Link, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		links
+	WHERE
+		host = $1 AND code = $2 AND ($3 = '' OR workspace = $3)`
+
+	lnk, err := scanLink(s.db.QueryRowContext(ctx, q, host, code, workspace))
+	if err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return link.Link{}, link.ErrNotFound
+		}
+		return link.Link{}, fmt.Errorf("selecting link[%s]: %w", link.Key(host, code), err)
+	}
+
+	return lnk, nil
+}
+
+// Query gets the short links which pass the filter and come after the cursor, newest first. Every
+// matching link is returned when the limit is zero.
+func (s *Store) Query(ctx context.Context, filter link.QueryFilter, after *link.Cursor, limit int) ([]link.Link, error) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.Workspace != "" {
+		where = append(where, "workspace = "+arg(filter.Workspace))
+	}
+
+	if filter.Owner != "" {
+		where = append(where, "owner = "+arg(filter.Owner))
+	}
+
+	if filter.Deleted != nil {
+		if *filter.Deleted {
+			where = append(where, "date_deleted IS NOT NULL")
+		} else {
+			where = append(where, "date_deleted IS NULL")
+		}
+	}
+
+	if len(filter.Tags) > 0 {
+		where = append(where, "tags @> "+arg(pq.Array(filter.Tags)))
+	}
+
+	if len(filter.FolderIDs) > 0 {
+		where = append(where, "folder_id = ANY("+arg(pq.Array(filter.FolderIDs))+")")
+	}
+
+	if filter.Host != "" {
+		where = append(where, "host = "+arg(filter.Host))
+	}
+
+	if filter.CreatedFrom != nil {
+		where = append(where, "date_created >= "+arg(filter.CreatedFrom.UTC()))
+	}
+
+	if filter.CreatedTo != nil {
+		where = append(where, "date_created < "+arg(filter.CreatedTo.UTC()))
+	}
+
+	if filter.Domain != "" {
+		domain := arg(filter.Domain)
+		where = append(where, fmt.Sprintf("(%[1]s = %[2]s OR right(%[1]s, length(%[2]s) + 1) = '.' || %[2]s)", hostExpr, domain))
+	}
+
+	switch filter.State {
+	case link.StateActive:
+		now := arg(filter.Now.UTC())
+		where = append(where, fmt.Sprintf("date_archived IS NULL AND (activates_at IS NULL OR activates_at <= %[1]s) AND %[2]s", now, notExpired(now)))
+	case link.StateScheduled:
+		now := arg(filter.Now.UTC())
+		where = append(where, fmt.Sprintf("date_archived IS NULL AND activates_at > %[1]s AND %[2]s", now, notExpired(now)))
+	case link.StateExpired:
+		now := arg(filter.Now.UTC())
+		where = append(where, fmt.Sprintf("NOT (date_archived IS NULL AND %s)", notExpired(now)))
+	}
+
+	if after != nil {
+		created, code, host := arg(after.DateCreated.UTC()), arg(after.Code), arg(after.Host)
+		where = append(where, fmt.Sprintf("(date_created < %[1]s OR (date_created = %[1]s AND (code, host) > (%[2]s, %[3]s)))", created, code, host))
+	}
+
+	q := `
+	SELECT` + columns + `
+	FROM
+		links`
+	if len(where) > 0 {
+		q += `
+	WHERE
+		` + strings.Join(where, " AND\n\t\t")
+	}
+	q += `
+	ORDER BY
+		date_created DESC, code, host`
+	if limit > 0 {
+		q += `
+	LIMIT ` + arg(limit)
+	}
+
+	return s.query(ctx, q, args...)
+}
+
+// QueryRevisions gets every revision of the short link identified by the given host and code in
+// the specified workspace, newest first.
+func (s *Store) QueryRevisions(ctx context.Context, workspace string, host string, code string) ([]link.Revision, error) {
+	const q = `
+	SELECT
+		r.code, r.host, r.number, r.author, r.changes, r.rollback_of, r.settings, r.date_created
+	FROM
+		link_revisions r
+	JOIN
+		links l ON l.host = r.host AND l.code = r.code
+	WHERE
+		r.host = $1 AND r.code = $2 AND l.workspace = $3
+	ORDER BY
+		r.number DESC`
+
+	rows, err := s.db.QueryContext(ctx, q, host, code, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("selecting revisions of link[%s]: %w", link.Key(host, code), err)
+	}
+	defer rows.Close()
+
+	revs := []link.Revision{}
+	for rows.Next() {
+		var rev link.Revision
+		var settings []byte
+		if err := rows.Scan(
+			&rev.Code,
+			&rev.Host,
+			&rev.Number,
+			&rev.Author,
+			pq.Array(&rev.Changes),
+			&rev.RollbackOf,
+			&settings,
+			&rev.DateCreated,
+		); err != nil {
+			return nil, fmt.Errorf("scanning revision: %w", err)
+		}
+		if err := json.Unmarshal(settings, &rev.Settings); err != nil {
+			return nil, fmt.Errorf("decoding revision settings: %w", err)
+		}
+		revs = append(revs, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating revisions: %w", err)
+	}
+
+	return revs, nil
+}
+
+// QueryExpired gets all the short links which have expired at the given time but are not archived
+// yet.
+func (s *Store) QueryExpired(ctx context.Context, now time.Time) ([]link.Link, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		links
+	WHERE
+		date_archived IS NULL AND date_deleted IS NULL AND
+		(expires_at <= $1 OR (max_clicks > 0 AND clicks >= max_clicks))`
+
+	return s.query(ctx, q, now.UTC())
+}
+
+// QueryDeleted gets all the short links which were moved to the trash at or before the given time.
+func (s *Store) QueryDeleted(ctx context.Context, before time.Time) ([]link.Link, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		links
+	WHERE
+		date_deleted <= $1`
+
+	return s.query(ctx, q, before.UTC())
+}
+
+// NextSequence returns the next value of the sequence used to generate codes.
+func (s *Store) NextSequence(ctx context.Context) (uint64, error) {
+	const q = `SELECT nextval('link_code_seq')`
+
+	var n uint64
+	if err := s.db.QueryRowContext(ctx, q).Scan(&n); err != nil {
+		return 0, fmt.Errorf("selecting next sequence value: %w", err)
+	}
+
+	return n, nil
+}
+
+// notExpired returns the condition under which a link has neither run past its expiration time nor
+// used up its click budget at the time held by the given parameter.
+func notExpired(now string) string {
+	return fmt.Sprintf("(expires_at IS NULL OR expires_at > %s) AND (max_clicks = 0 OR clicks < max_clicks)", now)
+}
+
+// execer is implemented by both sql.DB and sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// update writes every column of a link except the click count, provided it is still in the
+// workspace of the link.
+func update(ctx context.Context, db execer, lnk link.Link) error {
+	const q = `
+	UPDATE
+		links
+	SET
+		destination = $2,
+		redirect_status = $3,
+		activates_at = $4,
+		expires_at = $5,
+		max_clicks = $6,
+		fallback_url = $7,
+		date_updated = $8,
+		date_archived = $9,
+		password_hash = $10,
+		title = $11,
+		tags = $12,
+		date_deleted = $13,
+		folder_id = $14,
+		notes = $15,
+		threats = $16,
+		date_flagged = $17
+	WHERE
+		host = $18 AND code = $1 AND workspace = $19`
+
+	res, err := db.ExecContext(ctx, q,
+		lnk.Code,
+		lnk.Destination,
+		lnk.RedirectStatus,
+		nullTime(lnk.ActivatesAt),
+		nullTime(lnk.ExpiresAt),
+		lnk.MaxClicks,
+		lnk.FallbackURL,
+		lnk.DateUpdated.UTC(),
+		nullTime(lnk.DateArchived),
+		lnk.PasswordHash,
+		lnk.Title,
+		pq.Array(textArray(lnk.Tags)),
+		nullTime(lnk.DateDeleted),
+		lnk.FolderID,
+		lnk.Notes,
+		pq.Array(textArray(lnk.Threats)),
+		nullTime(lnk.DateFlagged),
+		lnk.Host,
+		lnk.Workspace,
+	)
+	if err != nil {
+		return fmt.Errorf("updating link[%s]: %w", lnk.Key(), err)
+	}
+
+	return checkAffected(res)
+}
+
+// insertRevision appends a revision to the history of its link, numbering it after the latest one.
+// The caller must hold a lock on the link for the numbering to be safe.
+func insertRevision(ctx context.Context, tx *sql.Tx, rev link.Revision) error {
+	const q = `
+	INSERT INTO link_revisions
+		(code, host, number, author, changes, rollback_of, settings, date_created)
+	SELECT
+		$1, $2, COALESCE(MAX(number), 0) + 1, $3, $4, $5, $6, $7
+	FROM
+		link_revisions
+	WHERE
+		host = $2 AND code = $1`
+
+	settings, err := json.Marshal(rev.Settings)
+	if err != nil {
+		return fmt.Errorf("encoding revision settings: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, q,
+		rev.Code,
+		rev.Host,
+		rev.Author,
+		pq.Array(rev.Changes),
+		rev.RollbackOf,
+		settings,
+		rev.DateCreated.UTC(),
+	); err != nil {
+		return fmt.Errorf("inserting revision of link[%s]: %w", link.Key(rev.Host, rev.Code), err)
+	}
+
+	return nil
+}
+
+// query runs a query which selects the link columns and returns every link found.
+func (s *Store) query(ctx context.Context, q string, args ...interface{}) ([]link.Link, error) {
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("selecting links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []link.Link{}
+	for rows.Next() {
+		lnk, err := scanLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning link: %w", err)
+		}
+		links = append(links, lnk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating links: %w", err)
+	}
+
+	return links, nil
+}
+
+// scanner is implemented by both sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanLink reads a single link out of a row.
+func scanLink(row scanner) (link.Link, error) {
+	var lnk link.Link
+	var activatesAt, expiresAt, dateArchived, dateDeleted, dateFlagged sql.NullTime
+	if err := row.Scan(
+		&lnk.Code,
+		&lnk.Destination,
+		&lnk.Owner,
+		&lnk.RedirectStatus,
+		&activatesAt,
+		&expiresAt,
+		&lnk.MaxClicks,
+		&lnk.Clicks,
+		&lnk.FallbackURL,
+		&lnk.DateCreated,
+		&lnk.DateUpdated,
+		&dateArchived,
+		&lnk.PasswordHash,
+		&lnk.Title,
+		pq.Array(&lnk.Tags),
+		&dateDeleted,
+		&lnk.FolderID,
+		&lnk.Notes,
+		pq.Array(&lnk.Threats),
+		&dateFlagged,
+		&lnk.Host,
+		&lnk.Workspace,
+	); err != nil {
+		return link.Link{}, err
+	}
+	if len(lnk.Tags) == 0 {
+		lnk.Tags = nil
+	}
+	if len(lnk.Threats) == 0 {
+		lnk.Threats = nil
+	}
+	lnk.ActivatesAt = timePtr(activatesAt)
+	lnk.ExpiresAt = timePtr(expiresAt)
+	lnk.DateArchived = timePtr(dateArchived)
+	lnk.DateDeleted = timePtr(dateDeleted)
+	lnk.DateFlagged = timePtr(dateFlagged)
+	lnk.Protected = len(lnk.PasswordHash) > 0
+
+	return lnk, nil
+}
+
+// checkAffected translates a statement that touched no rows into link.ErrNotFound.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return link.ErrNotFound
+	}
+
+	return nil
+}
+
+// nullTime converts an optional time into its database form.
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// textArray converts a list held by a link, such as its tags, into its database form, which is
+// never null.
+func textArray(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+
+	return list
+}
+
+// timePtr converts a nullable time from the database into an optional time.
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
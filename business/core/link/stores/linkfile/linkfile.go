@@ -0,0 +1,388 @@
+// Package linkfile contains a durable, single-file implementation of the link storer. Every change
+// is appended to a journal on disk and the full set of links is kept in memory for reads. The
+// journal is compacted once it holds mostly superseded records.
+package linkfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/link"
+	"github.com/yashshah7197/shrt/business/core/link/stores/linkmem"
+	"github.com/yashshah7197/shrt/business/sys/workspace"
+	"github.com/yashshah7197/shrt/foundation/journal"
+)
+
+// The set of operations recorded in the journal.
+const (
+	opPut      = "put"
+	opRevision = "rev"
+	opDelete   = "delete"
+	opSequence = "seq"
+)
+
+// compactThreshold is the minimum number of journal records before compaction is considered.
+const compactThreshold = 1000
+
+// record is the form in which a link is written to the journal. The password hash is left out of
+// the JSON form of a link so that it never reaches API clients, so it is carried alongside it here.
+type record struct {
+	link.Link
+	PasswordHash []byte `json:"password_hash,omitempty"`
+}
+
+// newRecord constructs the journal record for the given link.
+func newRecord(lnk link.Link) record {
+	return record{
+		Link:         lnk,
+		PasswordHash: lnk.PasswordHash,
+	}
+}
+
+// Store manages the set of APIs for short link access backed by a journal file.
+type Store struct {
+	mu      sync.Mutex
+	mem     *linkmem.Store
+	journal *journal.Journal
+	seq     uint64
+}
+
+// Open constructs a store for short links by replaying the journal at the given path.
+func Open(path string) (*Store, error) {
+	ctx := context.Background()
+	mem := linkmem.NewStore()
+
+	var seq uint64
+
+	// Rebuild the in-memory state from the journal records.
+	replay := func(rec journal.Record) error {
+		switch rec.Op {
+		case opPut:
+			var r record
+			if err := json.Unmarshal(rec.Data, &r); err != nil {
+				return err
+			}
+			lnk := r.Link
+			lnk.PasswordHash = r.PasswordHash
+
+			// Links recorded before workspaces existed belong to the default one.
+			if lnk.Workspace == "" {
+				lnk.Workspace = workspace.Default
+			}
+
+			// Records hold the full state of a link, so replace whatever was there before.
+			mem.Put(lnk)
+			return nil
+
+		case opRevision:
+			var rev link.Revision
+			if err := json.Unmarshal(rec.Data, &rev); err != nil {
+				return err
+			}
+			mem.PutRevision(rev)
+			return nil
+
+		case opDelete:
+			host, code := link.SplitKey(rec.Key)
+			lnk, err := mem.QueryByCode(ctx, "", host, code)
+			if err != nil {
+				return err
+			}
+			return mem.Delete(ctx, lnk.Workspace, host, code)
+
+		case opSequence:
+			n, err := strconv.ParseUint(rec.Key, 10, 64)
+			if err != nil {
+				return err
+			}
+			seq = n
+			return nil
+
+		default:
+			return fmt.Errorf("unknown operation %q", rec.Op)
+		}
+	}
+
+	jrnl, err := journal.Open(path, replay)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	s := Store{
+		mem:     mem,
+		journal: jrnl,
+		seq:     seq,
+	}
+
+	// Start off with a compact journal.
+	if err := s.compact(); err != nil {
+		jrnl.Close()
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Close closes the underlying journal file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.journal.Close()
+}
+
+// Create inserts a new short link into the store along with its first revision.
+func (s *Store) Create(ctx context.Context, lnk link.Link, rev link.Revision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByCode(ctx, "", lnk.Host, lnk.Code); err == nil {
+		return link.ErrCodeTaken
+	}
+
+	if err := s.appendRevised(lnk, rev); err != nil {
+		return err
+	}
+
+	if err := s.mem.Create(ctx, lnk, rev); err != nil {
+		return err
+	}
+	s.maybeCompact()
+
+	return nil
+}
+
+// Revise replaces a short link in the store and appends the revision which describes the change,
+// numbering it after the latest one. The click count is left untouched since it is only ever
+// changed by IncrementClicks.
+func (s *Store) Revise(ctx context.Context, lnk link.Link, rev link.Revision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.mem.QueryByCode(ctx, lnk.Workspace, lnk.Host, lnk.Code)
+	if err != nil {
+		return err
+	}
+	lnk.Clicks = existing.Clicks
+	rev.Number = s.mem.NextRevision(lnk.Host, lnk.Code)
+
+	if err := s.appendRevised(lnk, rev); err != nil {
+		return err
+	}
+
+	s.mem.Put(lnk)
+	s.mem.PutRevision(rev)
+	s.maybeCompact()
+
+	return nil
+}
+
+// IncrementClicks adds one to the click count of the short link identified by the given host and
+// code and returns the updated link.
+func (s *Store) IncrementClicks(ctx context.Context, host string, code string) (link.Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lnk, err := s.mem.QueryByCode(ctx, "", host, code)
+	if err != nil {
+		return link.Link{}, err
+	}
+	lnk.Clicks++
+
+	if err := s.journal.Append(opPut, lnk.Key(), newRecord(lnk)); err != nil {
+		return link.Link{}, fmt.Errorf("appending to journal: %w", err)
+	}
+
+	lnk, err = s.mem.IncrementClicks(ctx, host, code)
+	if err != nil {
+		return link.Link{}, err
+	}
+	s.maybeCompact()
+
+	return lnk, nil
+}
+
+// Archive archives the short link identified by the given host and code, provided it is expired
+// at the given time and is neither archived nor in the trash already.
+func (s *Store) Archive(ctx context.Context, host string, code string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lnk, err := s.mem.QueryByCode(ctx, "", host, code)
+	if err != nil {
+		return err
+	}
+	if lnk.DateArchived != nil || lnk.Deleted() || !lnk.Expired(now) {
+		return link.ErrNotFound
+	}
+	lnk.DateArchived = &now
+
+	if err := s.journal.Append(opPut, lnk.Key(), newRecord(lnk)); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	if err := s.mem.Archive(ctx, host, code, now); err != nil {
+		return err
+	}
+	s.maybeCompact()
+
+	return nil
+}
+
+// UpdateThreats writes the threats of a short link and when it was flagged, provided its URLs have
+// not changed since.
+func (s *Store) UpdateThreats(ctx context.Context, lnk link.Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.mem.QueryByCode(ctx, "", lnk.Host, lnk.Code)
+	if err != nil {
+		return err
+	}
+	if existing.Destination != lnk.Destination || existing.FallbackURL != lnk.FallbackURL {
+		return link.ErrNotFound
+	}
+	existing.Threats = lnk.Threats
+	existing.DateFlagged = lnk.DateFlagged
+
+	if err := s.journal.Append(opPut, existing.Key(), newRecord(existing)); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	if err := s.mem.UpdateThreats(ctx, lnk); err != nil {
+		return err
+	}
+	s.maybeCompact()
+
+	return nil
+}
+
+// Delete removes the short link identified by the given host and code in the specified workspace
+// from the store.
+func (s *Store) Delete(ctx context.Context, workspace string, host string, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByCode(ctx, workspace, host, code); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opDelete, link.Key(host, code), nil); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	if err := s.mem.Delete(ctx, workspace, host, code); err != nil {
+		return err
+	}
+	s.maybeCompact()
+
+	return nil
+}
+
+// QueryByCode gets the short link identified by the given host and code in the specified
+// workspace from the store. A blank workspace matches every workspace.
+func (s *Store) QueryByCode(ctx context.Context, workspace string, host string, code string) (link.Link, error) {
+	return s.mem.QueryByCode(ctx, workspace, host, code)
+}
+
+// Query gets the short links which pass the filter and come after the cursor, newest first. Every
+// matching link is returned when the limit is zero.
+func (s *Store) Query(ctx context.Context, filter link.QueryFilter, after *link.Cursor, limit int) ([]link.Link, error) {
+	return s.mem.Query(ctx, filter, after, limit)
+}
+
+// QueryRevisions gets every revision of the short link identified by the given host and code in
+// the specified workspace, newest first.
+func (s *Store) QueryRevisions(ctx context.Context, workspace string, host string, code string) ([]link.Revision, error) {
+	return s.mem.QueryRevisions(ctx, workspace, host, code)
+}
+
+// QueryExpired gets all the short links which have expired at the given time but are not archived
+// yet.
+func (s *Store) QueryExpired(ctx context.Context, now time.Time) ([]link.Link, error) {
+	return s.mem.QueryExpired(ctx, now)
+}
+
+// QueryDeleted gets all the short links which were moved to the trash at or before the given time.
+func (s *Store) QueryDeleted(ctx context.Context, before time.Time) ([]link.Link, error) {
+	return s.mem.QueryDeleted(ctx, before)
+}
+
+// NextSequence returns the next value of the sequence used to generate codes. Every value handed
+// out is recorded in the journal so the sequence never goes backwards across restarts.
+func (s *Store) NextSequence(ctx context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.seq + 1
+	if err := s.journal.Append(opSequence, strconv.FormatUint(next, 10), nil); err != nil {
+		return 0, fmt.Errorf("appending to journal: %w", err)
+	}
+	s.seq = next
+	s.maybeCompact()
+
+	return next, nil
+}
+
+// appendRevised writes a link together with its revision to the journal, so that neither is ever
+// found without the other. The caller must hold the store lock.
+func (s *Store) appendRevised(lnk link.Link, rev link.Revision) error {
+	batch := func(emit func(op string, key string, data interface{}) error) error {
+		if err := emit(opPut, lnk.Key(), newRecord(lnk)); err != nil {
+			return err
+		}
+		return emit(opRevision, lnk.Key(), rev)
+	}
+
+	if err := s.journal.AppendBatch(batch); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return nil
+}
+
+// maybeCompact compacts the journal once it holds more than twice as many records as there are
+// live links and revisions. A failed compaction leaves the current journal intact, so the error is
+// dropped and compaction is simply attempted again on the next write. The caller must hold the
+// store lock.
+func (s *Store) maybeCompact() {
+	records := s.journal.Records()
+	if records < compactThreshold || records < 2*(s.mem.Len()+s.mem.LenRevisions()+1) {
+		return
+	}
+
+	s.compact()
+}
+
+// compact rewrites the journal so that it holds exactly one record per live link and revision, plus
+// the current value of the sequence.
+func (s *Store) compact() error {
+	snapshot := func(emit func(op string, key string, data interface{}) error) error {
+		if s.seq > 0 {
+			if err := emit(opSequence, strconv.FormatUint(s.seq, 10), nil); err != nil {
+				return err
+			}
+		}
+		for _, lnk := range s.mem.All() {
+			if err := emit(opPut, lnk.Key(), newRecord(lnk)); err != nil {
+				return err
+			}
+		}
+		for _, rev := range s.mem.AllRevisions() {
+			if err := emit(opRevision, link.Key(rev.Host, rev.Code), rev); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := s.journal.Compact(snapshot); err != nil {
+		return fmt.Errorf("compacting journal: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,331 @@
+// Package linkmem contains a concurrency-safe, in-memory implementation of the link storer. It is
+// intended for tests and local development since nothing survives a restart.
+package linkmem
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/link"
+)
+
+// Store manages the set of APIs for short link access held in memory. Links and their revisions are
+// held under the key of the link.
+type Store struct {
+	mu        sync.RWMutex
+	links     map[string]link.Link
+	revisions map[string][]link.Revision
+	nrevs     int
+	seq       uint64
+}
+
+// NewStore constructs an empty in-memory store for short links.
+func NewStore() *Store {
+	return &Store{
+		links:     make(map[string]link.Link),
+		revisions: make(map[string][]link.Revision),
+	}
+}
+
+// Create inserts a new short link into the store along with its first revision.
+func (s *Store) Create(ctx context.Context, lnk link.Link, rev link.Revision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.links[lnk.Key()]; exists {
+		return link.ErrCodeTaken
+	}
+	s.links[lnk.Key()] = lnk
+	s.appendRevision(rev)
+
+	return nil
+}
+
+// Revise replaces a short link in the store and appends the revision which describes the change,
+// numbering it after the latest one. The click count is left untouched since it is only ever
+// changed by IncrementClicks.
+func (s *Store) Revise(ctx context.Context, lnk link.Link, rev link.Revision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.links[lnk.Key()]
+	if !exists || existing.Workspace != lnk.Workspace {
+		return link.ErrNotFound
+	}
+	lnk.Clicks = existing.Clicks
+	s.links[lnk.Key()] = lnk
+
+	rev.Number = s.nextRevision(lnk.Key())
+	s.appendRevision(rev)
+
+	return nil
+}
+
+// IncrementClicks adds one to the click count of the short link identified by the given host and
+// code and returns the updated link.
+func (s *Store) IncrementClicks(ctx context.Context, host string, code string) (link.Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := link.Key(host, code)
+	lnk, exists := s.links[key]
+	if !exists {
+		return link.Link{}, link.ErrNotFound
+	}
+	lnk.Clicks++
+	s.links[key] = lnk
+
+	return lnk, nil
+}
+
+// Archive archives the short link identified by the given host and code, provided it is expired
+// at the given time and is neither archived nor in the trash already.
+func (s *Store) Archive(ctx context.Context, host string, code string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := link.Key(host, code)
+	lnk, exists := s.links[key]
+	if !exists || lnk.DateArchived != nil || lnk.Deleted() || !lnk.Expired(now) {
+		return link.ErrNotFound
+	}
+	lnk.DateArchived = &now
+	s.links[key] = lnk
+
+	return nil
+}
+
+// UpdateThreats writes the threats of a short link and when it was flagged, provided its URLs have
+// not changed since.
+func (s *Store) UpdateThreats(ctx context.Context, lnk link.Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.links[lnk.Key()]
+	if !exists || existing.Destination != lnk.Destination || existing.FallbackURL != lnk.FallbackURL {
+		return link.ErrNotFound
+	}
+	existing.Threats = lnk.Threats
+	existing.DateFlagged = lnk.DateFlagged
+	s.links[lnk.Key()] = existing
+
+	return nil
+}
+
+// Delete removes the short link identified by the given host and code in the specified workspace
+// from the store.
+func (s *Store) Delete(ctx context.Context, workspace string, host string, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := link.Key(host, code)
+	if lnk, exists := s.links[key]; !exists || lnk.Workspace != workspace {
+		return link.ErrNotFound
+	}
+	delete(s.links, key)
+	s.nrevs -= len(s.revisions[key])
+	delete(s.revisions, key)
+
+	return nil
+}
+
+// QueryByCode gets the short link identified by the given host and code in the specified
+// workspace from the store. A blank workspace matches every workspace.
+func (s *Store) QueryByCode(ctx context.Context, workspace string, host string, code string) (link.Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lnk, exists := s.links[link.Key(host, code)]
+	if !exists || workspace != "" && lnk.Workspace != workspace {
+		return link.Link{}, link.ErrNotFound
+	}
+
+	return lnk, nil
+}
+
+// Query gets the short links which pass the filter and come after the cursor, newest first. Every
+// matching link is returned when the limit is zero.
+func (s *Store) Query(ctx context.Context, filter link.QueryFilter, after *link.Cursor, limit int) ([]link.Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	links := []link.Link{}
+	for _, lnk := range s.links {
+		if filter.Match(lnk) && (after == nil || after.After(lnk)) {
+			links = append(links, lnk)
+		}
+	}
+
+	sortNewestFirst(links)
+
+	if limit > 0 && len(links) > limit {
+		links = links[:limit]
+	}
+
+	return links, nil
+}
+
+// QueryRevisions gets every revision of the short link identified by the given host and code in
+// the specified workspace, newest first.
+func (s *Store) QueryRevisions(ctx context.Context, workspace string, host string, code string) ([]link.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := link.Key(host, code)
+	if lnk, exists := s.links[key]; !exists || lnk.Workspace != workspace {
+		return nil, link.ErrNotFound
+	}
+
+	stored := s.revisions[key]
+	revs := make([]link.Revision, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		revs = append(revs, stored[i])
+	}
+
+	return revs, nil
+}
+
+// QueryExpired gets all the short links which have expired at the given time but are not archived
+// yet.
+func (s *Store) QueryExpired(ctx context.Context, now time.Time) ([]link.Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	links := []link.Link{}
+	for _, lnk := range s.links {
+		if lnk.DateArchived == nil && !lnk.Deleted() && lnk.Expired(now) {
+			links = append(links, lnk)
+		}
+	}
+
+	return links, nil
+}
+
+// QueryDeleted gets all the short links which were moved to the trash at or before the given time.
+func (s *Store) QueryDeleted(ctx context.Context, before time.Time) ([]link.Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	links := []link.Link{}
+	for _, lnk := range s.links {
+		if lnk.Deleted() && !lnk.DateDeleted.After(before) {
+			links = append(links, lnk)
+		}
+	}
+
+	return links, nil
+}
+
+// NextSequence returns the next value of the sequence used to generate codes.
+func (s *Store) NextSequence(ctx context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+
+	return s.seq, nil
+}
+
+// Put stores the given short link as it is, replacing any link with the same host and code but
+// keeping its revisions. It is meant for stores which rebuild their state from a log.
+func (s *Store) Put(lnk link.Link) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.links[lnk.Key()] = lnk
+}
+
+// PutRevision appends the given revision as it is, number included. It is meant for stores which
+// rebuild their state from a log.
+func (s *Store) PutRevision(rev link.Revision) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.appendRevision(rev)
+}
+
+// NextRevision returns the number the next revision of the short link identified by the given host
+// and code will be given.
+func (s *Store) NextRevision(host string, code string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.nextRevision(link.Key(host, code))
+}
+
+// All returns every short link held in the store.
+func (s *Store) All() []link.Link {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	links := make([]link.Link, 0, len(s.links))
+	for _, lnk := range s.links {
+		links = append(links, lnk)
+	}
+
+	return links
+}
+
+// Len returns the number of short links held in the store.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.links)
+}
+
+// AllRevisions returns every revision held in the store, in the order they were made for each
+// short link.
+func (s *Store) AllRevisions() []link.Revision {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revs := make([]link.Revision, 0, s.nrevs)
+	for _, stored := range s.revisions {
+		revs = append(revs, stored...)
+	}
+
+	return revs
+}
+
+// LenRevisions returns the number of revisions held in the store.
+func (s *Store) LenRevisions() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.nrevs
+}
+
+// appendRevision adds a revision to the history of its link. The caller must hold the lock.
+func (s *Store) appendRevision(rev link.Revision) {
+	key := link.Key(rev.Host, rev.Code)
+	s.revisions[key] = append(s.revisions[key], rev)
+	s.nrevs++
+}
+
+// nextRevision returns the number of the next revision of the link with the given key. Links
+// created before revisions were kept start their history at one. The caller must hold the lock.
+func (s *Store) nextRevision(key string) int {
+	stored := s.revisions[key]
+	if len(stored) == 0 {
+		return 1
+	}
+
+	return stored[len(stored)-1].Number + 1
+}
+
+// sortNewestFirst orders the links by creation time, newest first, breaking ties by code and then
+// host so the ordering is stable.
+func sortNewestFirst(links []link.Link) {
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].DateCreated.Equal(links[j].DateCreated) {
+			if links[i].Code == links[j].Code {
+				return links[i].Host < links[j].Host
+			}
+			return links[i].Code < links[j].Code
+		}
+		return links[i].DateCreated.After(links[j].DateCreated)
+	})
+}
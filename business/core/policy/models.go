@@ -0,0 +1,80 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// reasonMaxLength bounds the length of the reason given for blocking a domain.
+const reasonMaxLength = 200
+
+// BlockedDomain represents an entry in the blocklist of domains that links can't point at. The
+// pattern is either an exact domain name or a wildcard such as *.example.com, which matches every
+// subdomain of example.com but not example.com itself.
+type BlockedDomain struct {
+	Pattern     string    `json:"pattern"`
+	Reason      string    `json:"reason,omitempty"`
+	CreatedBy   string    `json:"created_by"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// NewBlockedDomain contains the information needed to add a domain to the blocklist.
+type NewBlockedDomain struct {
+	Pattern string `json:"pattern"`
+	Reason  string `json:"reason"`
+}
+
+// Validate checks that the information for a new blocked domain is valid.
+func (nbd NewBlockedDomain) Validate() error {
+	var fields validate.FieldErrors
+
+	if !isPattern(normalize(nbd.Pattern)) {
+		fields.Add("pattern", "must be a domain name, optionally starting with *.")
+	}
+
+	if len(nbd.Reason) > reasonMaxLength {
+		fields.Add("reason", fmt.Sprintf("must be at most %d characters long", reasonMaxLength))
+	}
+
+	return fields.Err()
+}
+
+// isPattern reports whether the string is a domain name or a wildcard over the subdomains of one.
+func isPattern(s string) bool {
+	s = strings.TrimPrefix(s, "*.")
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(s, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// matches reports whether the host is matched by the pattern. A wildcard matches the subdomains of
+// its domain at any depth.
+func matches(pattern string, host string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+
+	return host == pattern
+}
+
+// normalize returns the form patterns and hosts are compared and stored in.
+func normalize(s string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), ".")
+}
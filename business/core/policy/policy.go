@@ -0,0 +1,252 @@
+// Package policy provides the core business API for the safety policy every destination of a short
+// link must pass. The policy only lets through the allowed schemes, turns away domains on the
+// blocklist and URLs pointing back at the service itself, and follows URLs of other known
+// shorteners to check where they really lead.
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound = errors.New("domain not found")
+	ErrExists   = errors.New("domain already exists")
+)
+
+// Set of error variables for URLs which break the policy. Their messages are phrased to be shown
+// against the field holding the URL.
+var (
+	ErrScheme       = errors.New("must use one of the allowed schemes")
+	ErrSelf         = errors.New("must not point back at this service")
+	ErrBlocked      = errors.New("points at a blocked domain")
+	ErrTooManyHops  = errors.New("redirects through too many shorteners")
+	ErrUnresolvable = errors.New("points at a shortened URL which could not be resolved")
+)
+
+// IsViolation reports whether the error is due to a URL breaking the policy, as opposed to the
+// policy failing to be checked.
+func IsViolation(err error) bool {
+	for _, v := range []error{ErrScheme, ErrSelf, ErrBlocked, ErrTooManyHops, ErrUnresolvable} {
+		if errors.Is(err, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Storer defines the behavior required to persist and retrieve the blocklist. Implementations
+// must be safe for concurrent use.
+type Storer interface {
+	Create(ctx context.Context, bd BlockedDomain) error
+	Delete(ctx context.Context, pattern string) error
+	Query(ctx context.Context) ([]BlockedDomain, error)
+}
+
+// DomainLister defines the behavior required to list the hostnames the service answers on besides
+// the configured own domains, such as its branded domains.
+type DomainLister interface {
+	Hosts(ctx context.Context) ([]string, error)
+}
+
+// Config represents the dependencies and settings required by the Core. Own domains and
+// shorteners are patterns just like the ones on the blocklist. The hosts of the domain lister, if
+// any, count as own domains as well.
+type Config struct {
+	Storer         Storer
+	Domains        DomainLister
+	Schemes        []string
+	OwnDomains     []string
+	Shorteners     []string
+	MaxHops        int
+	ResolveTimeout time.Duration
+}
+
+// Core manages the set of APIs for checking URLs against the policy and for blocklist access.
+type Core struct {
+	storer     Storer
+	domains    DomainLister
+	schemes    map[string]bool
+	ownDomains []string
+	shorteners []string
+	maxHops    int
+	client     *http.Client
+}
+
+// NewCore constructs a Core for policy API access.
+func NewCore(cfg Config) *Core {
+	c := Core{
+		storer:  cfg.Storer,
+		domains: cfg.Domains,
+		schemes: make(map[string]bool, len(cfg.Schemes)),
+		maxHops: cfg.MaxHops,
+		client: &http.Client{
+			Timeout: cfg.ResolveTimeout,
+
+			// Each redirect is looked at on its own, so none are followed automatically.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	for _, scheme := range cfg.Schemes {
+		c.schemes[strings.ToLower(scheme)] = true
+	}
+	for _, domain := range cfg.OwnDomains {
+		c.ownDomains = append(c.ownDomains, normalize(domain))
+	}
+	for _, domain := range cfg.Shorteners {
+		c.shorteners = append(c.shorteners, normalize(domain))
+	}
+
+	return &c
+}
+
+// Check runs the URL through the policy. A URL of a known shortener is followed, and every URL it
+// leads to has to pass the policy as well, for at most the configured number of hops.
+func (c *Core) Check(ctx context.Context, rawURL string) error {
+	blocklist, err := c.storer.Query(ctx)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	ownDomains := c.ownDomains
+	if c.domains != nil {
+		hosts, err := c.domains.Hosts(ctx)
+		if err != nil {
+			return fmt.Errorf("query domains: %w", err)
+		}
+		ownDomains = append(ownDomains[:len(ownDomains):len(ownDomains)], hosts...)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parsing url: %w", err)
+	}
+
+	for hops := 0; ; hops++ {
+		if !c.schemes[strings.ToLower(u.Scheme)] {
+			return ErrScheme
+		}
+
+		host := normalize(u.Hostname())
+		if matchesAny(ownDomains, host) {
+			return ErrSelf
+		}
+
+		for _, bd := range blocklist {
+			if matches(bd.Pattern, host) {
+				return fmt.Errorf("%w: %s", ErrBlocked, host)
+			}
+		}
+
+		if !matchesAny(c.shorteners, host) {
+			return nil
+		}
+
+		if hops == c.maxHops {
+			return ErrTooManyHops
+		}
+
+		next, err := c.resolve(ctx, u)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrUnresolvable, err)
+		}
+		if next == nil {
+			return nil
+		}
+		u = next
+	}
+}
+
+// resolve asks a shortener where the URL leads. It returns nil when the shortener serves the URL
+// itself rather than redirecting.
+func (c *Core) resolve(ctx context.Context, u *url.URL) (*url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		location, err := resp.Location()
+		if err != nil {
+			return nil, errors.New("redirect without a location")
+		}
+		return location, nil
+
+	case resp.StatusCode >= 400:
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return nil, nil
+}
+
+// CreateBlocked adds a new domain to the blocklist.
+func (c *Core) CreateBlocked(ctx context.Context, nbd NewBlockedDomain, createdBy string, now time.Time) (BlockedDomain, error) {
+	if err := nbd.Validate(); err != nil {
+		return BlockedDomain{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	bd := BlockedDomain{
+		Pattern:     normalize(nbd.Pattern),
+		Reason:      nbd.Reason,
+		CreatedBy:   createdBy,
+		DateCreated: now,
+	}
+
+	if err := c.storer.Create(ctx, bd); err != nil {
+		return BlockedDomain{}, fmt.Errorf("create: %w", err)
+	}
+
+	return bd, nil
+}
+
+// DeleteBlocked removes a domain from the blocklist.
+func (c *Core) DeleteBlocked(ctx context.Context, pattern string) error {
+	if err := c.storer.Delete(ctx, normalize(pattern)); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// QueryBlocked returns every domain on the blocklist in alphabetical order.
+func (c *Core) QueryBlocked(ctx context.Context) ([]BlockedDomain, error) {
+	blocklist, err := c.storer.Query(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	sort.Slice(blocklist, func(i, j int) bool {
+		return blocklist[i].Pattern < blocklist[j].Pattern
+	})
+
+	return blocklist, nil
+}
+
+// matchesAny reports whether the host is matched by any of the patterns.
+func matchesAny(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if matches(pattern, host) {
+			return true
+		}
+	}
+
+	return false
+}
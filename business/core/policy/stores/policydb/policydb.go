@@ -0,0 +1,95 @@
+// Package policydb contains the database/sql implementation of the blocklist storer.
+package policydb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yashshah7197/shrt/business/core/policy"
+	"github.com/yashshah7197/shrt/business/sys/database"
+)
+
+// Store manages the set of APIs for blocklist access in the database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore constructs a store for the blocklist backed by the given database.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create inserts a new blocked domain into the database.
+func (s *Store) Create(ctx context.Context, bd policy.BlockedDomain) error {
+	const q = `
+	INSERT INTO blocked_domains
+		(pattern, reason, created_by, date_created)
+	VALUES
+		($1, $2, $3, $4)`
+
+	if _, err := s.db.ExecContext(ctx, q, bd.Pattern, bd.Reason, bd.CreatedBy, bd.DateCreated.UTC()); err != nil {
+		if database.IsDuplicatedEntry(err) {
+			return policy.ErrExists
+		}
+		return fmt.Errorf("inserting blocked domain: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a blocked domain from the database.
+func (s *Store) Delete(ctx context.Context, pattern string) error {
+	const q = `
+	DELETE FROM
+		blocked_domains
+	WHERE
+		pattern = $1`
+
+	res, err := s.db.ExecContext(ctx, q, pattern)
+	if err != nil {
+		return fmt.Errorf("deleting blocked domain[%s]: %w", pattern, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking affected rows: %w", err)
+	}
+	if n == 0 {
+		return policy.ErrNotFound
+	}
+
+	return nil
+}
+
+// Query returns every blocked domain held in the database.
+func (s *Store) Query(ctx context.Context) ([]policy.BlockedDomain, error) {
+	const q = `
+	SELECT
+		pattern, reason, created_by, date_created
+	FROM
+		blocked_domains`
+
+	rows, err := s.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("selecting blocked domains: %w", err)
+	}
+	defer rows.Close()
+
+	domains := []policy.BlockedDomain{}
+	for rows.Next() {
+		var bd policy.BlockedDomain
+		if err := rows.Scan(&bd.Pattern, &bd.Reason, &bd.CreatedBy, &bd.DateCreated); err != nil {
+			return nil, fmt.Errorf("scanning blocked domain: %w", err)
+		}
+		domains = append(domains, bd)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating blocked domains: %w", err)
+	}
+
+	return domains, nil
+}
@@ -0,0 +1,127 @@
+// Package policyfile contains a durable, single-file implementation of the blocklist storer.
+// Every change is appended to a journal on disk and the blocklist is kept in memory for reads.
+package policyfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/policy"
+	"github.com/yashshah7197/shrt/business/core/policy/stores/policymem"
+	"github.com/yashshah7197/shrt/foundation/journal"
+)
+
+// The set of operations recorded in the journal.
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// Store manages the set of APIs for blocklist access backed by a journal file.
+type Store struct {
+	mu      sync.Mutex
+	mem     *policymem.Store
+	journal *journal.Journal
+}
+
+// Open constructs a store for the blocklist by replaying the journal at the given path.
+func Open(path string) (*Store, error) {
+	ctx := context.Background()
+	mem := policymem.NewStore()
+
+	// Rebuild the in-memory state from the journal records.
+	replay := func(rec journal.Record) error {
+		switch rec.Op {
+		case opPut:
+			var bd policy.BlockedDomain
+			if err := json.Unmarshal(rec.Data, &bd); err != nil {
+				return err
+			}
+			return mem.Create(ctx, bd)
+
+		case opDelete:
+			return mem.Delete(ctx, rec.Key)
+
+		default:
+			return fmt.Errorf("unknown operation %q", rec.Op)
+		}
+	}
+
+	jrnl, err := journal.Open(path, replay)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	s := Store{
+		mem:     mem,
+		journal: jrnl,
+	}
+
+	// The blocklist is small, so simply start off with a compact journal every time.
+	snapshot := func(emit func(op string, key string, data interface{}) error) error {
+		domains, err := mem.Query(ctx)
+		if err != nil {
+			return err
+		}
+		for _, bd := range domains {
+			if err := emit(opPut, bd.Pattern, bd); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := jrnl.Compact(snapshot); err != nil {
+		jrnl.Close()
+		return nil, fmt.Errorf("compacting journal: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Close closes the underlying journal file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.journal.Close()
+}
+
+// Create inserts a new blocked domain into the store.
+func (s *Store) Create(ctx context.Context, bd policy.BlockedDomain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByPattern(ctx, bd.Pattern); err == nil {
+		return policy.ErrExists
+	}
+
+	if err := s.journal.Append(opPut, bd.Pattern, bd); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Create(ctx, bd)
+}
+
+// Delete removes a blocked domain from the store.
+func (s *Store) Delete(ctx context.Context, pattern string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByPattern(ctx, pattern); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opDelete, pattern, nil); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Delete(ctx, pattern)
+}
+
+// Query returns every blocked domain held in the store.
+func (s *Store) Query(ctx context.Context) ([]policy.BlockedDomain, error) {
+	return s.mem.Query(ctx)
+}
@@ -0,0 +1,75 @@
+// Package policymem contains a concurrency-safe, in-memory implementation of the blocklist
+// storer. It is intended for tests and local development since nothing survives a restart.
+package policymem
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/policy"
+)
+
+// Store manages the set of APIs for blocklist access held in memory.
+type Store struct {
+	mu      sync.RWMutex
+	domains map[string]policy.BlockedDomain
+}
+
+// NewStore constructs an empty in-memory store for the blocklist.
+func NewStore() *Store {
+	return &Store{
+		domains: make(map[string]policy.BlockedDomain),
+	}
+}
+
+// Create inserts a new blocked domain into the store.
+func (s *Store) Create(ctx context.Context, bd policy.BlockedDomain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.domains[bd.Pattern]; exists {
+		return policy.ErrExists
+	}
+	s.domains[bd.Pattern] = bd
+
+	return nil
+}
+
+// Delete removes a blocked domain from the store.
+func (s *Store) Delete(ctx context.Context, pattern string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.domains[pattern]; !exists {
+		return policy.ErrNotFound
+	}
+	delete(s.domains, pattern)
+
+	return nil
+}
+
+// QueryByPattern gets a single blocked domain from the store.
+func (s *Store) QueryByPattern(ctx context.Context, pattern string) (policy.BlockedDomain, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bd, exists := s.domains[pattern]
+	if !exists {
+		return policy.BlockedDomain{}, policy.ErrNotFound
+	}
+
+	return bd, nil
+}
+
+// Query returns every blocked domain held in the store.
+func (s *Store) Query(ctx context.Context) ([]policy.BlockedDomain, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	domains := make([]policy.BlockedDomain, 0, len(s.domains))
+	for _, bd := range s.domains {
+		domains = append(domains, bd)
+	}
+
+	return domains, nil
+}
@@ -0,0 +1,52 @@
+package reserved
+
+import (
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// These are the expected values for Word.Kind.
+const (
+	// KindReserved marks a word that can't be used as a code.
+	KindReserved = "reserved"
+
+	// KindBlocked marks a word that can't appear anywhere in a code.
+	KindBlocked = "blocked"
+)
+
+// Word represents an entry in the registry of words that can't be used in short codes.
+type Word struct {
+	Word        string    `json:"word"`
+	Kind        string    `json:"kind"`
+	System      bool      `json:"system,omitempty"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// NewWord contains the information needed to add a word to the registry.
+type NewWord struct {
+	Word string `json:"word"`
+	Kind string `json:"kind"`
+}
+
+// Validate checks that the information for a new word is valid.
+func (nw NewWord) Validate() error {
+	var fields validate.FieldErrors
+
+	if err := validate.Slug(nw.Word, 1, 64); err != nil {
+		fields.Add("word", err.Error())
+	}
+
+	if nw.Kind != KindReserved && nw.Kind != KindBlocked {
+		fields.Add("kind", "must be one of reserved or blocked")
+	}
+
+	return fields.Err()
+}
+
+// normalize returns the form words are compared and stored in.
+func normalize(word string) string {
+	return strings.ToLower(word)
+}
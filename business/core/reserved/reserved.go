@@ -0,0 +1,168 @@
+// Package reserved provides the core business API for the registry of reserved and blocked words.
+// Reserved words can't be used as a short code, which keeps vanity codes from shadowing the routes
+// of the service. Blocked words can't appear anywhere in a short code.
+package reserved
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound = errors.New("word not found")
+	ErrExists   = errors.New("word already exists")
+	ErrSystem   = errors.New("system words can't be removed")
+	ErrReserved = errors.New("is reserved")
+	ErrBlocked  = errors.New("contains a blocked word")
+)
+
+// SystemWords are reserved in addition to the routes of the service, for routes which live outside
+// of the API mux or which are likely to be added in the future.
+var SystemWords = []string{"debug", "api", "admin", "static", "assets"}
+
+// Storer defines the behavior required to persist and retrieve registry words. Implementations
+// must be safe for concurrent use.
+type Storer interface {
+	Create(ctx context.Context, w Word) error
+	Delete(ctx context.Context, word string) error
+	Query(ctx context.Context) ([]Word, error)
+}
+
+// Core manages the set of APIs for registry access.
+type Core struct {
+	storer Storer
+
+	mu     sync.RWMutex
+	system map[string]struct{}
+}
+
+// NewCore constructs a Core for registry API access.
+func NewCore(storer Storer) *Core {
+	c := Core{
+		storer: storer,
+		system: make(map[string]struct{}),
+	}
+	c.Protect(SystemWords...)
+
+	return &c
+}
+
+// Protect reserves the given words on behalf of the system. These words are not persisted and
+// can't be removed through the API.
+func (c *Core) Protect(words ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, word := range words {
+		c.system[normalize(word)] = struct{}{}
+	}
+}
+
+// Create adds a new word to the registry.
+func (c *Core) Create(ctx context.Context, nw NewWord, createdBy string, now time.Time) (Word, error) {
+	if err := nw.Validate(); err != nil {
+		return Word{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	w := Word{
+		Word:        normalize(nw.Word),
+		Kind:        nw.Kind,
+		CreatedBy:   createdBy,
+		DateCreated: now,
+	}
+
+	if c.isSystem(w.Word) {
+		return Word{}, ErrExists
+	}
+
+	if err := c.storer.Create(ctx, w); err != nil {
+		return Word{}, fmt.Errorf("create: %w", err)
+	}
+
+	return w, nil
+}
+
+// Delete removes a word from the registry.
+func (c *Core) Delete(ctx context.Context, word string) error {
+	word = normalize(word)
+
+	if c.isSystem(word) {
+		return ErrSystem
+	}
+
+	if err := c.storer.Delete(ctx, word); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query returns every word in the registry, including the system words, in alphabetical order.
+func (c *Core) Query(ctx context.Context) ([]Word, error) {
+	words, err := c.storer.Query(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	c.mu.RLock()
+	for word := range c.system {
+		words = append(words, Word{
+			Word:   word,
+			Kind:   KindReserved,
+			System: true,
+		})
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(words, func(i, j int) bool {
+		return words[i].Word < words[j].Word
+	})
+
+	return words, nil
+}
+
+// Check verifies that the given code is neither reserved nor contains a blocked word. Words are
+// matched case-insensitively.
+func (c *Core) Check(ctx context.Context, code string) error {
+	code = normalize(code)
+
+	if c.isSystem(code) {
+		return ErrReserved
+	}
+
+	words, err := c.storer.Query(ctx)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	for _, w := range words {
+		switch w.Kind {
+		case KindReserved:
+			if code == w.Word {
+				return ErrReserved
+			}
+
+		case KindBlocked:
+			if strings.Contains(code, w.Word) {
+				return ErrBlocked
+			}
+		}
+	}
+
+	return nil
+}
+
+// isSystem reports whether the given normalized word is reserved by the system.
+func (c *Core) isSystem(word string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, exists := c.system[word]
+	return exists
+}
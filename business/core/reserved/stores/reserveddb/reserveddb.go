@@ -0,0 +1,95 @@
+// Package reserveddb contains the database/sql implementation of the registry storer.
+package reserveddb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yashshah7197/shrt/business/core/reserved"
+	"github.com/yashshah7197/shrt/business/sys/database"
+)
+
+// Store manages the set of APIs for registry access in the database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore constructs a store for registry words backed by the given database.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create inserts a new word into the database.
+func (s *Store) Create(ctx context.Context, w reserved.Word) error {
+	const q = `
+	INSERT INTO reserved_words
+		(word, kind, created_by, date_created)
+	VALUES
+		($1, $2, $3, $4)`
+
+	if _, err := s.db.ExecContext(ctx, q, w.Word, w.Kind, w.CreatedBy, w.DateCreated.UTC()); err != nil {
+		if database.IsDuplicatedEntry(err) {
+			return reserved.ErrExists
+		}
+		return fmt.Errorf("inserting word: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a word from the database.
+func (s *Store) Delete(ctx context.Context, word string) error {
+	const q = `
+	DELETE FROM
+		reserved_words
+	WHERE
+		word = $1`
+
+	res, err := s.db.ExecContext(ctx, q, word)
+	if err != nil {
+		return fmt.Errorf("deleting word[%s]: %w", word, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking affected rows: %w", err)
+	}
+	if n == 0 {
+		return reserved.ErrNotFound
+	}
+
+	return nil
+}
+
+// Query returns every word held in the database.
+func (s *Store) Query(ctx context.Context) ([]reserved.Word, error) {
+	const q = `
+	SELECT
+		word, kind, created_by, date_created
+	FROM
+		reserved_words`
+
+	rows, err := s.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("selecting words: %w", err)
+	}
+	defer rows.Close()
+
+	words := []reserved.Word{}
+	for rows.Next() {
+		var w reserved.Word
+		if err := rows.Scan(&w.Word, &w.Kind, &w.CreatedBy, &w.DateCreated); err != nil {
+			return nil, fmt.Errorf("scanning word: %w", err)
+		}
+		words = append(words, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating words: %w", err)
+	}
+
+	return words, nil
+}
@@ -0,0 +1,127 @@
+// Package reservedfile contains a durable, single-file implementation of the registry storer.
+// Every change is appended to a journal on disk and the registry is kept in memory for reads.
+package reservedfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/reserved"
+	"github.com/yashshah7197/shrt/business/core/reserved/stores/reservedmem"
+	"github.com/yashshah7197/shrt/foundation/journal"
+)
+
+// The set of operations recorded in the journal.
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// Store manages the set of APIs for registry access backed by a journal file.
+type Store struct {
+	mu      sync.Mutex
+	mem     *reservedmem.Store
+	journal *journal.Journal
+}
+
+// Open constructs a store for registry words by replaying the journal at the given path.
+func Open(path string) (*Store, error) {
+	ctx := context.Background()
+	mem := reservedmem.NewStore()
+
+	// Rebuild the in-memory state from the journal records.
+	replay := func(rec journal.Record) error {
+		switch rec.Op {
+		case opPut:
+			var w reserved.Word
+			if err := json.Unmarshal(rec.Data, &w); err != nil {
+				return err
+			}
+			return mem.Create(ctx, w)
+
+		case opDelete:
+			return mem.Delete(ctx, rec.Key)
+
+		default:
+			return fmt.Errorf("unknown operation %q", rec.Op)
+		}
+	}
+
+	jrnl, err := journal.Open(path, replay)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	s := Store{
+		mem:     mem,
+		journal: jrnl,
+	}
+
+	// The registry is small, so simply start off with a compact journal every time.
+	snapshot := func(emit func(op string, key string, data interface{}) error) error {
+		words, err := mem.Query(ctx)
+		if err != nil {
+			return err
+		}
+		for _, w := range words {
+			if err := emit(opPut, w.Word, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := jrnl.Compact(snapshot); err != nil {
+		jrnl.Close()
+		return nil, fmt.Errorf("compacting journal: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Close closes the underlying journal file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.journal.Close()
+}
+
+// Create inserts a new word into the store.
+func (s *Store) Create(ctx context.Context, w reserved.Word) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByWord(ctx, w.Word); err == nil {
+		return reserved.ErrExists
+	}
+
+	if err := s.journal.Append(opPut, w.Word, w); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Create(ctx, w)
+}
+
+// Delete removes a word from the store.
+func (s *Store) Delete(ctx context.Context, word string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByWord(ctx, word); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opDelete, word, nil); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Delete(ctx, word)
+}
+
+// Query returns every word held in the store.
+func (s *Store) Query(ctx context.Context) ([]reserved.Word, error) {
+	return s.mem.Query(ctx)
+}
@@ -0,0 +1,75 @@
+// Package reservedmem contains a concurrency-safe, in-memory implementation of the registry
+// storer. It is intended for tests and local development since nothing survives a restart.
+package reservedmem
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yashshah7197/shrt/business/core/reserved"
+)
+
+// Store manages the set of APIs for registry access held in memory.
+type Store struct {
+	mu    sync.RWMutex
+	words map[string]reserved.Word
+}
+
+// NewStore constructs an empty in-memory store for registry words.
+func NewStore() *Store {
+	return &Store{
+		words: make(map[string]reserved.Word),
+	}
+}
+
+// Create inserts a new word into the store.
+func (s *Store) Create(ctx context.Context, w reserved.Word) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.words[w.Word]; exists {
+		return reserved.ErrExists
+	}
+	s.words[w.Word] = w
+
+	return nil
+}
+
+// Delete removes a word from the store.
+func (s *Store) Delete(ctx context.Context, word string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.words[word]; !exists {
+		return reserved.ErrNotFound
+	}
+	delete(s.words, word)
+
+	return nil
+}
+
+// QueryByWord gets a single word from the store.
+func (s *Store) QueryByWord(ctx context.Context, word string) (reserved.Word, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w, exists := s.words[word]
+	if !exists {
+		return reserved.Word{}, reserved.ErrNotFound
+	}
+
+	return w, nil
+}
+
+// Query returns every word held in the store.
+func (s *Store) Query(ctx context.Context) ([]reserved.Word, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	words := make([]reserved.Word, 0, len(s.words))
+	for _, w := range s.words {
+		words = append(words, w)
+	}
+
+	return words, nil
+}
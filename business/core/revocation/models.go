@@ -0,0 +1,11 @@
+package revocation
+
+import "time"
+
+// Revocation represents a token which was revoked before it expired. It only needs to be kept
+// until then, since the token is turned down anyway from that point on.
+type Revocation struct {
+	ID          string    `json:"id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	DateRevoked time.Time `json:"date_revoked"`
+}
@@ -0,0 +1,109 @@
+// Package revocation provides the core business API for the list of tokens which were revoked
+// before they expired. Every request checks the list, so it is answered from a cache in memory and
+// the store is only there to keep the list across restarts and to share it between instances.
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Storer defines the behavior required to persist and retrieve revocations. Implementations must
+// be safe for concurrent use, and must accept a token being revoked more than once.
+type Storer interface {
+	Create(ctx context.Context, r Revocation) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+	Query(ctx context.Context) ([]Revocation, error)
+}
+
+// Core manages the set of APIs for revocation access.
+type Core struct {
+	storer Storer
+
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+// NewCore constructs a Core for revocation API access, starting off with the revocations which are
+// in the store at the given time.
+func NewCore(ctx context.Context, storer Storer, now time.Time) (*Core, error) {
+	c := Core{
+		storer:  storer,
+		revoked: make(map[string]time.Time),
+	}
+
+	if _, err := c.Reload(ctx, now); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Revoke adds the token identified by the given ID to the list until it expires. Tokens which have
+// already expired are left off.
+func (c *Core) Revoke(ctx context.Context, id string, expiresAt time.Time, now time.Time) error {
+	if !expiresAt.After(now) {
+		return nil
+	}
+
+	r := Revocation{
+		ID:          id,
+		ExpiresAt:   expiresAt,
+		DateRevoked: now,
+	}
+
+	if err := c.storer.Create(ctx, r); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.revoked[id] = expiresAt
+
+	return nil
+}
+
+// Revoked reports whether the token identified by the given ID is on the list.
+func (c *Core) Revoked(id string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, revoked := c.revoked[id]
+	return revoked
+}
+
+// Reload drops the revocations which have expired at the given time from the store, and then
+// replaces the cache with what is left. This picks up tokens revoked by other instances sharing the
+// store. It returns the number of revocations dropped.
+func (c *Core) Reload(ctx context.Context, now time.Time) (int, error) {
+	dropped, err := c.storer.DeleteExpired(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired: %w", err)
+	}
+
+	revocations, err := c.storer.Query(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("query: %w", err)
+	}
+
+	revoked := make(map[string]time.Time, len(revocations))
+	for _, r := range revocations {
+		revoked[r.ID] = r.ExpiresAt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Keep what was revoked through this instance while the store was being read.
+	for id, expiresAt := range c.revoked {
+		if _, exists := revoked[id]; !exists && expiresAt.After(now) {
+			revoked[id] = expiresAt
+		}
+	}
+	c.revoked = revoked
+
+	return dropped, nil
+}
@@ -0,0 +1,92 @@
+// Package revocationdb contains the database/sql implementation of the revocation storer.
+package revocationdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/revocation"
+)
+
+// Store manages the set of APIs for revocation access in the database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore constructs a store for revocations backed by the given database.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create adds a revocation to the database, replacing an earlier one of the same token.
+func (s *Store) Create(ctx context.Context, r revocation.Revocation) error {
+	const q = `
+	INSERT INTO revocations
+		(token_id, expires_at, date_revoked)
+	VALUES
+		($1, $2, $3)
+	ON CONFLICT (token_id) DO UPDATE SET
+		expires_at = EXCLUDED.expires_at,
+		date_revoked = EXCLUDED.date_revoked`
+
+	if _, err := s.db.ExecContext(ctx, q, r.ID, r.ExpiresAt.UTC(), r.DateRevoked.UTC()); err != nil {
+		return fmt.Errorf("inserting revocation: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes every revocation which has expired at the given time from the database.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	const q = `
+	DELETE FROM
+		revocations
+	WHERE
+		expires_at <= $1`
+
+	res, err := s.db.ExecContext(ctx, q, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired revocations: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("checking affected rows: %w", err)
+	}
+
+	return int(n), nil
+}
+
+// Query gets every revocation in the database.
+func (s *Store) Query(ctx context.Context) ([]revocation.Revocation, error) {
+	const q = `
+	SELECT
+		token_id, expires_at, date_revoked
+	FROM
+		revocations`
+
+	rows, err := s.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("selecting revocations: %w", err)
+	}
+	defer rows.Close()
+
+	revocations := []revocation.Revocation{}
+	for rows.Next() {
+		var r revocation.Revocation
+		if err := rows.Scan(&r.ID, &r.ExpiresAt, &r.DateRevoked); err != nil {
+			return nil, fmt.Errorf("scanning revocation: %w", err)
+		}
+		revocations = append(revocations, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating revocations: %w", err)
+	}
+
+	return revocations, nil
+}
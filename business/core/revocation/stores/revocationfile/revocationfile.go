@@ -0,0 +1,136 @@
+// Package revocationfile contains a durable, single-file implementation of the revocation storer.
+// Every change is appended to a journal on disk and the revocations are kept in memory for reads.
+package revocationfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/revocation"
+	"github.com/yashshah7197/shrt/business/core/revocation/stores/revocationmem"
+	"github.com/yashshah7197/shrt/foundation/journal"
+)
+
+// The set of operations recorded in the journal.
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// Store manages the set of APIs for revocation access backed by a journal file.
+type Store struct {
+	mu      sync.Mutex
+	mem     *revocationmem.Store
+	journal *journal.Journal
+}
+
+// Open constructs a store for revocations by replaying the journal at the given path.
+func Open(path string) (*Store, error) {
+	ctx := context.Background()
+	mem := revocationmem.NewStore()
+
+	// Rebuild the in-memory state from the journal records.
+	replay := func(rec journal.Record) error {
+		switch rec.Op {
+		case opPut:
+			var r revocation.Revocation
+			if err := json.Unmarshal(rec.Data, &r); err != nil {
+				return err
+			}
+			return mem.Create(ctx, r)
+
+		case opDelete:
+			return mem.Delete(ctx, rec.Key)
+
+		default:
+			return fmt.Errorf("unknown operation %q", rec.Op)
+		}
+	}
+
+	jrnl, err := journal.Open(path, replay)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	s := Store{
+		mem:     mem,
+		journal: jrnl,
+	}
+
+	// Revocations are dropped as they expire, so start off with a compact journal every time.
+	snapshot := func(emit func(op string, key string, data interface{}) error) error {
+		revocations, err := mem.Query(ctx)
+		if err != nil {
+			return err
+		}
+		for _, r := range revocations {
+			if err := emit(opPut, r.ID, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := jrnl.Compact(snapshot); err != nil {
+		jrnl.Close()
+		return nil, fmt.Errorf("compacting journal: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Close closes the underlying journal file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.journal.Close()
+}
+
+// Create adds a revocation to the store, replacing an earlier one of the same token.
+func (s *Store) Create(ctx context.Context, r revocation.Revocation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.journal.Append(opPut, r.ID, r); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Create(ctx, r)
+}
+
+// DeleteExpired removes every revocation which has expired at the given time from the store.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revocations, err := s.mem.Query(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int
+	for _, r := range revocations {
+		if r.ExpiresAt.After(now) {
+			continue
+		}
+
+		if err := s.journal.Append(opDelete, r.ID, nil); err != nil {
+			return deleted, fmt.Errorf("appending to journal: %w", err)
+		}
+		if err := s.mem.Delete(ctx, r.ID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// Query gets every revocation in the store.
+func (s *Store) Query(ctx context.Context) ([]revocation.Revocation, error) {
+	return s.mem.Query(ctx)
+}
@@ -0,0 +1,74 @@
+// Package revocationmem contains a concurrency-safe, in-memory implementation of the revocation
+// storer. It is intended for tests and local development since nothing survives a restart.
+package revocationmem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/revocation"
+)
+
+// Store manages the set of APIs for revocation access held in memory.
+type Store struct {
+	mu          sync.RWMutex
+	revocations map[string]revocation.Revocation
+}
+
+// NewStore constructs an empty in-memory store for revocations.
+func NewStore() *Store {
+	return &Store{
+		revocations: make(map[string]revocation.Revocation),
+	}
+}
+
+// Create adds a revocation to the store, replacing an earlier one of the same token.
+func (s *Store) Create(ctx context.Context, r revocation.Revocation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revocations[r.ID] = r
+
+	return nil
+}
+
+// Delete removes the revocation of the token identified by the given ID from the store, if there
+// is one.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.revocations, id)
+
+	return nil
+}
+
+// DeleteExpired removes every revocation which has expired at the given time from the store.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int
+	for id, r := range s.revocations {
+		if !r.ExpiresAt.After(now) {
+			delete(s.revocations, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// Query gets every revocation in the store.
+func (s *Store) Query(ctx context.Context) ([]revocation.Revocation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revocations := make([]revocation.Revocation, 0, len(s.revocations))
+	for _, r := range s.revocations {
+		revocations = append(revocations, r)
+	}
+
+	return revocations, nil
+}
@@ -0,0 +1,39 @@
+package session
+
+import "time"
+
+// RefreshToken represents a refresh token, which trades in for a new access token once the last
+// one expires. Signing in starts a new family of refresh tokens, and every time a token is used it
+// is rotated: it is marked as used and replaced by a new token of the same family. Only a hash of
+// the token itself is ever kept.
+type RefreshToken struct {
+	ID              string     `json:"id"`
+	Family          string     `json:"family"`
+	Subject         string     `json:"subject"`
+	AccessID        string     `json:"access_id"`
+	AccessExpiresAt time.Time  `json:"access_expires_at"`
+	Hash            string     `json:"-"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	RotatedAt       *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	DateCreated     time.Time  `json:"date_created"`
+}
+
+// Live reports whether the token can still be used at the given time.
+func (rt RefreshToken) Live(now time.Time) bool {
+	return rt.RotatedAt == nil && rt.RevokedAt == nil && now.Before(rt.ExpiresAt)
+}
+
+// Access identifies the access token handed out along with a refresh token, so that it can be
+// revoked together with the family of the refresh token.
+type Access struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+// IssuedToken is a refresh token as it is handed out, the only time the token itself is ever
+// known.
+type IssuedToken struct {
+	RefreshToken
+	Secret string
+}
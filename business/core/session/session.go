@@ -0,0 +1,221 @@
+// Package session provides the core business API for the sessions users keep up with refresh
+// tokens. Access tokens are short-lived, and a refresh token trades in for a new access token and a
+// new refresh token. A refresh token can only be used once: using it a second time means it has
+// leaked, so the whole family of tokens it belongs to is revoked, access tokens included.
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yashshah7197/shrt/business/core/revocation"
+	"github.com/yashshah7197/shrt/business/sys/codegen"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound = errors.New("refresh token not found")
+)
+
+// Set of error variables for refreshing tokens.
+var (
+	ErrInvalidToken = errors.New("refresh token is invalid or expired")
+	ErrReused       = errors.New("refresh token was already used, the session has been revoked")
+)
+
+// The shape of the refresh tokens handed out. They are long enough that they can't be guessed.
+const (
+	secretAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	secretLength   = 48
+)
+
+// Storer defines the behavior required to persist and retrieve refresh tokens. Implementations
+// must be safe for concurrent use.
+type Storer interface {
+	Create(ctx context.Context, rt RefreshToken) error
+	Update(ctx context.Context, rt RefreshToken) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+	QueryByHash(ctx context.Context, hash string) (RefreshToken, error)
+	QueryByAccessID(ctx context.Context, accessID string) (RefreshToken, error)
+	QueryByFamily(ctx context.Context, family string) ([]RefreshToken, error)
+}
+
+// Config represents the dependencies and settings required by the Core. Refresh tokens stay valid
+// for the TTL unless they are used or revoked before then. Access tokens are revoked through the
+// revocation list.
+type Config struct {
+	Storer     Storer
+	Revocation *revocation.Core
+	TTL        time.Duration
+}
+
+// Core manages the set of APIs for session access.
+type Core struct {
+	storer     Storer
+	revocation *revocation.Core
+	ttl        time.Duration
+	generator  *codegen.Random
+
+	// mu makes using a refresh token and rotating it a single step, so the same token can't be
+	// rotated twice by requests racing each other.
+	mu sync.Mutex
+}
+
+// NewCore constructs a Core for session API access.
+func NewCore(cfg Config) (*Core, error) {
+	alphabet, err := codegen.NewAlphabet(secretAlphabet)
+	if err != nil {
+		return nil, fmt.Errorf("constructing alphabet: %w", err)
+	}
+
+	generator, err := codegen.NewRandom(alphabet, secretLength)
+	if err != nil {
+		return nil, fmt.Errorf("constructing generator: %w", err)
+	}
+
+	c := Core{
+		storer:     cfg.Storer,
+		revocation: cfg.Revocation,
+		ttl:        cfg.TTL,
+		generator:  generator,
+	}
+
+	return &c, nil
+}
+
+// Start begins a new session for the subject, handing out the first refresh token of a new family
+// along with the given access token.
+func (c *Core) Start(ctx context.Context, subject string, access Access, now time.Time) (IssuedToken, error) {
+	return c.issue(ctx, uuid.NewString(), subject, access, now)
+}
+
+// Rotate trades in the given refresh token for a new one of the same family, handed out along with
+// the given access token. A token which was already used revokes its whole family instead.
+func (c *Core) Rotate(ctx context.Context, secret string, access Access, now time.Time) (IssuedToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rt, err := c.storer.QueryByHash(ctx, hash(secret))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return IssuedToken{}, ErrInvalidToken
+		}
+		return IssuedToken{}, fmt.Errorf("query: %w", err)
+	}
+
+	// If the token was used before, either the client or somebody who got hold of the token is
+	// replaying it, and there is no telling which. End the session for both.
+	if rt.RotatedAt != nil && rt.RevokedAt == nil {
+		if err := c.revokeFamily(ctx, rt.Family, now); err != nil {
+			return IssuedToken{}, err
+		}
+		return IssuedToken{}, ErrReused
+	}
+
+	if !rt.Live(now) {
+		return IssuedToken{}, ErrInvalidToken
+	}
+
+	rt.RotatedAt = &now
+	if err := c.storer.Update(ctx, rt); err != nil {
+		return IssuedToken{}, fmt.Errorf("update: %w", err)
+	}
+
+	return c.issue(ctx, rt.Family, rt.Subject, access, now)
+}
+
+// End finishes the session the given access token belongs to. The access token is revoked right
+// away, and so is the family of the refresh token it was handed out with, if there is one.
+func (c *Core) End(ctx context.Context, access Access, now time.Time) error {
+	if err := c.revocation.Revoke(ctx, access.ID, access.ExpiresAt, now); err != nil {
+		return fmt.Errorf("revoking access token[%s]: %w", access.ID, err)
+	}
+
+	rt, err := c.storer.QueryByAccessID(ctx, access.ID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("query: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.revokeFamily(ctx, rt.Family, now)
+}
+
+// DeleteExpired removes the refresh tokens which have expired at the given time, and returns how
+// many were removed.
+func (c *Core) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	deleted, err := c.storer.DeleteExpired(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// issue hands out a new refresh token of the given family.
+func (c *Core) issue(ctx context.Context, family string, subject string, access Access, now time.Time) (IssuedToken, error) {
+	secret, err := c.generator.Generate(ctx)
+	if err != nil {
+		return IssuedToken{}, fmt.Errorf("generating refresh token: %w", err)
+	}
+
+	rt := RefreshToken{
+		ID:              uuid.NewString(),
+		Family:          family,
+		Subject:         subject,
+		AccessID:        access.ID,
+		AccessExpiresAt: access.ExpiresAt,
+		Hash:            hash(secret),
+		ExpiresAt:       now.Add(c.ttl),
+		DateCreated:     now,
+	}
+
+	if err := c.storer.Create(ctx, rt); err != nil {
+		return IssuedToken{}, fmt.Errorf("create: %w", err)
+	}
+
+	return IssuedToken{RefreshToken: rt, Secret: secret}, nil
+}
+
+// revokeFamily revokes every refresh token of the family, along with the access tokens which were
+// handed out with them. The caller must hold the lock.
+func (c *Core) revokeFamily(ctx context.Context, family string, now time.Time) error {
+	tokens, err := c.storer.QueryByFamily(ctx, family)
+	if err != nil {
+		return fmt.Errorf("query family: %w", err)
+	}
+
+	for _, rt := range tokens {
+		if err := c.revocation.Revoke(ctx, rt.AccessID, rt.AccessExpiresAt, now); err != nil {
+			return fmt.Errorf("revoking access token[%s]: %w", rt.AccessID, err)
+		}
+
+		if rt.RevokedAt != nil {
+			continue
+		}
+		rt.RevokedAt = &now
+		if err := c.storer.Update(ctx, rt); err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// hash returns the form in which a refresh token is stored and looked up. Tokens are long and
+// random, so a single round of SHA-256 is enough to keep them safe.
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
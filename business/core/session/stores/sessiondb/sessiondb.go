@@ -0,0 +1,222 @@
+// Package sessiondb contains the database/sql implementation of the session storer.
+package sessiondb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/session"
+	"github.com/yashshah7197/shrt/business/sys/database"
+)
+
+// columns lists the refresh token columns in the order scanToken reads them.
+const columns = `
+		token_id, family, subject, access_id, access_expires_at, hash, expires_at, rotated_at,
+		revoked_at, date_created`
+
+// Store manages the set of APIs for refresh token access in the database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore constructs a store for refresh tokens backed by the given database.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create inserts a new refresh token into the database.
+func (s *Store) Create(ctx context.Context, rt session.RefreshToken) error {
+	const q = `
+	INSERT INTO refresh_tokens (` + columns + `)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	if _, err := s.db.ExecContext(ctx, q,
+		rt.ID,
+		rt.Family,
+		rt.Subject,
+		rt.AccessID,
+		rt.AccessExpiresAt.UTC(),
+		rt.Hash,
+		rt.ExpiresAt.UTC(),
+		nullTime(rt.RotatedAt),
+		nullTime(rt.RevokedAt),
+		rt.DateCreated.UTC(),
+	); err != nil {
+		return fmt.Errorf("inserting refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a refresh token in the database. Only the times a token was rotated and revoked
+// at ever change.
+func (s *Store) Update(ctx context.Context, rt session.RefreshToken) error {
+	const q = `
+	UPDATE
+		refresh_tokens
+	SET
+		rotated_at = $2,
+		revoked_at = $3
+	WHERE
+		token_id = $1`
+
+	res, err := s.db.ExecContext(ctx, q, rt.ID, nullTime(rt.RotatedAt), nullTime(rt.RevokedAt))
+	if err != nil {
+		return fmt.Errorf("updating refresh token[%s]: %w", rt.ID, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking affected rows: %w", err)
+	}
+	if n == 0 {
+		return session.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteExpired removes every refresh token which has expired at the given time from the
+// database.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	const q = `
+	DELETE FROM
+		refresh_tokens
+	WHERE
+		expires_at <= $1`
+
+	res, err := s.db.ExecContext(ctx, q, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired refresh tokens: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("checking affected rows: %w", err)
+	}
+
+	return int(n), nil
+}
+
+// QueryByHash gets the refresh token with the given hash from the database.
+func (s *Store) QueryByHash(ctx context.Context, hash string) (session.RefreshToken, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		refresh_tokens
+	WHERE
+		hash = $1`
+
+	rt, err := scanToken(s.db.QueryRowContext(ctx, q, hash))
+	if err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return session.RefreshToken{}, session.ErrNotFound
+		}
+		return session.RefreshToken{}, fmt.Errorf("selecting refresh token by hash: %w", err)
+	}
+
+	return rt, nil
+}
+
+// QueryByAccessID gets the refresh token handed out along with the access token identified by the
+// given ID from the database.
+func (s *Store) QueryByAccessID(ctx context.Context, accessID string) (session.RefreshToken, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		refresh_tokens
+	WHERE
+		access_id = $1`
+
+	rt, err := scanToken(s.db.QueryRowContext(ctx, q, accessID))
+	if err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return session.RefreshToken{}, session.ErrNotFound
+		}
+		return session.RefreshToken{}, fmt.Errorf("selecting refresh token by access token[%s]: %w", accessID, err)
+	}
+
+	return rt, nil
+}
+
+// QueryByFamily gets every refresh token of the specified family from the database.
+func (s *Store) QueryByFamily(ctx context.Context, family string) ([]session.RefreshToken, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		refresh_tokens
+	WHERE
+		family = $1`
+
+	rows, err := s.db.QueryContext(ctx, q, family)
+	if err != nil {
+		return nil, fmt.Errorf("selecting refresh tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []session.RefreshToken{}
+	for rows.Next() {
+		rt, err := scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning refresh token: %w", err)
+		}
+		tokens = append(tokens, rt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating refresh tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// scanner is implemented by both sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanToken reads a single refresh token out of a row.
+func scanToken(row scanner) (session.RefreshToken, error) {
+	var (
+		rt        session.RefreshToken
+		rotatedAt sql.NullTime
+		revokedAt sql.NullTime
+	)
+	if err := row.Scan(
+		&rt.ID,
+		&rt.Family,
+		&rt.Subject,
+		&rt.AccessID,
+		&rt.AccessExpiresAt,
+		&rt.Hash,
+		&rt.ExpiresAt,
+		&rotatedAt,
+		&revokedAt,
+		&rt.DateCreated,
+	); err != nil {
+		return session.RefreshToken{}, err
+	}
+	if rotatedAt.Valid {
+		rt.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		rt.RevokedAt = &revokedAt.Time
+	}
+
+	return rt, nil
+}
+
+// nullTime converts an optional time into its database form.
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
@@ -0,0 +1,165 @@
+// Package sessionfile contains a durable, single-file implementation of the session storer. Every
+// change is appended to a journal on disk and the refresh tokens are kept in memory for reads.
+package sessionfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/session"
+	"github.com/yashshah7197/shrt/business/core/session/stores/sessionmem"
+	"github.com/yashshah7197/shrt/foundation/journal"
+)
+
+// The set of operations recorded in the journal.
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// record is the form in which a refresh token is written to the journal. The hash is left out of
+// the JSON form of a token, so it is carried alongside it here.
+type record struct {
+	session.RefreshToken
+	Hash string `json:"hash"`
+}
+
+// Store manages the set of APIs for refresh token access backed by a journal file.
+type Store struct {
+	mu      sync.Mutex
+	mem     *sessionmem.Store
+	journal *journal.Journal
+}
+
+// Open constructs a store for refresh tokens by replaying the journal at the given path.
+func Open(path string) (*Store, error) {
+	ctx := context.Background()
+	mem := sessionmem.NewStore()
+
+	// Rebuild the in-memory state from the journal records.
+	replay := func(rec journal.Record) error {
+		switch rec.Op {
+		case opPut:
+			var r record
+			if err := json.Unmarshal(rec.Data, &r); err != nil {
+				return err
+			}
+			rt := r.RefreshToken
+			rt.Hash = r.Hash
+
+			// Records hold the full state of a token, so replace whatever was there before.
+			return mem.Create(ctx, rt)
+
+		case opDelete:
+			return mem.Delete(ctx, rec.Key)
+
+		default:
+			return fmt.Errorf("unknown operation %q", rec.Op)
+		}
+	}
+
+	jrnl, err := journal.Open(path, replay)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	s := Store{
+		mem:     mem,
+		journal: jrnl,
+	}
+
+	// Every refresh is recorded, so start off with a compact journal every time.
+	snapshot := func(emit func(op string, key string, data interface{}) error) error {
+		for _, rt := range mem.All() {
+			if err := emit(opPut, rt.ID, record{RefreshToken: rt, Hash: rt.Hash}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := jrnl.Compact(snapshot); err != nil {
+		jrnl.Close()
+		return nil, fmt.Errorf("compacting journal: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Close closes the underlying journal file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.journal.Close()
+}
+
+// Create inserts a new refresh token into the store.
+func (s *Store) Create(ctx context.Context, rt session.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.journal.Append(opPut, rt.ID, record{RefreshToken: rt, Hash: rt.Hash}); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Create(ctx, rt)
+}
+
+// Update replaces a refresh token in the store.
+func (s *Store) Update(ctx context.Context, rt session.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByID(ctx, rt.ID); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opPut, rt.ID, record{RefreshToken: rt, Hash: rt.Hash}); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Update(ctx, rt)
+}
+
+// DeleteExpired removes every refresh token which has expired at the given time from the store.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int
+	for _, rt := range s.mem.All() {
+		if now.Before(rt.ExpiresAt) {
+			continue
+		}
+
+		if err := s.journal.Append(opDelete, rt.ID, nil); err != nil {
+			return deleted, fmt.Errorf("appending to journal: %w", err)
+		}
+		if err := s.mem.Delete(ctx, rt.ID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// QueryByHash gets the refresh token with the given hash from the store.
+func (s *Store) QueryByHash(ctx context.Context, hash string) (session.RefreshToken, error) {
+	return s.mem.QueryByHash(ctx, hash)
+}
+
+// QueryByAccessID gets the refresh token handed out along with the access token identified by the
+// given ID from the store.
+func (s *Store) QueryByAccessID(ctx context.Context, accessID string) (session.RefreshToken, error) {
+	return s.mem.QueryByAccessID(ctx, accessID)
+}
+
+// QueryByFamily gets every refresh token of the specified family from the store.
+func (s *Store) QueryByFamily(ctx context.Context, family string) ([]session.RefreshToken, error) {
+	return s.mem.QueryByFamily(ctx, family)
+}
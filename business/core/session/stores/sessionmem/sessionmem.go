@@ -0,0 +1,143 @@
+// Package sessionmem contains a concurrency-safe, in-memory implementation of the session storer.
+// It is intended for tests and local development since nothing survives a restart.
+package sessionmem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/session"
+)
+
+// Store manages the set of APIs for refresh token access held in memory.
+type Store struct {
+	mu     sync.RWMutex
+	tokens map[string]session.RefreshToken
+}
+
+// NewStore constructs an empty in-memory store for refresh tokens.
+func NewStore() *Store {
+	return &Store{
+		tokens: make(map[string]session.RefreshToken),
+	}
+}
+
+// All returns every refresh token in the store, for taking a snapshot of it.
+func (s *Store) All() []session.RefreshToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := make([]session.RefreshToken, 0, len(s.tokens))
+	for _, rt := range s.tokens {
+		tokens = append(tokens, rt)
+	}
+
+	return tokens
+}
+
+// Create inserts a new refresh token into the store.
+func (s *Store) Create(ctx context.Context, rt session.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[rt.ID] = rt
+
+	return nil
+}
+
+// Update replaces a refresh token in the store.
+func (s *Store) Update(ctx context.Context, rt session.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tokens[rt.ID]; !exists {
+		return session.ErrNotFound
+	}
+	s.tokens[rt.ID] = rt
+
+	return nil
+}
+
+// Delete removes the refresh token identified by the given ID from the store, if there is one.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, id)
+
+	return nil
+}
+
+// DeleteExpired removes every refresh token which has expired at the given time from the store.
+func (s *Store) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int
+	for id, rt := range s.tokens {
+		if !now.Before(rt.ExpiresAt) {
+			delete(s.tokens, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// QueryByID gets the refresh token identified by the given ID from the store.
+func (s *Store) QueryByID(ctx context.Context, id string) (session.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rt, exists := s.tokens[id]
+	if !exists {
+		return session.RefreshToken{}, session.ErrNotFound
+	}
+
+	return rt, nil
+}
+
+// QueryByHash gets the refresh token with the given hash from the store.
+func (s *Store) QueryByHash(ctx context.Context, hash string) (session.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, rt := range s.tokens {
+		if rt.Hash == hash {
+			return rt, nil
+		}
+	}
+
+	return session.RefreshToken{}, session.ErrNotFound
+}
+
+// QueryByAccessID gets the refresh token handed out along with the access token identified by the
+// given ID from the store.
+func (s *Store) QueryByAccessID(ctx context.Context, accessID string) (session.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, rt := range s.tokens {
+		if rt.AccessID == accessID {
+			return rt, nil
+		}
+	}
+
+	return session.RefreshToken{}, session.ErrNotFound
+}
+
+// QueryByFamily gets every refresh token of the specified family from the store.
+func (s *Store) QueryByFamily(ctx context.Context, family string) ([]session.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := []session.RefreshToken{}
+	for _, rt := range s.tokens {
+		if rt.Family == family {
+			tokens = append(tokens, rt)
+		}
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,125 @@
+package user
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+	"github.com/yashshah7197/shrt/business/sys/workspace"
+)
+
+// The bounds on the name of a user and on the length of a password. Passwords are hashed with
+// bcrypt, which only looks at the first 72 bytes.
+const (
+	nameMaxLength     = 100
+	emailMaxLength    = 254
+	passwordMinLength = 8
+	passwordMaxLength = 72
+)
+
+// User represents a member of a workspace who signs in with an email address and a password. Every
+// user belongs to exactly one workspace, in which they hold a single role. Only the hash of the
+// password is ever kept, and it never leaves the service. An account which fails to sign in too
+// many times in a row is locked for a while.
+type User struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	Workspace    string     `json:"workspace"`
+	Role         string     `json:"role"`
+	PasswordHash []byte     `json:"-"`
+	FailedLogins int        `json:"-"`
+	LockedUntil  *time.Time `json:"-"`
+	DateCreated  time.Time  `json:"date_created"`
+	DateUpdated  time.Time  `json:"date_updated"`
+}
+
+// Locked reports whether the account is locked at the given time.
+func (u User) Locked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// NewSignup contains the information needed to sign up. Signing up starts a new workspace, with
+// the new user as its owner.
+type NewSignup struct {
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	Workspace string `json:"workspace"`
+}
+
+// Validate checks that the information for signing up is valid.
+func (ns NewSignup) Validate() error {
+	var fields validate.FieldErrors
+
+	validateAccount(&fields, ns.Name, ns.Email, ns.Password)
+
+	if err := workspace.Validate(ns.Workspace); err != nil {
+		fields.Add("workspace", err.Error())
+	}
+
+	return fields.Err()
+}
+
+// NewUser contains the information needed to add a member to a workspace.
+type NewUser struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// Validate checks that the information for a new user is valid.
+func (nu NewUser) Validate() error {
+	var fields validate.FieldErrors
+
+	validateAccount(&fields, nu.Name, nu.Email, nu.Password)
+
+	if !auth.IsRole(nu.Role) {
+		fields.Add("role", fmt.Sprintf("must be one of %s, %s or %s", auth.RoleOwner, auth.RoleEditor, auth.RoleViewer))
+	}
+
+	return fields.Err()
+}
+
+// Login contains the credentials a user signs in with.
+type Login struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// validateAccount checks the details every account is made up of.
+func validateAccount(fields *validate.FieldErrors, name string, email string, password string) {
+	switch {
+	case strings.TrimSpace(name) == "":
+		fields.Add("name", "must not be blank")
+	case len(name) > nameMaxLength:
+		fields.Add("name", fmt.Sprintf("must be at most %d characters long", nameMaxLength))
+	}
+
+	if !isEmail(email) {
+		fields.Add("email", "must be a valid email address")
+	}
+
+	if len(password) < passwordMinLength || len(password) > passwordMaxLength {
+		fields.Add("password", fmt.Sprintf("must be between %d and %d characters long", passwordMinLength, passwordMaxLength))
+	}
+}
+
+// isEmail reports whether the string is a bare email address, without a display name.
+func isEmail(s string) bool {
+	if len(s) > emailMaxLength {
+		return false
+	}
+
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// normalizeEmail returns the form email addresses are compared and stored in.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,310 @@
+// Package userdb contains the database/sql implementation of the user storer.
+package userdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/user"
+	"github.com/yashshah7197/shrt/business/sys/database"
+)
+
+// columns lists the user columns in the order scanUser reads them.
+const columns = `
+		user_id, name, email, workspace, role, password_hash, failed_logins, locked_until,
+		date_created, date_updated`
+
+// Store manages the set of APIs for user access in the database.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore constructs a store for users backed by the given database.
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// Create inserts a new user into the database.
+func (s *Store) Create(ctx context.Context, u user.User) error {
+	return insertUser(ctx, s.db, u)
+}
+
+// CreateOwner inserts a new user into the database as the first member of their workspace. The
+// workspace is claimed in the same transaction, and the primary key of the workspaces table keeps
+// two users from claiming the same one.
+func (s *Store) CreateOwner(ctx context.Context, u user.User) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const q = `
+	INSERT INTO workspaces
+		(workspace, created_by, date_created)
+	VALUES
+		($1, $2, $3)`
+
+	if _, err := tx.ExecContext(ctx, q, u.Workspace, u.ID, u.DateCreated.UTC()); err != nil {
+		if database.IsDuplicatedEntry(err) {
+			return user.ErrWorkspaceTaken
+		}
+		return fmt.Errorf("inserting workspace: %w", err)
+	}
+
+	if err := insertUser(ctx, tx, u); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a user in the database.
+func (s *Store) Update(ctx context.Context, u user.User) error {
+	const q = `
+	UPDATE
+		users
+	SET
+		name = $2,
+		email = $3,
+		role = $4,
+		password_hash = $5,
+		failed_logins = $6,
+		locked_until = $7,
+		date_updated = $8
+	WHERE
+		user_id = $1`
+
+	res, err := s.db.ExecContext(ctx, q,
+		u.ID,
+		u.Name,
+		u.Email,
+		u.Role,
+		string(u.PasswordHash),
+		u.FailedLogins,
+		nullTime(u.LockedUntil),
+		u.DateUpdated.UTC(),
+	)
+	if err != nil {
+		if database.IsDuplicatedEntry(err) {
+			return user.ErrEmailTaken
+		}
+		return fmt.Errorf("updating user[%s]: %w", u.ID, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking affected rows: %w", err)
+	}
+	if n == 0 {
+		return user.ErrNotFound
+	}
+
+	return nil
+}
+
+// AddFailedLogin counts a failed sign in against the user and returns them as they now stand.
+func (s *Store) AddFailedLogin(ctx context.Context, id string) (user.User, error) {
+	const q = `
+	UPDATE
+		users
+	SET
+		failed_logins = failed_logins + 1
+	WHERE
+		user_id = $1
+	RETURNING` + columns
+
+	u, err := scanUser(s.db.QueryRowContext(ctx, q, id))
+	if err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.User{}, user.ErrNotFound
+		}
+		return user.User{}, fmt.Errorf("counting failed login for user[%s]: %w", id, err)
+	}
+
+	return u, nil
+}
+
+// SetLockout locks the user until the given time, or unlocks them if it is nil, and clears their
+// failed sign ins.
+func (s *Store) SetLockout(ctx context.Context, id string, lockedUntil *time.Time) error {
+	const q = `
+	UPDATE
+		users
+	SET
+		failed_logins = 0,
+		locked_until = $2
+	WHERE
+		user_id = $1`
+
+	res, err := s.db.ExecContext(ctx, q, id, nullTime(lockedUntil))
+	if err != nil {
+		return fmt.Errorf("setting lockout for user[%s]: %w", id, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking affected rows: %w", err)
+	}
+	if n == 0 {
+		return user.ErrNotFound
+	}
+
+	return nil
+}
+
+// QueryByID gets the user identified by the given ID from the database.
+func (s *Store) QueryByID(ctx context.Context, id string) (user.User, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		users
+	WHERE
+		user_id = $1`
+
+	u, err := scanUser(s.db.QueryRowContext(ctx, q, id))
+	if err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.User{}, user.ErrNotFound
+		}
+		return user.User{}, fmt.Errorf("selecting user[%s]: %w", id, err)
+	}
+
+	return u, nil
+}
+
+// QueryByEmail gets the user with the given email address from the database.
+func (s *Store) QueryByEmail(ctx context.Context, email string) (user.User, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		users
+	WHERE
+		email = $1`
+
+	u, err := scanUser(s.db.QueryRowContext(ctx, q, email))
+	if err != nil {
+		if errors.Is(err, database.ErrDBNotFound) {
+			return user.User{}, user.ErrNotFound
+		}
+		return user.User{}, fmt.Errorf("selecting user by email: %w", err)
+	}
+
+	return u, nil
+}
+
+// QueryByWorkspace gets every member of the specified workspace from the database.
+func (s *Store) QueryByWorkspace(ctx context.Context, workspace string) ([]user.User, error) {
+	const q = `
+	SELECT` + columns + `
+	FROM
+		users
+	WHERE
+		workspace = $1`
+
+	rows, err := s.db.QueryContext(ctx, q, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("selecting users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []user.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning user: %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+// execer is implemented by both sql.DB and sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertUser inserts a new user through the given database or transaction.
+func insertUser(ctx context.Context, db execer, u user.User) error {
+	const q = `
+	INSERT INTO users (` + columns + `)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	if _, err := db.ExecContext(ctx, q,
+		u.ID,
+		u.Name,
+		u.Email,
+		u.Workspace,
+		u.Role,
+		string(u.PasswordHash),
+		u.FailedLogins,
+		nullTime(u.LockedUntil),
+		u.DateCreated.UTC(),
+		u.DateUpdated.UTC(),
+	); err != nil {
+		if database.IsDuplicatedEntry(err) {
+			return user.ErrEmailTaken
+		}
+		return fmt.Errorf("inserting user: %w", err)
+	}
+
+	return nil
+}
+
+// scanner is implemented by both sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser reads a single user out of a row.
+func scanUser(row scanner) (user.User, error) {
+	var (
+		u           user.User
+		hash        string
+		lockedUntil sql.NullTime
+	)
+	if err := row.Scan(
+		&u.ID,
+		&u.Name,
+		&u.Email,
+		&u.Workspace,
+		&u.Role,
+		&hash,
+		&u.FailedLogins,
+		&lockedUntil,
+		&u.DateCreated,
+		&u.DateUpdated,
+	); err != nil {
+		return user.User{}, err
+	}
+	u.PasswordHash = []byte(hash)
+	if lockedUntil.Valid {
+		u.LockedUntil = &lockedUntil.Time
+	}
+
+	return u, nil
+}
+
+// nullTime converts an optional time into its database form.
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
@@ -0,0 +1,235 @@
+// Package userfile contains a durable, single-file implementation of the user storer. Every change
+// is appended to a journal on disk and the users are kept in memory for reads.
+package userfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/user"
+	"github.com/yashshah7197/shrt/business/core/user/stores/usermem"
+	"github.com/yashshah7197/shrt/foundation/journal"
+)
+
+// The set of operations recorded in the journal.
+const (
+	opPut = "put"
+)
+
+// record is the form in which a user is written to the journal. The password hash and the state of
+// the lockout are left out of the JSON form of a user so that they never reach API clients, so they
+// are carried alongside it here.
+type record struct {
+	user.User
+	PasswordHash []byte     `json:"password_hash"`
+	FailedLogins int        `json:"failed_logins,omitempty"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+}
+
+// newRecord constructs the journal record for the given user.
+func newRecord(u user.User) record {
+	return record{
+		User:         u,
+		PasswordHash: u.PasswordHash,
+		FailedLogins: u.FailedLogins,
+		LockedUntil:  u.LockedUntil,
+	}
+}
+
+// Store manages the set of APIs for user access backed by a journal file.
+type Store struct {
+	mu      sync.Mutex
+	mem     *usermem.Store
+	journal *journal.Journal
+}
+
+// Open constructs a store for users by replaying the journal at the given path.
+func Open(path string) (*Store, error) {
+	ctx := context.Background()
+	mem := usermem.NewStore()
+
+	// Rebuild the in-memory state from the journal records.
+	replay := func(rec journal.Record) error {
+		switch rec.Op {
+		case opPut:
+			var r record
+			if err := json.Unmarshal(rec.Data, &r); err != nil {
+				return err
+			}
+			u := r.User
+			u.PasswordHash = r.PasswordHash
+			u.FailedLogins = r.FailedLogins
+			u.LockedUntil = r.LockedUntil
+
+			// Records hold the full state of a user, so replace whatever was there before.
+			if err := mem.Update(ctx, u); err == nil {
+				return nil
+			}
+			return mem.Create(ctx, u)
+
+		default:
+			return fmt.Errorf("unknown operation %q", rec.Op)
+		}
+	}
+
+	jrnl, err := journal.Open(path, replay)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+
+	s := Store{
+		mem:     mem,
+		journal: jrnl,
+	}
+
+	// Every failed login is recorded, so start off with a compact journal every time.
+	snapshot := func(emit func(op string, key string, data interface{}) error) error {
+		for _, u := range mem.All() {
+			if err := emit(opPut, u.ID, newRecord(u)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := jrnl.Compact(snapshot); err != nil {
+		jrnl.Close()
+		return nil, fmt.Errorf("compacting journal: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Close closes the underlying journal file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.journal.Close()
+}
+
+// Create inserts a new user into the store.
+func (s *Store) Create(ctx context.Context, u user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkEmail(ctx, u); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opPut, u.ID, newRecord(u)); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Create(ctx, u)
+}
+
+// CreateOwner inserts a new user into the store as the first member of their workspace.
+func (s *Store) CreateOwner(ctx context.Context, u user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, err := s.mem.QueryByWorkspace(ctx, u.Workspace)
+	if err != nil {
+		return err
+	}
+	if len(members) > 0 {
+		return user.ErrWorkspaceTaken
+	}
+	if err := s.checkEmail(ctx, u); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opPut, u.ID, newRecord(u)); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.CreateOwner(ctx, u)
+}
+
+// Update replaces a user in the store.
+func (s *Store) Update(ctx context.Context, u user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.mem.QueryByID(ctx, u.ID); err != nil {
+		return err
+	}
+	if err := s.checkEmail(ctx, u); err != nil {
+		return err
+	}
+
+	if err := s.journal.Append(opPut, u.ID, newRecord(u)); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.Update(ctx, u)
+}
+
+// AddFailedLogin counts a failed sign in against the user and returns them as they now stand.
+func (s *Store) AddFailedLogin(ctx context.Context, id string) (user.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, err := s.mem.QueryByID(ctx, id)
+	if err != nil {
+		return user.User{}, err
+	}
+	u.FailedLogins++
+
+	if err := s.journal.Append(opPut, u.ID, newRecord(u)); err != nil {
+		return user.User{}, fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.AddFailedLogin(ctx, id)
+}
+
+// SetLockout locks the user until the given time, or unlocks them if it is nil, and clears their
+// failed sign ins.
+func (s *Store) SetLockout(ctx context.Context, id string, lockedUntil *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, err := s.mem.QueryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	u.FailedLogins = 0
+	u.LockedUntil = lockedUntil
+
+	if err := s.journal.Append(opPut, u.ID, newRecord(u)); err != nil {
+		return fmt.Errorf("appending to journal: %w", err)
+	}
+
+	return s.mem.SetLockout(ctx, id, lockedUntil)
+}
+
+// QueryByID gets the user identified by the given ID from the store.
+func (s *Store) QueryByID(ctx context.Context, id string) (user.User, error) {
+	return s.mem.QueryByID(ctx, id)
+}
+
+// QueryByEmail gets the user with the given email address from the store.
+func (s *Store) QueryByEmail(ctx context.Context, email string) (user.User, error) {
+	return s.mem.QueryByEmail(ctx, email)
+}
+
+// QueryByWorkspace gets every member of the specified workspace.
+func (s *Store) QueryByWorkspace(ctx context.Context, workspace string) ([]user.User, error) {
+	return s.mem.QueryByWorkspace(ctx, workspace)
+}
+
+// checkEmail makes sure no other user has the email address of the given user before it reaches
+// the journal, so the journal never holds a change the in-memory store would turn down. The caller
+// must hold the store lock.
+func (s *Store) checkEmail(ctx context.Context, u user.User) error {
+	other, err := s.mem.QueryByEmail(ctx, u.Email)
+	if err == nil && other.ID != u.ID {
+		return user.ErrEmailTaken
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+// Package usermem contains a concurrency-safe, in-memory implementation of the user storer. It is
+// intended for tests and local development since nothing survives a restart.
+package usermem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yashshah7197/shrt/business/core/user"
+)
+
+// Store manages the set of APIs for user access held in memory.
+type Store struct {
+	mu    sync.RWMutex
+	users map[string]user.User
+}
+
+// NewStore constructs an empty in-memory store for users.
+func NewStore() *Store {
+	return &Store{
+		users: make(map[string]user.User),
+	}
+}
+
+// Create inserts a new user into the store.
+func (s *Store) Create(ctx context.Context, u user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(u) {
+		return user.ErrEmailTaken
+	}
+	s.users[u.ID] = u
+
+	return nil
+}
+
+// CreateOwner inserts a new user into the store as the first member of their workspace.
+func (s *Store) CreateOwner(ctx context.Context, u user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.workspaceTaken(u.Workspace) {
+		return user.ErrWorkspaceTaken
+	}
+	if s.emailTaken(u) {
+		return user.ErrEmailTaken
+	}
+	s.users[u.ID] = u
+
+	return nil
+}
+
+// Update replaces a user in the store.
+func (s *Store) Update(ctx context.Context, u user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[u.ID]; !exists {
+		return user.ErrNotFound
+	}
+	if s.emailTaken(u) {
+		return user.ErrEmailTaken
+	}
+	s.users[u.ID] = u
+
+	return nil
+}
+
+// AddFailedLogin counts a failed sign in against the user and returns them as they now stand.
+func (s *Store) AddFailedLogin(ctx context.Context, id string) (user.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, exists := s.users[id]
+	if !exists {
+		return user.User{}, user.ErrNotFound
+	}
+	u.FailedLogins++
+	s.users[id] = u
+
+	return u, nil
+}
+
+// SetLockout locks the user until the given time, or unlocks them if it is nil, and clears their
+// failed sign ins.
+func (s *Store) SetLockout(ctx context.Context, id string, lockedUntil *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, exists := s.users[id]
+	if !exists {
+		return user.ErrNotFound
+	}
+	u.FailedLogins = 0
+	u.LockedUntil = lockedUntil
+	s.users[id] = u
+
+	return nil
+}
+
+// QueryByID gets the user identified by the given ID from the store.
+func (s *Store) QueryByID(ctx context.Context, id string) (user.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, exists := s.users[id]
+	if !exists {
+		return user.User{}, user.ErrNotFound
+	}
+
+	return u, nil
+}
+
+// QueryByEmail gets the user with the given email address from the store.
+func (s *Store) QueryByEmail(ctx context.Context, email string) (user.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+
+	return user.User{}, user.ErrNotFound
+}
+
+// QueryByWorkspace gets every member of the specified workspace.
+func (s *Store) QueryByWorkspace(ctx context.Context, workspace string) ([]user.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := []user.User{}
+	for _, u := range s.users {
+		if u.Workspace == workspace {
+			users = append(users, u)
+		}
+	}
+
+	return users, nil
+}
+
+// All returns every user held in the store.
+func (s *Store) All() []user.User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]user.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+
+	return users
+}
+
+// emailTaken reports whether another user already has the email address of the given user. The
+// caller must hold the lock.
+func (s *Store) emailTaken(u user.User) bool {
+	for _, other := range s.users {
+		if other.ID != u.ID && other.Email == u.Email {
+			return true
+		}
+	}
+
+	return false
+}
+
+// workspaceTaken reports whether any user belongs to the workspace already. Users are never
+// removed, so a workspace stays taken once it has had a member. The caller must hold the lock.
+func (s *Store) workspaceTaken(workspace string) bool {
+	for _, u := range s.users {
+		if u.Workspace == workspace {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,254 @@
+// Package user provides the core business API for the accounts people sign in to the service
+// with. Signing up starts a new workspace with the new user as its owner, and owners add the other
+// members of their workspace themselves. Accounts are locked for a while after too many failed
+// attempts at signing in, no matter where the attempts come from.
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/business/sys/validate"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound       = errors.New("user not found")
+	ErrEmailTaken     = errors.New("email is already taken")
+	ErrWorkspaceTaken = errors.New("workspace is already taken")
+)
+
+// Set of error variables for signing in.
+var (
+	ErrAuthenticationFailure = errors.New("authentication failed")
+	ErrLocked                = errors.New("account is locked, please try again later")
+)
+
+// Storer defines the behavior required to persist and retrieve users. Implementations must be safe
+// for concurrent use, and must return ErrEmailTaken when two users would share an email address.
+// CreateOwner claims the workspace of the user along with storing them, and must return
+// ErrWorkspaceTaken when the workspace was ever claimed or used before, however many users race
+// for it. AddFailedLogin counts a sign in attempt against the user in a single step and returns the
+// user as they stand afterwards, so that attempts made in parallel are all counted.
+type Storer interface {
+	Create(ctx context.Context, u User) error
+	CreateOwner(ctx context.Context, u User) error
+	Update(ctx context.Context, u User) error
+	AddFailedLogin(ctx context.Context, id string) (User, error)
+	SetLockout(ctx context.Context, id string, lockedUntil *time.Time) error
+	QueryByID(ctx context.Context, id string) (User, error)
+	QueryByEmail(ctx context.Context, email string) (User, error)
+	QueryByWorkspace(ctx context.Context, workspace string) ([]User, error)
+}
+
+// Config represents the dependencies and settings required by the Core. An account is locked for
+// the lockout period once it fails to sign in the maximum number of times in a row. The reserved
+// workspaces can't be signed up for, such as the workspace of the operator and the default
+// workspace which holds the links from before there were workspaces.
+type Config struct {
+	Storer      Storer
+	MaxFailures int
+	Lockout     time.Duration
+	Reserved    []string
+}
+
+// Core manages the set of APIs for user access.
+type Core struct {
+	storer      Storer
+	maxFailures int
+	lockout     time.Duration
+	reserved    map[string]bool
+
+	// dummyHash is compared against when signing in to an unknown account, so that it takes as
+	// long as signing in to a known one and the two can't be told apart by timing.
+	dummyHash []byte
+}
+
+// NewCore constructs a Core for user API access.
+func NewCore(cfg Config) (*Core, error) {
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("generating dummy hash: %w", err)
+	}
+
+	c := Core{
+		storer:      cfg.Storer,
+		maxFailures: cfg.MaxFailures,
+		lockout:     cfg.Lockout,
+		reserved:    make(map[string]bool, len(cfg.Reserved)),
+		dummyHash:   dummyHash,
+	}
+	for _, ws := range cfg.Reserved {
+		c.reserved[ws] = true
+	}
+
+	return &c, nil
+}
+
+// Signup creates a new user as the owner of a new workspace. Workspaces which already have members
+// can't be signed up to; their owners add new members instead. Neither can reserved workspaces.
+func (c *Core) Signup(ctx context.Context, ns NewSignup, now time.Time) (User, error) {
+	if err := ns.Validate(); err != nil {
+		return User{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	if c.reserved[ns.Workspace] {
+		return User{}, fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "workspace", Error: "is reserved"}})
+	}
+
+	u, err := newUser(ns.Name, ns.Email, ns.Password, ns.Workspace, auth.RoleOwner, now)
+	if err != nil {
+		return User{}, err
+	}
+
+	if err := c.storer.CreateOwner(ctx, u); err != nil {
+		return User{}, createError(err)
+	}
+
+	return u, nil
+}
+
+// Create adds a new member to the specified workspace.
+func (c *Core) Create(ctx context.Context, nu NewUser, workspace string, now time.Time) (User, error) {
+	if err := nu.Validate(); err != nil {
+		return User{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	u, err := newUser(nu.Name, nu.Email, nu.Password, workspace, nu.Role, now)
+	if err != nil {
+		return User{}, err
+	}
+
+	if err := c.storer.Create(ctx, u); err != nil {
+		return User{}, createError(err)
+	}
+
+	return u, nil
+}
+
+// Authenticate finds the user with the given email address and checks their password. Every
+// failure counts towards locking the account, and a locked account can't sign in even with the
+// right password until the lockout has passed. The attempt is counted before the password is
+// checked, so that no more than the maximum number of passwords can be tried at once however many
+// requests are made in parallel.
+func (c *Core) Authenticate(ctx context.Context, login Login, now time.Time) (User, error) {
+	u, err := c.storer.QueryByEmail(ctx, normalizeEmail(login.Email))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			bcrypt.CompareHashAndPassword(c.dummyHash, []byte(login.Password))
+			return User{}, ErrAuthenticationFailure
+		}
+		return User{}, fmt.Errorf("query: %w", err)
+	}
+
+	if u.Locked(now) {
+		return User{}, ErrLocked
+	}
+
+	u, err = c.storer.AddFailedLogin(ctx, u.ID)
+	if err != nil {
+		return User{}, fmt.Errorf("add failed login: %w", err)
+	}
+
+	switch {
+	case u.Locked(now):
+		return User{}, ErrLocked
+	case u.FailedLogins > c.maxFailures:
+		if err := c.lock(ctx, u, now); err != nil {
+			return User{}, err
+		}
+		return User{}, ErrLocked
+	}
+
+	if err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(login.Password)); err != nil {
+		if u.FailedLogins >= c.maxFailures {
+			if err := c.lock(ctx, u, now); err != nil {
+				return User{}, err
+			}
+		}
+		return User{}, ErrAuthenticationFailure
+	}
+
+	if err := c.storer.SetLockout(ctx, u.ID, nil); err != nil {
+		return User{}, fmt.Errorf("set lockout: %w", err)
+	}
+	u.FailedLogins = 0
+	u.LockedUntil = nil
+
+	return u, nil
+}
+
+// QueryByID gets the user identified by the given ID.
+func (c *Core) QueryByID(ctx context.Context, id string) (User, error) {
+	u, err := c.storer.QueryByID(ctx, id)
+	if err != nil {
+		return User{}, fmt.Errorf("query: %w", err)
+	}
+
+	return u, nil
+}
+
+// QueryByWorkspace gets every member of the specified workspace, ordered by email address.
+func (c *Core) QueryByWorkspace(ctx context.Context, workspace string) ([]User, error) {
+	users, err := c.storer.QueryByWorkspace(ctx, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Email < users[j].Email
+	})
+
+	return users, nil
+}
+
+// lock keeps the user from signing in for the lockout period and starts counting their failures
+// afresh.
+func (c *Core) lock(ctx context.Context, u User, now time.Time) error {
+	lockedUntil := now.Add(c.lockout)
+	if err := c.storer.SetLockout(ctx, u.ID, &lockedUntil); err != nil {
+		return fmt.Errorf("set lockout: %w", err)
+	}
+
+	return nil
+}
+
+// newUser hashes the password of a new user and constructs them.
+func newUser(name string, email string, password string, workspace string, role string, now time.Time) (User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, fmt.Errorf("generating password hash: %w", err)
+	}
+
+	u := User{
+		ID:           uuid.NewString(),
+		Name:         name,
+		Email:        normalizeEmail(email),
+		Workspace:    workspace,
+		Role:         role,
+		PasswordHash: hash,
+		DateCreated:  now,
+		DateUpdated:  now,
+	}
+
+	return u, nil
+}
+
+// createError translates a failure to store a new user into the field it is about, if any.
+func createError(err error) error {
+	switch {
+	case errors.Is(err, ErrEmailTaken):
+		return fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "email", Error: "is already taken"}})
+	case errors.Is(err, ErrWorkspaceTaken):
+		return fmt.Errorf("validating data: %w", validate.FieldErrors{{Field: "workspace", Error: "is already taken"}})
+	}
+
+	return fmt.Errorf("create: %w", err)
+}
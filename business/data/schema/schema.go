@@ -0,0 +1,179 @@
+// Package schema contains the database schema, migrations and seeding data.
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationsFS holds the migration scripts. Each script is named <version>_<description>.sql and
+// versions must be consecutive, starting at 1.
+//
+//go:embed sql/*.sql
+var migrationsFS embed.FS
+
+// lockID is the postgres advisory lock used to keep concurrent migrations from interleaving.
+const lockID = 7197
+
+// Migration represents a single versioned change to the database schema.
+type Migration struct {
+	Version     int
+	Description string
+	Script      string
+}
+
+// Migrations returns the full set of embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationsFS, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+
+		parts := strings.SplitN(name, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("migration %q: name must be <version>_<description>.sql", entry.Name())
+		}
+
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("migration %q: parsing version: %w", entry.Name(), err)
+		}
+
+		script, err := fs.ReadFile(migrationsFS, path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("migration %q: reading script: %w", entry.Name(), err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version:     version,
+			Description: strings.ReplaceAll(parts[1], "_", " "),
+			Script:      string(script),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	// Make sure there are no gaps or duplicates in the versions.
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be consecutive from 1: found %d at position %d", m.Version, i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Latest returns the version of the newest embedded migration.
+func Latest() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(migrations), nil
+}
+
+// Version returns the version of the newest migration applied to the database. A database which
+// has never been migrated is at version 0.
+func Version(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	const qExists = `SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'schema_migrations')`
+	if err := db.QueryRowContext(ctx, qExists).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("checking for migrations table: %w", err)
+	}
+
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	const q = `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+	if err := db.QueryRowContext(ctx, q).Scan(&version); err != nil {
+		return 0, fmt.Errorf("querying schema version: %w", err)
+	}
+
+	return version, nil
+}
+
+// Migrate attempts to bring the schema for the database up to date with the embedded migrations.
+// Each migration runs in its own transaction together with the record of it being applied, so it
+// is safe to run Migrate any number of times.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	// Hold a single connection for the duration so the advisory lock stays with us.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	const qTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INT PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at  TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc')
+	)`
+	if _, err := conn.ExecContext(ctx, qTable); err != nil {
+		return fmt.Errorf("creating migrations table: %w", err)
+	}
+
+	var current int
+	const qVersion = `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+	if err := conn.QueryRowContext(ctx, qVersion).Scan(&current); err != nil {
+		return fmt.Errorf("querying schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+
+		if err := apply(ctx, conn, m); err != nil {
+			return fmt.Errorf("applying migration %d %q: %w", m.Version, m.Description, err)
+		}
+	}
+
+	return nil
+}
+
+// apply runs a single migration and records it as applied within one transaction.
+func apply(ctx context.Context, conn *sql.Conn, m Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.Script); err != nil {
+		return fmt.Errorf("executing script: %w", err)
+	}
+
+	const q = `INSERT INTO schema_migrations (version, description) VALUES ($1, $2)`
+	if _, err := tx.ExecContext(ctx, q, m.Version, m.Description); err != nil {
+		return fmt.Errorf("recording version: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS links (
+	code            TEXT PRIMARY KEY,
+	destination     TEXT NOT NULL,
+	owner           TEXT NOT NULL,
+	redirect_status INT NOT NULL DEFAULT 0,
+	date_created    TIMESTAMP NOT NULL,
+	date_updated    TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS links_owner_idx ON links (owner, date_created DESC);
+
+CREATE TABLE IF NOT EXISTS users (
+	user_id       UUID PRIMARY KEY,
+	name          TEXT NOT NULL,
+	email         TEXT UNIQUE NOT NULL,
+	roles         TEXT[] NOT NULL,
+	password_hash TEXT NOT NULL,
+	date_created  TIMESTAMP NOT NULL,
+	date_updated  TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS click_events (
+	click_id    BIGSERIAL PRIMARY KEY,
+	code        TEXT NOT NULL,
+	occurred_at TIMESTAMP NOT NULL,
+	referrer    TEXT NOT NULL DEFAULT '',
+	user_agent  TEXT NOT NULL DEFAULT '',
+	client_ip   TEXT NOT NULL DEFAULT '',
+	trace_id    TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS click_events_code_idx ON click_events (code, occurred_at);
@@ -0,0 +1 @@
+CREATE SEQUENCE IF NOT EXISTS link_code_seq START WITH 1;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS reserved_words (
+	word         TEXT PRIMARY KEY,
+	kind         TEXT NOT NULL,
+	created_by   TEXT NOT NULL DEFAULT '',
+	date_created TIMESTAMP NOT NULL
+);
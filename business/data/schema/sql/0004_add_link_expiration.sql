@@ -0,0 +1,8 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS activates_at TIMESTAMP NULL;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP NULL;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS max_clicks INT NOT NULL DEFAULT 0;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS clicks INT NOT NULL DEFAULT 0;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS fallback_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE links ADD COLUMN IF NOT EXISTS date_archived TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS links_expiry_idx ON links (expires_at) WHERE date_archived IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS password_hash BYTEA NULL;
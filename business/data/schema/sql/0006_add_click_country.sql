@@ -0,0 +1 @@
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS country TEXT NOT NULL DEFAULT '';
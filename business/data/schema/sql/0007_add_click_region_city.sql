@@ -0,0 +1,2 @@
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS region TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS city TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,30 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+ALTER TABLE links ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS link_revisions (
+	code         TEXT NOT NULL REFERENCES links (code) ON DELETE CASCADE,
+	number       INT NOT NULL,
+	author       TEXT NOT NULL,
+	changes      TEXT[] NOT NULL,
+	rollback_of  INT NOT NULL DEFAULT 0,
+	settings     JSONB NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	PRIMARY KEY (code, number)
+);
+
+-- Start the history of every existing link with a revision recording its creation.
+INSERT INTO link_revisions (code, number, author, changes, settings, date_created)
+SELECT
+	code, 1, owner, '{created}',
+	jsonb_strip_nulls(jsonb_build_object(
+		'destination', destination,
+		'redirect_status', redirect_status,
+		'activates_at', to_char(activates_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+		'expires_at', to_char(expires_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+		'max_clicks', max_clicks,
+		'fallback_url', fallback_url
+	)),
+	date_created
+FROM
+	links
+ON CONFLICT DO NOTHING;
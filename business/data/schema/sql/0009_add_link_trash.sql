@@ -0,0 +1,3 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS date_deleted TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS links_trash_idx ON links (date_deleted) WHERE date_deleted IS NOT NULL;
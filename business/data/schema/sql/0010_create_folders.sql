@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS folders (
+	folder_id    UUID PRIMARY KEY,
+	owner        TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	parent_id    TEXT NOT NULL DEFAULT '',
+	date_created TIMESTAMP NOT NULL,
+	date_updated TIMESTAMP NOT NULL,
+	UNIQUE (owner, parent_id, name)
+);
+
+ALTER TABLE links ADD COLUMN IF NOT EXISTS folder_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS links_folder_idx ON links (owner, folder_id);
+CREATE INDEX IF NOT EXISTS links_tags_idx ON links USING GIN (tags);
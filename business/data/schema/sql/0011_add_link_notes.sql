@@ -0,0 +1 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS blocked_domains (
+	pattern      TEXT PRIMARY KEY,
+	reason       TEXT NOT NULL DEFAULT '',
+	created_by   TEXT NOT NULL DEFAULT '',
+	date_created TIMESTAMP NOT NULL
+);
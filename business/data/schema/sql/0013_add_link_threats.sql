@@ -0,0 +1,2 @@
+ALTER TABLE links ADD COLUMN IF NOT EXISTS threats TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE links ADD COLUMN IF NOT EXISTS date_flagged TIMESTAMP NULL;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS domains (
+	host            TEXT PRIMARY KEY,
+	redirect_status INT NOT NULL DEFAULT 0,
+	not_found_url   TEXT NOT NULL DEFAULT '',
+	owners          TEXT[] NOT NULL DEFAULT '{}',
+	created_by      TEXT NOT NULL DEFAULT '',
+	date_created    TIMESTAMP NOT NULL,
+	date_updated    TIMESTAMP NOT NULL
+);
+
+-- Codes are only unique within the host of a link, with a blank host for the default namespace.
+ALTER TABLE links ADD COLUMN IF NOT EXISTS host TEXT NOT NULL DEFAULT '';
+ALTER TABLE link_revisions ADD COLUMN IF NOT EXISTS host TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS host TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE link_revisions DROP CONSTRAINT IF EXISTS link_revisions_code_fkey;
+ALTER TABLE link_revisions DROP CONSTRAINT IF EXISTS link_revisions_pkey;
+ALTER TABLE links DROP CONSTRAINT IF EXISTS links_pkey;
+
+ALTER TABLE links ADD PRIMARY KEY (host, code);
+ALTER TABLE link_revisions ADD PRIMARY KEY (host, code, number);
+ALTER TABLE link_revisions ADD FOREIGN KEY (host, code) REFERENCES links (host, code) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS click_events_code_idx;
+CREATE INDEX IF NOT EXISTS click_events_host_code_idx ON click_events (host, code, occurred_at);
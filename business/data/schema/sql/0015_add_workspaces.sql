@@ -0,0 +1,12 @@
+-- Every link, folder and domain belongs to a workspace. Rows from before workspaces existed belong
+-- to the default one.
+ALTER TABLE links ADD COLUMN IF NOT EXISTS workspace TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS workspace TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE domains ADD COLUMN IF NOT EXISTS workspace TEXT NOT NULL DEFAULT 'default';
+
+-- Folder names are unique within their parent across the whole workspace rather than per owner.
+ALTER TABLE folders DROP CONSTRAINT IF EXISTS folders_owner_parent_id_name_key;
+ALTER TABLE folders ADD CONSTRAINT folders_workspace_parent_id_name_key UNIQUE (workspace, parent_id, name);
+
+CREATE INDEX IF NOT EXISTS links_workspace_idx ON links (workspace, date_created DESC);
+CREATE INDEX IF NOT EXISTS links_workspace_folder_idx ON links (workspace, folder_id);
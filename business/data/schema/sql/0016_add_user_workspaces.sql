@@ -0,0 +1,11 @@
+-- Users belong to a single workspace, in which they hold a single role, instead of holding global
+-- roles. Failed logins are counted on the account so that it can be locked for a while.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS workspace TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'VIEWER';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_logins INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+UPDATE users SET role = 'OWNER' WHERE 'ADMIN' = ANY (roles) OR 'admin' = ANY (roles);
+ALTER TABLE users DROP COLUMN IF EXISTS roles;
+
+CREATE INDEX IF NOT EXISTS users_workspace_idx ON users (workspace);
@@ -0,0 +1,15 @@
+-- Only a hash of every API key is kept, which is what keys are looked up by when they are used.
+CREATE TABLE IF NOT EXISTS api_keys (
+	key_id       UUID PRIMARY KEY,
+	name         TEXT NOT NULL,
+	prefix       TEXT NOT NULL,
+	workspace    TEXT NOT NULL,
+	owner        TEXT NOT NULL,
+	scopes       TEXT[] NOT NULL DEFAULT '{}',
+	hash         TEXT NOT NULL UNIQUE,
+	expires_at   TIMESTAMP,
+	last_used_at TIMESTAMP,
+	date_created TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_workspace_owner_idx ON api_keys (workspace, owner);
@@ -0,0 +1,24 @@
+-- Refresh tokens come in families, one for every time a user signs in. Only a hash of every token
+-- is kept, which is what tokens are looked up by when they are used.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	token_id          UUID PRIMARY KEY,
+	family            UUID NOT NULL,
+	subject           TEXT NOT NULL,
+	access_id         TEXT NOT NULL,
+	access_expires_at TIMESTAMP NOT NULL,
+	hash              TEXT NOT NULL UNIQUE,
+	expires_at        TIMESTAMP NOT NULL,
+	rotated_at        TIMESTAMP,
+	revoked_at        TIMESTAMP,
+	date_created      TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_idx ON refresh_tokens (family);
+CREATE INDEX IF NOT EXISTS refresh_tokens_access_id_idx ON refresh_tokens (access_id);
+
+-- Tokens revoked before they expired. They are dropped once they expire.
+CREATE TABLE IF NOT EXISTS revocations (
+	token_id     TEXT PRIMARY KEY,
+	expires_at   TIMESTAMP NOT NULL,
+	date_revoked TIMESTAMP NOT NULL
+);
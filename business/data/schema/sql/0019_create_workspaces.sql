@@ -0,0 +1,17 @@
+-- Every workspace is claimed once, by the user who signs up for it, and its name is never handed
+-- out again. The workspaces in use so far are claimed up front.
+CREATE TABLE IF NOT EXISTS workspaces (
+	workspace    TEXT PRIMARY KEY,
+	created_by   TEXT NOT NULL DEFAULT '',
+	date_created TIMESTAMP NOT NULL
+);
+
+INSERT INTO workspaces (workspace, date_created)
+SELECT workspace, NOW() AT TIME ZONE 'utc' FROM (
+	SELECT 'default' AS workspace
+	UNION SELECT workspace FROM users
+	UNION SELECT workspace FROM links
+	UNION SELECT workspace FROM folders
+	UNION SELECT workspace FROM domains
+) AS used
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,16 @@
+-- The users and click_events tables were briefly created by 0006 and 0016 instead of 0001, so a
+-- database may have either shape of them. Bring both to the same one: users hold a single role in
+-- their workspace and click events carry their host and location.
+ALTER TABLE users DROP COLUMN IF EXISTS roles;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS workspace TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'VIEWER';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_logins INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+CREATE INDEX IF NOT EXISTS users_workspace_idx ON users (workspace);
+
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS host TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS country TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS region TEXT NOT NULL DEFAULT '';
+ALTER TABLE click_events ADD COLUMN IF NOT EXISTS city TEXT NOT NULL DEFAULT '';
+DROP INDEX IF EXISTS click_events_code_idx;
+CREATE INDEX IF NOT EXISTS click_events_host_code_idx ON click_events (host, code, occurred_at);
@@ -0,0 +1,139 @@
+// Package access provides the signed cookies which let a visitor through the password prompt of a
+// protected short link without being asked again.
+package access
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+
+	"github.com/yashshah7197/shrt/business/sys/auth"
+	"github.com/yashshah7197/shrt/foundation/keystore"
+)
+
+// audience is set on every pass so it can't be mistaken for an API token, or the other way round.
+const audience = "shrt-link-access"
+
+// cookieName is the name of the cookie carrying a pass.
+const cookieName = "shrt_access"
+
+// ErrNoPass is returned when a request doesn't carry a valid pass for a short link.
+var ErrNoPass = errors.New("no valid pass for link")
+
+// Gate issues and checks passes for protected short links. A pass is a JWT signed with the active
+// key of the keystore, scoped to a single code on a single host and carried in a cookie whose path
+// is that code. Passes are checked with the key named in their kid header, just like API tokens.
+type Gate struct {
+	keystore           *keystore.KeyStore
+	signatureAlgorithm jwa.SignatureAlgorithm
+	ttl                time.Duration
+}
+
+// NewGate constructs a Gate which signs passes with the active key and lets them live for the
+// given duration.
+func NewGate(keystore *keystore.KeyStore, ttl time.Duration) (*Gate, error) {
+	// The active key of the keystore is used to sign new passes.
+	if _, _, err := keystore.SigningKey(); err != nil {
+		return nil, fmt.Errorf("looking up signing key: %w", err)
+	}
+
+	g := Gate{
+		keystore:           keystore,
+		signatureAlgorithm: jwa.RS256,
+		ttl:                ttl,
+	}
+
+	return &g, nil
+}
+
+// Issue constructs a cookie carrying a pass for the short link identified by the given host and
+// code.
+func (g *Gate) Issue(r *http.Request, host string, code string, now time.Time) (*http.Cookie, error) {
+	expires := now.Add(g.ttl)
+
+	token, err := jwt.NewBuilder().
+		Audience([]string{audience}).
+		Subject(subject(host, code)).
+		IssuedAt(now).
+		Expiration(expires).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("generating pass: %w", err)
+	}
+
+	keyID, privateKey, err := g.keystore.SigningKey()
+	if err != nil {
+		return nil, fmt.Errorf("fetching signing key: %w", err)
+	}
+
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.KeyIDKey, keyID); err != nil {
+		return nil, fmt.Errorf("setting kid header: %w", err)
+	}
+
+	signed, err := jwt.Sign(token, g.signatureAlgorithm, privateKey, jwt.WithHeaders(headers))
+	if err != nil {
+		return nil, fmt.Errorf("signing pass with private key: %w", err)
+	}
+
+	cookie := http.Cookie{
+		Name:     cookieName,
+		Value:    string(signed),
+		Path:     "/" + code,
+		Expires:  expires,
+		MaxAge:   int(g.ttl.Seconds()),
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	return &cookie, nil
+}
+
+// Check verifies that the request carries a valid pass for the short link identified by the given
+// host and code at the given time.
+func (g *Gate) Check(r *http.Request, host string, code string, now time.Time) error {
+	cookie, err := r.Cookie(cookieName)
+	if err != nil {
+		return ErrNoPass
+	}
+
+	// A pass signed with a key which is unknown or retired by now is no pass at all.
+	keyID, err := auth.KeyID(cookie.Value)
+	if err != nil {
+		return ErrNoPass
+	}
+
+	publicKey, err := g.keystore.PublicKey(keyID)
+	if err != nil {
+		return ErrNoPass
+	}
+
+	if _, err := jwt.ParseString(
+		cookie.Value,
+		jwt.WithVerify(g.signatureAlgorithm, publicKey),
+		jwt.WithValidate(true),
+		jwt.WithAudience(audience),
+		jwt.WithSubject(subject(host, code)),
+		jwt.WithClock(jwt.ClockFunc(func() time.Time { return now })),
+	); err != nil {
+		return ErrNoPass
+	}
+
+	return nil
+}
+
+// subject returns the subject of the passes for the short link identified by the given host and
+// code, so that a pass for a code on one host doesn't open the same code on another.
+func subject(host string, code string) string {
+	if host == "" {
+		return code
+	}
+
+	return host + "/" + code
+}
@@ -2,33 +2,47 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
 	"github.com/lestrrat-go/jwx/jwt"
 
+	"github.com/yashshah7197/shrt/business/sys/workspace"
 	"github.com/yashshah7197/shrt/foundation/keystore"
 )
 
+// ErrRevoked is returned when a token which has been revoked before it expired is validated.
+var ErrRevoked = errors.New("token has been revoked")
+
+// RevocationList defines the behavior required to find out whether the token with the given ID has
+// been revoked. It is consulted on every request, so implementations should answer from memory.
+type RevocationList interface {
+	Revoked(id string) bool
+}
+
 // Auth is used to authenticate clients. It can generate a token for a set of user claims and
-// recreate the claims by parsing a token.
+// recreate the claims by parsing a token. Tokens are signed with the active key of the keystore and
+// name the key in their kid header, so that they keep being verified once another key is active.
 type Auth struct {
-	activeKeyID        string
 	keystore           *keystore.KeyStore
+	revoked            RevocationList
 	signatureAlgorithm jwa.SignatureAlgorithm
 }
 
-// New creates a new Auth to support authentication and authorization.
-func New(activeKeyID string, keystore *keystore.KeyStore) (*Auth, error) {
-	// The activeKeyID represents the private key used to sign new tokens.
-	_, err := keystore.PrivateKey(activeKeyID)
-	if err != nil {
-		return nil, fmt.Errorf("looking up private key: %w", err)
+// New creates a new Auth to support authentication and authorization. Tokens listed as revoked
+// are turned down even though they have not expired yet; no tokens are turned down when the list
+// is nil.
+func New(keystore *keystore.KeyStore, revoked RevocationList) (*Auth, error) {
+	// The active key of the keystore is used to sign new tokens.
+	if _, _, err := keystore.SigningKey(); err != nil {
+		return nil, fmt.Errorf("looking up signing key: %w", err)
 	}
 
 	a := Auth{
-		activeKeyID:        activeKeyID,
 		keystore:           keystore,
+		revoked:            revoked,
 		signatureAlgorithm: jwa.RS256,
 	}
 
@@ -37,26 +51,37 @@ func New(activeKeyID string, keystore *keystore.KeyStore) (*Auth, error) {
 
 // GenerateToken generates a signed JWT token string representing the user Claims.
 func (a *Auth) GenerateToken(claims Claims) (string, error) {
-	// Generate a new JSON Web Token.
-	token, err := jwt.NewBuilder().
+	// Generate a new JSON Web Token. Only tokens with an ID can be revoked later on.
+	builder := jwt.NewBuilder().
 		Issuer(claims.Issuer).
 		Subject(claims.Subject).
 		IssuedAt(claims.IssuedAt).
 		Expiration(claims.ExpiresAt).
-		Claim("roles", claims.Roles).
-		Build()
+		Claim("workspace", claims.Workspace).
+		Claim("role", claims.Role)
+	if claims.ID != "" {
+		builder = builder.JwtID(claims.ID)
+	}
+
+	token, err := builder.Build()
 	if err != nil {
 		return "", fmt.Errorf("generating token: %w", err)
 	}
 
-	// Fetch the private key associated with the active key id from the keystore.
-	privateKey, err := a.keystore.PrivateKey(a.activeKeyID)
+	// Fetch the active key from the keystore.
+	keyID, privateKey, err := a.keystore.SigningKey()
 	if err != nil {
-		return "", fmt.Errorf("fetching private key: %w", err)
+		return "", fmt.Errorf("fetching signing key: %w", err)
 	}
 
-	// Sign the token with the private key associated with the active key id.
-	signedToken, err := jwt.Sign(token, a.signatureAlgorithm, privateKey)
+	// Name the key in the header so that the token can be verified with it later on.
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.KeyIDKey, keyID); err != nil {
+		return "", fmt.Errorf("setting kid header: %w", err)
+	}
+
+	// Sign the token with the private key of the active key.
+	signedToken, err := jwt.Sign(token, a.signatureAlgorithm, privateKey, jwt.WithHeaders(headers))
 	if err != nil {
 		return "", fmt.Errorf("signing token with private key: %w", err)
 	}
@@ -64,12 +89,18 @@ func (a *Auth) GenerateToken(claims Claims) (string, error) {
 	return string(signedToken), nil
 }
 
-// ValidateToken parses a JSON Web Token, verifies it and then validates it.
+// ValidateToken parses a JSON Web Token, verifies it with the key named in its kid header and then
+// validates it. Tokens signed with a retired key are turned down.
 func (a *Auth) ValidateToken(tokenString string) (Claims, error) {
-	// Fetch the public key associated with the active key id from the keystore.
-	publicKey, err := a.keystore.PublicKey(a.activeKeyID)
+	keyID, err := KeyID(tokenString)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	// Fetch the public key associated with the key id of the token from the keystore.
+	publicKey, err := a.keystore.PublicKey(keyID)
 	if err != nil {
-		return Claims{}, fmt.Errorf("fetching public key: %w", err)
+		return Claims{}, fmt.Errorf("fetching public key[%s]: %w", keyID, err)
 	}
 
 	// Verify and validate the token.
@@ -82,26 +113,55 @@ func (a *Auth) ValidateToken(tokenString string) (Claims, error) {
 		return Claims{}, fmt.Errorf("validating token: %w", err)
 	}
 
-	// Parse the roles from the token claims
-	r, ok := token.Get("roles")
-	if !ok {
-		return Claims{}, fmt.Errorf("parsing roles from token claims: %w", err)
+	// Turn down tokens which were revoked before they expired.
+	if id := token.JwtID(); id != "" && a.revoked != nil && a.revoked.Revoked(id) {
+		return Claims{}, ErrRevoked
 	}
 
-	// Put all the parsed roles into a slice of strings
-	var roles []string
-	for _, role := range r.([]interface{}) {
-		roles = append(roles, role.(string))
+	// Parse the workspace and the role within it from the token claims.
+	ws, _ := token.Get("workspace")
+	workspaceID, _ := ws.(string)
+	if err := workspace.Validate(workspaceID); err != nil {
+		return Claims{}, errors.New("parsing workspace from token claims: missing or invalid")
+	}
+
+	r, _ := token.Get("role")
+	role, _ := r.(string)
+	if !IsRole(role) {
+		return Claims{}, errors.New("parsing role from token claims: missing or invalid")
 	}
 
 	// Recreate the claims from the token.
 	claims := Claims{
+		ID:        token.JwtID(),
 		Issuer:    token.Issuer(),
 		Subject:   token.Subject(),
+		Workspace: workspaceID,
+		Role:      role,
 		IssuedAt:  token.IssuedAt(),
 		ExpiresAt: token.Expiration(),
-		Roles:     roles,
 	}
 
 	return claims, nil
 }
+
+// KeyID reads the id of the key a JSON Web Token was signed with out of its kid header, without
+// verifying the token.
+func KeyID(tokenString string) (string, error) {
+	msg, err := jws.ParseString(tokenString)
+	if err != nil {
+		return "", fmt.Errorf("parsing token: %w", err)
+	}
+
+	signatures := msg.Signatures()
+	if len(signatures) != 1 {
+		return "", errors.New("parsing token: expected a single signature")
+	}
+
+	keyID := signatures[0].ProtectedHeaders().KeyID()
+	if keyID == "" {
+		return "", errors.New("parsing kid from token header: missing")
+	}
+
+	return keyID, nil
+}
@@ -6,34 +6,60 @@ import (
 	"time"
 )
 
-// These are the expected values for Claims.Roles.
+// These are the expected values for Claims.Role. Owners manage everything in their workspace,
+// including its settings, editors manage its links and folders, and viewers can only look.
 const (
-	RoleAdmin = "ADMIN"
-	RoleUser  = "USER"
+	RoleOwner  = "OWNER"
+	RoleEditor = "EDITOR"
+	RoleViewer = "VIEWER"
 )
 
-// Claims represents the set of authorization claims transmitted via a JWT.
+// IsRole reports whether the given string is one of the expected roles.
+func IsRole(role string) bool {
+	switch role {
+	case RoleOwner, RoleEditor, RoleViewer:
+		return true
+	}
+
+	return false
+}
+
+// ErrForbidden is returned when an authenticated client attempts an action it has no rights to.
+var ErrForbidden = errors.New("you are not authorized for that action")
+
+// Claims represents the set of authorization claims transmitted via a JWT. The subject acts within
+// a single workspace, in which it holds a single role. The ID identifies the token itself so that
+// it can be revoked, and is blank for clients which authenticated some other way.
 type Claims struct {
+	ID        string
 	Issuer    string
 	Subject   string
+	Workspace string
+	Role      string
 	IssuedAt  time.Time
 	ExpiresAt time.Time
-	Roles     []string
 }
 
-// Authorized returns true if the claims has at least one of the provided roles.
+// Authorized returns true if the role of the claims is one of the provided roles.
 func (c Claims) Authorized(roles ...string) bool {
-	for _, has := range c.Roles {
-		for _, want := range roles {
-			if has == want {
-				return true
-			}
+	for _, want := range roles {
+		if c.Role == want {
+			return true
 		}
 	}
 
 	return false
 }
 
+// KeyPrefix starts every API key, which tells them apart from JWTs at a glance.
+const KeyPrefix = "shrt_live_"
+
+// KeyValidator defines the behavior required to recreate the claims of a client from an API key,
+// the long-lived alternative to a JWT.
+type KeyValidator interface {
+	ValidateKey(ctx context.Context, key string, now time.Time) (Claims, error)
+}
+
 // ctxKeyClaims represents the type of value for the context key.
 type ctxKeyClaims int
 
@@ -0,0 +1,356 @@
+// Package codegen provides the strategies used to generate short codes.
+package codegen
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// DefaultAlphabet is the base62 alphabet used when none is configured.
+const DefaultAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ErrExhausted is returned when a generator has run out of codes to hand out.
+var ErrExhausted = errors.New("code space exhausted")
+
+// Generator defines the behavior required to generate short codes.
+type Generator interface {
+	Generate(ctx context.Context) (string, error)
+}
+
+// NextFunc returns the next value of a monotonically increasing sequence. Sequences are usually
+// provided by the link store so they survive restarts.
+type NextFunc func(ctx context.Context) (uint64, error)
+
+// =================================================================================================
+
+// Alphabet represents the set of characters codes are built from.
+type Alphabet struct {
+	chars string
+	index [256]int
+}
+
+// NewAlphabet constructs an Alphabet from the given characters. Characters must be unique and be
+// letters, digits, '-' or '_' so codes are always safe to use in a URL path.
+func NewAlphabet(chars string) (Alphabet, error) {
+	if len(chars) < 2 {
+		return Alphabet{}, errors.New("alphabet must contain at least two characters")
+	}
+
+	a := Alphabet{
+		chars: chars,
+	}
+	for i := range a.index {
+		a.index[i] = -1
+	}
+
+	for i := 0; i < len(chars); i++ {
+		c := chars[i]
+
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-', c == '_':
+		default:
+			return Alphabet{}, fmt.Errorf("alphabet contains invalid character %q", c)
+		}
+
+		if a.index[c] != -1 {
+			return Alphabet{}, fmt.Errorf("alphabet contains duplicate character %q", c)
+		}
+		a.index[c] = i
+	}
+
+	return a, nil
+}
+
+// Base returns the number of characters in the alphabet.
+func (a Alphabet) Base() int {
+	return len(a.chars)
+}
+
+// Encode converts the number into its representation in the alphabet, left padding it with the
+// zero character to the given minimum width.
+func (a Alphabet) Encode(n uint64, width int) string {
+	base := uint64(len(a.chars))
+
+	var buf [64]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = a.chars[n%base]
+		n /= base
+		if n == 0 {
+			break
+		}
+	}
+
+	for len(buf)-i < width {
+		i--
+		buf[i] = a.chars[0]
+	}
+
+	return string(buf[i:])
+}
+
+// Decode converts a code produced by Encode back into its number.
+func (a Alphabet) Decode(code string) (uint64, error) {
+	if code == "" {
+		return 0, errors.New("empty code")
+	}
+
+	base := uint64(len(a.chars))
+
+	var n uint64
+	for i := 0; i < len(code); i++ {
+		d := a.index[code[i]]
+		if d == -1 {
+			return 0, fmt.Errorf("character %q is not in the alphabet", code[i])
+		}
+
+		hi, lo := bits.Mul64(n, base)
+		lo, carry := bits.Add64(lo, uint64(d), 0)
+		if hi != 0 || carry != 0 {
+			return 0, errors.New("code overflows")
+		}
+		n = lo
+	}
+
+	return n, nil
+}
+
+// =================================================================================================
+
+// Random generates cryptographically random codes of a fixed length. These are the hardest codes
+// to guess.
+type Random struct {
+	alphabet Alphabet
+	length   int
+}
+
+// NewRandom constructs a generator of random codes with the given length.
+func NewRandom(alphabet Alphabet, length int) (*Random, error) {
+	if length < 1 {
+		return nil, errors.New("length must be at least 1")
+	}
+
+	r := Random{
+		alphabet: alphabet,
+		length:   length,
+	}
+
+	return &r, nil
+}
+
+// Generate returns a new random code.
+func (r *Random) Generate(ctx context.Context) (string, error) {
+	max := big.NewInt(int64(r.alphabet.Base()))
+
+	code := make([]byte, r.length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("reading random data: %w", err)
+		}
+		code[i] = r.alphabet.chars[n.Int64()]
+	}
+
+	return string(code), nil
+}
+
+// =================================================================================================
+
+// Sequence generates codes by encoding the values of a sequence in the alphabet. These are the
+// shortest possible codes but they are easy to enumerate.
+type Sequence struct {
+	alphabet Alphabet
+	next     NextFunc
+}
+
+// NewSequence constructs a generator of sequential codes.
+func NewSequence(alphabet Alphabet, next NextFunc) *Sequence {
+	return &Sequence{
+		alphabet: alphabet,
+		next:     next,
+	}
+}
+
+// Generate returns the code for the next value of the sequence.
+func (s *Sequence) Generate(ctx context.Context) (string, error) {
+	n, err := s.next(ctx)
+	if err != nil {
+		return "", fmt.Errorf("fetching next sequence value: %w", err)
+	}
+
+	return s.alphabet.Encode(n, 1), nil
+}
+
+// =================================================================================================
+
+// Obfuscated generates fixed length codes from the values of a sequence, scrambled by a reversible
+// permutation derived from a secret key. Consecutive values map to codes that look unrelated, so
+// the codes can't be enumerated without the key, while every code can still be mapped back to its
+// sequence value.
+//
+// The permutation runs several rounds of an affine map n -> (n*multiplier + offset) mod
+// base^length followed by reversing the digits of the result. The affine map is a bijection
+// because every multiplier is coprime with the modulus, and reversing the digits makes sure the
+// high digits get mixed as well as the low ones.
+type Obfuscated struct {
+	alphabet Alphabet
+	length   int
+	modulus  uint64
+	rounds   [obfuscationRounds]round
+	next     NextFunc
+}
+
+// obfuscationRounds is the number of rounds the permutation is applied for.
+const obfuscationRounds = 4
+
+// round holds the parameters of a single round of the permutation.
+type round struct {
+	multiplier uint64
+	inverse    uint64
+	offset     uint64
+}
+
+// NewObfuscated constructs a generator of obfuscated sequential codes with the given length.
+func NewObfuscated(alphabet Alphabet, length int, key uint64, next NextFunc) (*Obfuscated, error) {
+	if length < 1 {
+		return nil, errors.New("length must be at least 1")
+	}
+
+	// Calculate the size of the code space, making sure it fits in 64 bits.
+	modulus := uint64(1)
+	for i := 0; i < length; i++ {
+		hi, lo := bits.Mul64(modulus, uint64(alphabet.Base()))
+		if hi != 0 {
+			return nil, fmt.Errorf("length %d is too long for an alphabet of %d characters", length, alphabet.Base())
+		}
+		modulus = lo
+	}
+
+	o := Obfuscated{
+		alphabet: alphabet,
+		length:   length,
+		modulus:  modulus,
+		next:     next,
+	}
+
+	// Derive the parameters for every round from the key.
+	state := key
+	for i := range o.rounds {
+		o.rounds[i] = newRound(splitMix64(&state), splitMix64(&state), modulus)
+	}
+
+	return &o, nil
+}
+
+// Generate returns the obfuscated code for the next value of the sequence.
+func (o *Obfuscated) Generate(ctx context.Context) (string, error) {
+	n, err := o.next(ctx)
+	if err != nil {
+		return "", fmt.Errorf("fetching next sequence value: %w", err)
+	}
+
+	if n >= o.modulus {
+		return "", ErrExhausted
+	}
+
+	x := n
+	for _, r := range o.rounds {
+		x = o.reverse(addMod(mulMod(x, r.multiplier, o.modulus), r.offset, o.modulus))
+	}
+
+	return o.alphabet.Encode(x, o.length), nil
+}
+
+// Decode returns the sequence value the given code was generated from.
+func (o *Obfuscated) Decode(code string) (uint64, error) {
+	if len(code) != o.length {
+		return 0, fmt.Errorf("code must be %d characters long", o.length)
+	}
+
+	x, err := o.alphabet.Decode(code)
+	if err != nil {
+		return 0, err
+	}
+
+	if x >= o.modulus {
+		return 0, errors.New("code is out of range")
+	}
+
+	// Undo every round in reverse order.
+	for i := len(o.rounds) - 1; i >= 0; i-- {
+		r := o.rounds[i]
+		x = o.reverse(x)
+		x = mulMod(addMod(x, o.modulus-r.offset, o.modulus), r.inverse, o.modulus)
+	}
+
+	return x, nil
+}
+
+// reverse reverses the order of the digits of the value written with exactly length digits.
+func (o *Obfuscated) reverse(x uint64) uint64 {
+	base := uint64(o.alphabet.Base())
+
+	var r uint64
+	for i := 0; i < o.length; i++ {
+		r = r*base + x%base
+		x /= base
+	}
+
+	return r
+}
+
+// newRound derives the parameters for a round of the permutation from two random values.
+func newRound(a, b, modulus uint64) round {
+	m := new(big.Int).SetUint64(modulus)
+	one := big.NewInt(1)
+
+	// Look for a multiplier which is coprime with the modulus so that it has an inverse. Tiny code
+	// spaces may only admit the identity.
+	r := round{
+		multiplier: 1,
+		inverse:    1,
+		offset:     b % modulus,
+	}
+
+	candidate := new(big.Int).SetUint64(a % modulus)
+	for tries := 0; tries < 64; tries++ {
+		if candidate.Cmp(one) > 0 {
+			if inverse := new(big.Int).ModInverse(candidate, m); inverse != nil {
+				r.multiplier = candidate.Uint64()
+				r.inverse = inverse.Uint64()
+				break
+			}
+		}
+		candidate.Add(candidate, one)
+		candidate.Mod(candidate, m)
+	}
+
+	return r
+}
+
+// splitMix64 advances the state and returns the next value of the SplitMix64 generator. It turns
+// a single key into a stream of well mixed values.
+func splitMix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// mulMod returns a*b mod m without overflowing.
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+// addMod returns a+b mod m without overflowing.
+func addMod(a, b, m uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	return bits.Rem64(carry, sum, m)
+}
@@ -0,0 +1,214 @@
+package codegen_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yashshah7197/shrt/business/sys/codegen"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// counter returns a NextFunc which hands out the values of a sequence starting at the given value.
+func counter(start uint64) codegen.NextFunc {
+	n := start
+	return func(ctx context.Context) (uint64, error) {
+		v := n
+		n++
+		return v, nil
+	}
+}
+
+func TestAlphabet(t *testing.T) {
+	t.Log("Given the need to build codes from an alphabet.")
+	{
+		invalid := []string{"", "a", "aa", "ab/", "ab c"}
+		for testID, chars := range invalid {
+			if _, err := codegen.NewAlphabet(chars); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the alphabet %q.", failed, testID, chars)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the alphabet %q.", success, testID, chars)
+		}
+
+		alphabet, err := codegen.NewAlphabet(codegen.DefaultAlphabet)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to construct the default alphabet: %s.", failed, err)
+		}
+
+		tt := []struct {
+			n     uint64
+			width int
+			code  string
+		}{
+			{0, 1, "0"},
+			{61, 1, "z"},
+			{62, 1, "10"},
+			{62, 4, "0010"},
+			{3843, 2, "zz"},
+			{1<<64 - 1, 1, "LygHa16AHYF"},
+		}
+
+		for testID, tst := range tt {
+			code := alphabet.Encode(tst.n, tst.width)
+			if code != tst.code {
+				t.Fatalf("\t%s\tTest %d:\tShould encode %d as %q, got %q.", failed, testID, tst.n, tst.code, code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould encode %d as %q.", success, testID, tst.n, tst.code)
+
+			n, err := alphabet.Decode(code)
+			if err != nil || n != tst.n {
+				t.Fatalf("\t%s\tTest %d:\tShould decode %q back to %d, got %d: %v.", failed, testID, code, tst.n, n, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould decode %q back to %d.", success, testID, code, tst.n)
+		}
+
+		if _, err := alphabet.Decode("LygHa16AHYG"); err == nil {
+			t.Fatalf("\t%s\tShould reject a code which overflows.", failed)
+		}
+		t.Logf("\t%s\tShould reject a code which overflows.", success)
+
+		if _, err := alphabet.Decode("ab-c"); err == nil {
+			t.Fatalf("\t%s\tShould reject a code with characters outside the alphabet.", failed)
+		}
+		t.Logf("\t%s\tShould reject a code with characters outside the alphabet.", success)
+	}
+}
+
+func TestObfuscated(t *testing.T) {
+	t.Log("Given the need to generate obfuscated codes which never collide.")
+	{
+		tt := []struct {
+			name   string
+			chars  string
+			length int
+		}{
+			{"decimal", "0123456789", 3},
+			{"hex", "0123456789abcdef", 4},
+			{"binary", "01", 10},
+			{"tiny", "ab", 1},
+		}
+
+		for testID, tst := range tt {
+			t.Run(tst.name, func(t *testing.T) {
+				alphabet, err := codegen.NewAlphabet(tst.chars)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to construct the alphabet: %s.", failed, testID, err)
+				}
+
+				gen, err := codegen.NewObfuscated(alphabet, tst.length, 6820379104728341, counter(0))
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to construct the generator: %s.", failed, testID, err)
+				}
+
+				// Walk the whole code space, which must be covered exactly once.
+				space := 1
+				for i := 0; i < tst.length; i++ {
+					space *= len(tst.chars)
+				}
+
+				seen := make(map[string]bool, space)
+				for n := 0; n < space; n++ {
+					code, err := gen.Generate(context.Background())
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould generate a code for %d: %s.", failed, testID, n, err)
+					}
+					if len(code) != tst.length {
+						t.Fatalf("\t%s\tTest %d:\tShould generate codes of length %d, got %q.", failed, testID, tst.length, code)
+					}
+					if seen[code] {
+						t.Fatalf("\t%s\tTest %d:\tShould never generate %q twice.", failed, testID, code)
+					}
+					seen[code] = true
+
+					v, err := gen.Decode(code)
+					if err != nil || v != uint64(n) {
+						t.Fatalf("\t%s\tTest %d:\tShould decode %q back to %d, got %d: %v.", failed, testID, code, n, v, err)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould generate %d unique codes which decode back.", success, testID, space)
+
+				if _, err := gen.Generate(context.Background()); !errors.Is(err, codegen.ErrExhausted) {
+					t.Fatalf("\t%s\tTest %d:\tShould report the code space as exhausted, got %v.", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould report the code space as exhausted.", success, testID)
+			})
+		}
+	}
+}
+
+func TestObfuscatedKeys(t *testing.T) {
+	t.Log("Given the need to keep codes from being enumerated without the key.")
+	{
+		alphabet, err := codegen.NewAlphabet(codegen.DefaultAlphabet)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to construct the default alphabet: %s.", failed, err)
+		}
+
+		first, err := codegen.NewObfuscated(alphabet, 7, 1, counter(1))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to construct the first generator: %s.", failed, err)
+		}
+		second, err := codegen.NewObfuscated(alphabet, 7, 2, counter(1))
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to construct the second generator: %s.", failed, err)
+		}
+
+		const values = 100000
+		seen := make(map[string]bool, values)
+		var same int
+		for n := 0; n < values; n++ {
+			a, err := first.Generate(context.Background())
+			if err != nil {
+				t.Fatalf("\t%s\tShould generate a code: %s.", failed, err)
+			}
+			b, err := second.Generate(context.Background())
+			if err != nil {
+				t.Fatalf("\t%s\tShould generate a code: %s.", failed, err)
+			}
+
+			if seen[a] {
+				t.Fatalf("\t%s\tShould never generate %q twice.", failed, a)
+			}
+			seen[a] = true
+
+			if a == b {
+				same++
+			}
+		}
+		t.Logf("\t%s\tShould generate %d unique codes.", success, values)
+
+		if same > values/1000 {
+			t.Fatalf("\t%s\tShould generate different codes for different keys, %d were the same.", failed, same)
+		}
+		t.Logf("\t%s\tShould generate different codes for different keys.", success)
+
+		if _, err := codegen.NewObfuscated(alphabet, 11, 1, counter(0)); err == nil {
+			t.Fatalf("\t%s\tShould reject a code space which doesn't fit in 64 bits.", failed)
+		}
+		t.Logf("\t%s\tShould reject a code space which doesn't fit in 64 bits.", success)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	t.Log("Given the need to generate the shortest codes from a sequence.")
+	{
+		alphabet, err := codegen.NewAlphabet(codegen.DefaultAlphabet)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to construct the default alphabet: %s.", failed, err)
+		}
+
+		gen := codegen.NewSequence(alphabet, counter(61))
+		for testID, want := range []string{"z", "10", "11"} {
+			code, err := gen.Generate(context.Background())
+			if err != nil || code != want {
+				t.Fatalf("\t%s\tTest %d:\tShould generate %q, got %q: %v.", failed, testID, want, code, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould generate %q.", success, testID, want)
+		}
+	}
+}
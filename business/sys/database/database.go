@@ -0,0 +1,99 @@
+// Package database provides support for access to the database.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrDBNotFound is returned when a query matches no rows.
+var ErrDBNotFound = sql.ErrNoRows
+
+// uniqueViolation is the postgres error code for a uniqueness constraint violation.
+const uniqueViolation = "23505"
+
+// Config is the required properties to use the database.
+type Config struct {
+	User            string
+	Password        string
+	Host            string
+	Name            string
+	MaxIdleConns    int
+	MaxOpenConns    int
+	ConnMaxLifetime time.Duration
+	DisableTLS      bool
+}
+
+// Open knows how to open a database connection pool based on the configuration.
+func Open(cfg Config) (*sql.DB, error) {
+	sslMode := "require"
+	if cfg.DisableTLS {
+		sslMode = "disable"
+	}
+
+	q := make(url.Values)
+	q.Set("sslmode", sslMode)
+	q.Set("timezone", "utc")
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     cfg.Host,
+		Path:     cfg.Name,
+		RawQuery: q.Encode(),
+	}
+
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	return db, nil
+}
+
+// StatusCheck returns nil if it can successfully talk to the database. It returns a non-nil error
+// otherwise.
+func StatusCheck(ctx context.Context, db *sql.DB) error {
+	// First check we can ping the database.
+	var pingError error
+	for attempts := 1; ; attempts++ {
+		pingError = db.PingContext(ctx)
+		if pingError == nil {
+			break
+		}
+		time.Sleep(time.Duration(attempts) * 100 * time.Millisecond)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	// Make sure we didn't timeout or be cancelled.
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Run a simple query to determine connectivity. Running this query forces a round trip
+	// through the database.
+	var tmp bool
+	return db.QueryRowContext(ctx, "SELECT true").Scan(&tmp)
+}
+
+// IsDuplicatedEntry reports whether the given error was caused by a uniqueness constraint
+// violation.
+func IsDuplicatedEntry(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == uniqueViolation
+	}
+
+	return false
+}
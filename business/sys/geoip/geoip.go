@@ -0,0 +1,134 @@
+// Package geoip locates client addresses using a local database in the MaxMind DB format, such as
+// GeoLite2 City. The database is swapped out for a new one whenever its file changes, without
+// interrupting lookups.
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yashshah7197/shrt/foundation/mmdb"
+)
+
+// Location represents where a client address is. The country is an ISO 3166-1 code, the region an
+// ISO 3166-2 code and the city an English name. Any of them may be blank when they aren't known.
+type Location struct {
+	Country string
+	Region  string
+	City    string
+}
+
+// Locator looks up the location of client addresses. It is safe for concurrent use.
+type Locator struct {
+	path string
+
+	mu      sync.RWMutex
+	reader  *mmdb.Reader
+	modTime time.Time
+	size    int64
+}
+
+// Open constructs a Locator backed by the database file at the given path.
+func Open(path string) (*Locator, error) {
+	l := Locator{
+		path: path,
+	}
+
+	if _, err := l.Reload(); err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// Metadata returns the metadata of the database currently in use.
+func (l *Locator) Metadata() mmdb.Metadata {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.reader.Metadata()
+}
+
+// Reload swaps in the database file if it has changed since it was last loaded, and reports
+// whether it did. The database in use is kept if the new one can't be read.
+func (l *Locator) Reload() (bool, error) {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return false, fmt.Errorf("checking database file: %w", err)
+	}
+
+	l.mu.RLock()
+	unchanged := l.reader != nil && info.ModTime().Equal(l.modTime) && info.Size() == l.size
+	l.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	reader, err := mmdb.Open(l.path)
+	if err != nil {
+		return false, fmt.Errorf("opening database: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.reader = reader
+	l.modTime = info.ModTime()
+	l.size = info.Size()
+
+	return true, nil
+}
+
+// Locate looks up the location of the given address. Addresses which are not in the database
+// get a blank location.
+func (l *Locator) Locate(ip net.IP) (Location, error) {
+	l.mu.RLock()
+	reader := l.reader
+	l.mu.RUnlock()
+
+	rec, found, err := reader.Lookup(ip)
+	if err != nil {
+		return Location{}, fmt.Errorf("looking up %s: %w", ip, err)
+	}
+	if !found {
+		return Location{}, nil
+	}
+
+	var loc Location
+	loc.Country = str(rec, "country", "iso_code")
+	if loc.Country == "" {
+		loc.Country = str(rec, "registered_country", "iso_code")
+	}
+
+	if subdivisions, ok := field(rec, "subdivisions").([]interface{}); ok && len(subdivisions) > 0 {
+		if code := str(subdivisions[0], "iso_code"); code != "" && loc.Country != "" {
+			loc.Region = loc.Country + "-" + code
+		}
+	}
+
+	loc.City = str(rec, "city", "names", "en")
+
+	return loc, nil
+}
+
+// field follows the given path of map keys through a decoded record.
+func field(rec interface{}, path ...string) interface{} {
+	for _, key := range path {
+		m, ok := rec.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		rec = m[key]
+	}
+
+	return rec
+}
+
+// str follows the given path of map keys through a decoded record to a string.
+func str(rec interface{}, path ...string) string {
+	s, _ := field(rec, path...).(string)
+	return s
+}
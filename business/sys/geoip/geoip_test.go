@@ -0,0 +1,146 @@
+package geoip_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yashshah7197/shrt/business/sys/geoip"
+	"github.com/yashshah7197/shrt/foundation/mmdb/mmdbtest"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// city returns a record laid out like those of GeoLite2 City.
+func city(country string, region string, name string) map[string]interface{} {
+	rec := map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": country},
+	}
+	if region != "" {
+		rec["subdivisions"] = []interface{}{
+			map[string]interface{}{"iso_code": region},
+			map[string]interface{}{"iso_code": "XX"},
+		}
+	}
+	if name != "" {
+		rec["city"] = map[string]interface{}{
+			"names": map[string]interface{}{"de": "Name", "en": name},
+		}
+	}
+
+	return rec
+}
+
+// write stores a database holding the given networks at the given path.
+func write(t *testing.T, path string, networks map[string]interface{}) {
+	t.Helper()
+
+	db := mmdbtest.New(6, 28)
+	for cidr, rec := range networks {
+		if err := db.Insert(cidr, rec); err != nil {
+			t.Fatalf("\t%s\tShould be able to insert %s: %s.", failed, cidr, err)
+		}
+	}
+
+	if err := os.WriteFile(path, db.Bytes(), 0o644); err != nil {
+		t.Fatalf("\t%s\tShould be able to write the database: %s.", failed, err)
+	}
+}
+
+func TestLocate(t *testing.T) {
+	t.Log("Given the need to locate client addresses.")
+	{
+		path := filepath.Join(t.TempDir(), "city.mmdb")
+		write(t, path, map[string]interface{}{
+			"81.2.69.0/24":   city("GB", "ENG", "London"),
+			"89.160.20.0/24": city("SE", "E", ""),
+			"2.125.160.0/19": city("GB", "", ""),
+			"2001:db8::/32":  city("US", "CA", "San Francisco"),
+			"202.196.224.0/20": map[string]interface{}{
+				"registered_country": map[string]interface{}{"iso_code": "PH"},
+				"subdivisions":       []interface{}{map[string]interface{}{"iso_code": "00"}},
+			},
+			"10.0.0.0/8": map[string]interface{}{
+				"subdivisions": []interface{}{map[string]interface{}{"iso_code": "ENG"}},
+				"city":         map[string]interface{}{"names": map[string]interface{}{"en": "Nowhere"}},
+			},
+		})
+
+		loc, err := geoip.Open(path)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to open the database: %s.", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to open the database.", success)
+
+		tt := []struct {
+			ip   string
+			want geoip.Location
+		}{
+			{"81.2.69.142", geoip.Location{Country: "GB", Region: "GB-ENG", City: "London"}},
+			{"89.160.20.128", geoip.Location{Country: "SE", Region: "SE-E"}},
+			{"2.125.160.216", geoip.Location{Country: "GB"}},
+			{"2001:db8::1", geoip.Location{Country: "US", Region: "US-CA", City: "San Francisco"}},
+			{"202.196.224.1", geoip.Location{Country: "PH", Region: "PH-00"}},
+			{"10.1.2.3", geoip.Location{City: "Nowhere"}},
+			{"127.0.0.1", geoip.Location{}},
+			{"2001:db9::1", geoip.Location{}},
+		}
+
+		for testID, tst := range tt {
+			got, err := loc.Locate(net.ParseIP(tst.ip))
+			if err != nil || got != tst.want {
+				t.Fatalf("\t%s\tTest %d:\tShould locate %s at %+v, got %+v: %v.", failed, testID, tst.ip, tst.want, got, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould locate %s at %+v.", success, testID, tst.ip, tst.want)
+		}
+
+		if _, err := geoip.Open(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+			t.Fatalf("\t%s\tShould fail to open a missing database.", failed)
+		}
+		t.Logf("\t%s\tShould fail to open a missing database.", success)
+	}
+}
+
+func TestReload(t *testing.T) {
+	t.Log("Given the need to swap in a new database without a restart.")
+	{
+		path := filepath.Join(t.TempDir(), "city.mmdb")
+		write(t, path, map[string]interface{}{"81.2.69.0/24": city("GB", "ENG", "London")})
+
+		loc, err := geoip.Open(path)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to open the database: %s.", failed, err)
+		}
+
+		if swapped, err := loc.Reload(); err != nil || swapped {
+			t.Fatalf("\t%s\tShould keep a database which hasn't changed, got %t: %v.", failed, swapped, err)
+		}
+		t.Logf("\t%s\tShould keep a database which hasn't changed.", success)
+
+		write(t, path, map[string]interface{}{"81.2.69.0/24": city("GB", "SCT", "Edinburgh")})
+		if swapped, err := loc.Reload(); err != nil || !swapped {
+			t.Fatalf("\t%s\tShould swap in a database which has changed, got %t: %v.", failed, swapped, err)
+		}
+		edinburgh := geoip.Location{Country: "GB", Region: "GB-SCT", City: "Edinburgh"}
+		if got, err := loc.Locate(net.ParseIP("81.2.69.1")); err != nil || got != edinburgh {
+			t.Fatalf("\t%s\tShould locate addresses with the new database, got %+v: %v.", failed, got, err)
+		}
+		t.Logf("\t%s\tShould swap in a database which has changed.", success)
+
+		if err := os.WriteFile(path, []byte("not a database"), 0o644); err != nil {
+			t.Fatalf("\t%s\tShould be able to corrupt the database: %s.", failed, err)
+		}
+		if _, err := loc.Reload(); err == nil {
+			t.Fatalf("\t%s\tShould fail to swap in a corrupt database.", failed)
+		}
+		if got, err := loc.Locate(net.ParseIP("81.2.69.1")); err != nil || got != edinburgh {
+			t.Fatalf("\t%s\tShould keep the database in use, got %+v: %v.", failed, got, err)
+		}
+		t.Logf("\t%s\tShould keep the database in use when the new one is corrupt.", success)
+	}
+}
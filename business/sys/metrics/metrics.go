@@ -18,6 +18,8 @@ type metrics struct {
 	requests   *expvar.Int
 	errors     *expvar.Int
 	panics     *expvar.Int
+	collisions *expvar.Int
+	clickDrops *expvar.Int
 }
 
 // init constructs the metrics value that will be used to capture metrics. The metrics value is
@@ -29,6 +31,8 @@ func init() {
 		requests:   expvar.NewInt("requests"),
 		errors:     expvar.NewInt("errors"),
 		panics:     expvar.NewInt("panics"),
+		collisions: expvar.NewInt("collisions"),
+		clickDrops: expvar.NewInt("click_drops"),
 	}
 }
 
@@ -73,3 +77,17 @@ func AddPanic(ctx context.Context) {
 		v.panics.Add(1)
 	}
 }
+
+// AddCollision increments the short code collisions metric by 1.
+func AddCollision(ctx context.Context) {
+	if v, ok := ctx.Value(metricsKey).(*metrics); ok {
+		v.collisions.Add(1)
+	}
+}
+
+// AddClickDrops increments the dropped click events metric by the given number of events.
+func AddClickDrops(ctx context.Context, n int) {
+	if v, ok := ctx.Value(metricsKey).(*metrics); ok {
+		v.clickDrops.Add(int64(n))
+	}
+}
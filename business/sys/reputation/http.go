@@ -0,0 +1,125 @@
+package reputation
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// LookupRequest is the body of a lookup sent to an HTTP provider. It holds the hex encoded
+// PrefixLength byte prefixes of the hashes of the expressions of a URL.
+type LookupRequest struct {
+	Prefixes []string `json:"prefixes"`
+}
+
+// LookupResponse is the body of the answer to a lookup. It holds every entry of the provider's
+// lists which starts with one of the requested prefixes.
+type LookupResponse struct {
+	Matches []Match `json:"matches"`
+}
+
+// Match is an entry of a threat list which a lookup turned up. The hash is hex encoded and may be a
+// prefix of a full hash.
+type Match struct {
+	Threat string `json:"threat"`
+	Hash   string `json:"hash"`
+}
+
+// maxResponse bounds the size of a lookup response.
+const maxResponse = 1 << 20
+
+// HTTPConfig represents the settings required by an HTTP provider. The API key is sent as a bearer
+// token when it is set.
+type HTTPConfig struct {
+	URL     string
+	APIKey  string
+	Timeout time.Duration
+}
+
+// HTTP checks URLs against the threat lists of a remote provider. Only the hash prefixes of a URL
+// are sent, and the full hashes which come back are compared locally, so the provider never learns
+// which URL was checked. It is safe for concurrent use.
+type HTTP struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewHTTP constructs an HTTP provider which posts lookups to the given URL.
+func NewHTTP(cfg HTTPConfig) *HTTP {
+	return &HTTP{
+		url:    cfg.URL,
+		apiKey: cfg.APIKey,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+	}
+}
+
+// Check returns the names of the provider's lists the URL is on, in alphabetical order.
+func (h *HTTP) Check(ctx context.Context, rawURL string) ([]string, error) {
+	hashes, err := Hashes(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("hashing url: %w", err)
+	}
+
+	var lr LookupRequest
+	seen := make(map[string]bool)
+	for _, hash := range hashes {
+		if prefix := hex.EncodeToString(hash[:PrefixLength]); !seen[prefix] {
+			seen[prefix] = true
+			lr.Prefixes = append(lr.Prefixes, prefix)
+		}
+	}
+
+	body, err := json.Marshal(lr)
+	if err != nil {
+		return nil, fmt.Errorf("encoding lookup: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("building lookup: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.apiKey)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending lookup: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lookup failed with status %d", resp.StatusCode)
+	}
+
+	var lresp LookupResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponse)).Decode(&lresp); err != nil {
+		return nil, fmt.Errorf("decoding lookup response: %w", err)
+	}
+
+	var threats []string
+	for _, m := range lresp.Matches {
+		entry, err := parseHash(m.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("decoding match: %w", err)
+		}
+
+		for _, hash := range hashes {
+			if bytes.HasPrefix(hash[:], entry) {
+				threats = merge(threats, []string{m.Threat})
+				break
+			}
+		}
+	}
+
+	return threats, nil
+}
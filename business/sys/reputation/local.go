@@ -0,0 +1,192 @@
+package reputation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// entry is a hash, or a prefix of one, on a threat list.
+type entry struct {
+	threat string
+	hash   []byte
+}
+
+// fileState identifies the version of a list file which was loaded.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Local checks URLs against threat lists held in local files. Each file is a list named after the
+// file without its extension, such as malware for malware.txt. A list has one hex encoded SHA-256
+// hash or hash prefix of at least PrefixLength bytes per line; blank lines and lines starting with
+// # are ignored. A URL is on a list when the hash of any of its expressions starts with an entry,
+// so lists of full hashes never flag a URL by mistake. The lists are swapped out for new ones
+// whenever their files change, without interrupting checks. Local is safe for concurrent use.
+type Local struct {
+	paths []string
+
+	mu      sync.RWMutex
+	entries map[[PrefixLength]byte][]entry
+	files   map[string]fileState
+}
+
+// OpenLocal constructs a Local backed by the list files at the given paths.
+func OpenLocal(paths []string) (*Local, error) {
+	l := Local{
+		paths: paths,
+	}
+
+	if _, err := l.Reload(); err != nil {
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// Len returns the number of entries on the lists currently in use.
+func (l *Local) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var n int
+	for _, list := range l.entries {
+		n += len(list)
+	}
+
+	return n
+}
+
+// Reload swaps in the lists if any of their files has changed since they were last loaded, and
+// reports whether it did. The lists in use are kept if the new ones can't be read.
+func (l *Local) Reload() (bool, error) {
+	files := make(map[string]fileState, len(l.paths))
+	for _, path := range l.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, fmt.Errorf("checking list file: %w", err)
+		}
+		files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	l.mu.RLock()
+	unchanged := l.files != nil
+	for path, state := range files {
+		if old, ok := l.files[path]; !ok || !old.modTime.Equal(state.modTime) || old.size != state.size {
+			unchanged = false
+		}
+	}
+	l.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	entries := make(map[[PrefixLength]byte][]entry)
+	for _, path := range l.paths {
+		if err := readList(path, entries); err != nil {
+			return false, fmt.Errorf("reading list %s: %w", path, err)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = entries
+	l.files = files
+
+	return true, nil
+}
+
+// Check returns the names of the lists the URL is on, in alphabetical order.
+func (l *Local) Check(ctx context.Context, rawURL string) ([]string, error) {
+	hashes, err := Hashes(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("hashing url: %w", err)
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var threats []string
+	for _, hash := range hashes {
+		var key [PrefixLength]byte
+		copy(key[:], hash[:])
+
+		for _, e := range l.entries[key] {
+			if bytes.HasPrefix(hash[:], e.hash) {
+				threats = merge(threats, []string{e.threat})
+			}
+		}
+	}
+
+	return threats, nil
+}
+
+// Find returns the entries on the lists which start with the given hash prefix. It lets the lists
+// be served to other instances over HTTP.
+func (l *Local) Find(prefix [PrefixLength]byte) []Match {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var matches []Match
+	for _, e := range l.entries[prefix] {
+		matches = append(matches, Match{
+			Threat: e.threat,
+			Hash:   hex.EncodeToString(e.hash),
+		})
+	}
+
+	return matches
+}
+
+// readList adds the entries of the list file at the given path to the set of entries.
+func readList(path string, entries map[[PrefixLength]byte][]entry) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	threat := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, err := parseHash(text)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var key [PrefixLength]byte
+		copy(key[:], hash)
+		entries[key] = append(entries[key], entry{threat: threat, hash: hash})
+	}
+
+	return scanner.Err()
+}
+
+// parseHash decodes a hex encoded hash or hash prefix.
+func parseHash(s string) ([]byte, error) {
+	hash, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding hash: %w", err)
+	}
+	if len(hash) < PrefixLength || len(hash) > sha256.Size {
+		return nil, fmt.Errorf("hash must be between %d and %d bytes long", PrefixLength, sha256.Size)
+	}
+
+	return hash, nil
+}
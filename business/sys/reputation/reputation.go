@@ -0,0 +1,132 @@
+// Package reputation checks URLs against threat intelligence, in the manner of the Safe Browsing
+// API. A URL is broken down into the host suffix and path prefix expressions it matches, whose
+// SHA-256 hashes are looked up in threat lists. Lists hold hashes, or prefixes of them, so that
+// neither the lists nor the lookups give away the URLs themselves.
+package reputation
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// PrefixLength is the number of leading bytes of a hash that lookups are keyed on. Threat lists
+// hold hashes of at least this length.
+const PrefixLength = 4
+
+// Checker is implemented by the providers of threat intelligence. Check returns the names of the
+// threat lists the URL is on, and none when it is not known to be a threat.
+type Checker interface {
+	Check(ctx context.Context, rawURL string) ([]string, error)
+}
+
+// Multi is a Checker which consults every one of a set of checkers.
+type Multi []Checker
+
+// Check returns the names of the threat lists the URL is on according to any of the checkers, in
+// alphabetical order.
+func (m Multi) Check(ctx context.Context, rawURL string) ([]string, error) {
+	var threats []string
+	for _, c := range m {
+		found, err := c.Check(ctx, rawURL)
+		if err != nil {
+			return nil, err
+		}
+		threats = merge(threats, found)
+	}
+
+	return threats, nil
+}
+
+// Expressions returns the host suffix and path prefix combinations of the URL that threat lists
+// are matched against. The hosts are the exact host and up to four of its parent domains, and the
+// paths are the exact path with and without its query and up to four of its leading directories.
+func Expressions(rawURL string) ([]string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, err
+	}
+
+	host := strings.Trim(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return nil, errors.New("url has no host")
+	}
+
+	hosts := []string{host}
+	if net.ParseIP(host) == nil {
+		labels := strings.Split(host, ".")
+		start := len(labels) - 5
+		if start < 1 {
+			start = 1
+		}
+		for i := start; i < len(labels)-1; i++ {
+			hosts = append(hosts, strings.Join(labels[i:], "."))
+		}
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	var paths []string
+	if u.RawQuery != "" {
+		paths = append(paths, path+"?"+u.RawQuery)
+	}
+	paths = append(paths, path)
+
+	dir := "/"
+	paths = append(paths, dir)
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i < len(segments)-1 && i < 3; i++ {
+		dir += segments[i] + "/"
+		paths = append(paths, dir)
+	}
+
+	seen := make(map[string]bool)
+	var exprs []string
+	for _, h := range hosts {
+		for _, p := range paths {
+			if expr := h + p; !seen[expr] {
+				seen[expr] = true
+				exprs = append(exprs, expr)
+			}
+		}
+	}
+
+	return exprs, nil
+}
+
+// Hashes returns the SHA-256 hashes of the expressions of the URL.
+func Hashes(rawURL string) ([][sha256.Size]byte, error) {
+	exprs, err := Expressions(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([][sha256.Size]byte, len(exprs))
+	for i, expr := range exprs {
+		hashes[i] = sha256.Sum256([]byte(expr))
+	}
+
+	return hashes, nil
+}
+
+// merge adds the names which are not in the sorted set yet and keeps it sorted.
+func merge(set []string, names []string) []string {
+	for _, name := range names {
+		i := sort.SearchStrings(set, name)
+		if i < len(set) && set[i] == name {
+			continue
+		}
+		set = append(set, "")
+		copy(set[i+1:], set[i:])
+		set[i] = name
+	}
+
+	return set
+}
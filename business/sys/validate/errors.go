@@ -57,6 +57,23 @@ func (fe FieldErrors) Error() string {
 	return string(data)
 }
 
+// Add appends an error for the specified field to the collection.
+func (fe *FieldErrors) Add(field string, err string) {
+	*fe = append(*fe, FieldError{
+		Field: field,
+		Error: err,
+	})
+}
+
+// Err returns the collection as an error if it holds at least one field error, otherwise nil.
+func (fe FieldErrors) Err() error {
+	if len(fe) == 0 {
+		return nil
+	}
+
+	return fe
+}
+
 // Cause iterates through all the wrapped errors until the root error value is reached.
 func Cause(err error) error {
 	root := err
@@ -1,2 +1,43 @@
 // Package validate contains the support for validating models.
 package validate
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// URL checks that the given string is an absolute URL with both a scheme and a host.
+func URL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.New("must be a valid URL")
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return errors.New("must be an absolute URL")
+	}
+
+	return nil
+}
+
+// Slug checks that the given string is between min and max characters long, is made up of only
+// letters, digits, '-' and '_', and starts and ends with a letter or a digit.
+func Slug(value string, min int, max int) error {
+	if len(value) < min || len(value) > max {
+		return fmt.Errorf("must be between %d and %d characters long", min, max)
+	}
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case (c == '-' || c == '_') && i != 0 && i != len(value)-1:
+		default:
+			return errors.New("must contain only letters, digits, '-' and '_' and start and end with a letter or digit")
+		}
+	}
+
+	return nil
+}
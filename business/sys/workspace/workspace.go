@@ -0,0 +1,17 @@
+// Package workspace provides support for the workspaces which partition the data of the service
+// between tenants. Every link, folder and branded domain belongs to exactly one workspace, and the
+// members of a workspace only ever see what belongs to it.
+package workspace
+
+import "github.com/yashshah7197/shrt/business/sys/validate"
+
+// Default is the workspace everything created before workspaces existed belongs to.
+const Default = "default"
+
+// idMaxLength bounds the length of a workspace ID.
+const idMaxLength = 64
+
+// Validate checks that the given string is a valid workspace ID.
+func Validate(id string) error {
+	return validate.Slug(id, 1, idMaxLength)
+}
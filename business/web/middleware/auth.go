@@ -3,7 +3,6 @@ package middleware
 import (
 	"context"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 
@@ -12,12 +11,18 @@ import (
 	"github.com/yashshah7197/shrt/foundation/web"
 )
 
-// Authenticate validates a JSON Web Token from the 'Authorization' header.
-func Authenticate(a *auth.Auth) web.Middleware {
+// Authenticate validates a JSON Web Token or an API key from the 'Authorization' header. API keys
+// are told apart by their prefix and are only accepted when a key validator is given.
+func Authenticate(a *auth.Auth, keys auth.KeyValidator) web.Middleware {
 	// This is the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
 		// Create the handler that will be attached in the middleware chain.
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			v, err := web.GetValues(ctx)
+			if err != nil {
+				return web.NewShutdownError("web value missing from context")
+			}
+
 			// Expecting: bearer <token>
 			authString := r.Header.Get("Authorization")
 
@@ -28,8 +33,16 @@ func Authenticate(a *auth.Auth) web.Middleware {
 				return validate.NewRequestError(err, http.StatusUnauthorized)
 			}
 
-			// Validate that the token was signed by us.
-			claims, err := a.ValidateToken(parts[1])
+			var claims auth.Claims
+			switch {
+			case strings.HasPrefix(parts[1], auth.KeyPrefix) && keys != nil:
+				// Validate that the key was issued by us and is still live.
+				claims, err = keys.ValidateKey(ctx, parts[1], v.Now)
+
+			default:
+				// Validate that the token was signed by us.
+				claims, err = a.ValidateToken(parts[1])
+			}
 			if err != nil {
 				return validate.NewRequestError(err, http.StatusUnauthorized)
 			}
@@ -56,18 +69,40 @@ func Authorize(roles ...string) web.Middleware {
 			// Ensure that the claims are present in the context.
 			claims, err := auth.GetClaims(ctx)
 			if err != nil {
-				return validate.NewRequestError(
-					fmt.Errorf("you are not authorized for that action"),
-					http.StatusForbidden,
-				)
+				return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
 			}
 
 			// Check that the claims have one of the authorized roles.
 			if !claims.Authorized(roles...) {
-				return validate.NewRequestError(
-					fmt.Errorf("you are not authorized for that action"),
-					http.StatusForbidden,
-				)
+				return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+			}
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// AuthorizeWorkspace validates that an authenticated user is acting within the specified
+// workspace. It guards the settings of the service itself, which belong to no single tenant.
+func AuthorizeWorkspace(workspace string) web.Middleware {
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			// Ensure that the claims are present in the context.
+			claims, err := auth.GetClaims(ctx)
+			if err != nil {
+				return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
+			}
+
+			// Check that the claims are for the authorized workspace.
+			if claims.Workspace != workspace {
+				return validate.NewRequestError(auth.ErrForbidden, http.StatusForbidden)
 			}
 
 			// Call the next handler.
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/yashshah7197/shrt/foundation/web"
+)
+
+// Deadline gives the request the specified time to be read and answered, in place of the read and
+// write timeouts of the server. It is meant for the few routes which take large uploads.
+func Deadline(timeout time.Duration) web.Middleware {
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if err := web.SetDeadline(ctx, time.Now().Add(timeout)); err != nil {
+				return fmt.Errorf("setting deadline: %w", err)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,297 @@
+// Package journal provides an append-only, file-backed log of JSON records that can be replayed on
+// startup and compacted once it grows too large.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Record represents a single entry in the journal. The first record of a batch holds the number of
+// records in the batch.
+type Record struct {
+	Op    string          `json:"op"`
+	Key   string          `json:"key"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Batch int             `json:"batch,omitempty"`
+}
+
+// Journal represents an append-only log of records stored in a single file. It is safe for
+// concurrent use.
+type Journal struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	records int
+}
+
+// Open opens the journal stored at the given path, creating it if it doesn't exist. Every record
+// already in the journal is passed to the replay function in the order it was written. A record
+// left partially written by a crash is discarded, and so is a batch which was not written in full.
+func Open(path string, replay func(Record) error) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating journal directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening journal file: %w", err)
+	}
+
+	j := Journal{
+		path: path,
+		file: file,
+	}
+
+	// Replay the existing records, keeping track of where the last complete record ends. The
+	// records of a batch are held back until the whole batch has been read.
+	var offset int64
+	var batch []Record
+	var batchSize int
+	var batchLen int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("reading journal file: %w", err)
+		}
+
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("decoding journal record at offset %d: %w", offset+batchLen, err)
+		}
+
+		if len(batch) == 0 && rec.Batch > 1 {
+			batchSize = rec.Batch
+		}
+		batch = append(batch, rec)
+		batchLen += int64(len(line))
+		if len(batch) < batchSize {
+			continue
+		}
+
+		for _, rec := range batch {
+			if err := replay(rec); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("replaying journal record at offset %d: %w", offset, err)
+			}
+		}
+
+		offset += batchLen
+		j.records += len(batch)
+		batch, batchSize, batchLen = batch[:0], 0, 0
+	}
+
+	// Drop anything after the last complete record or batch and position the file for appending.
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("truncating journal file: %w", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("seeking journal file: %w", err)
+	}
+
+	return &j, nil
+}
+
+// Append writes a new record to the end of the journal and flushes it to stable storage.
+func (j *Journal) Append(op string, key string, data interface{}) error {
+	line, err := encode(op, key, data)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(line); err != nil {
+		return fmt.Errorf("writing journal record: %w", err)
+	}
+
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("syncing journal file: %w", err)
+	}
+
+	j.records++
+
+	return nil
+}
+
+// AppendBatch writes every record produced by the batch function to the end of the journal and
+// flushes them to stable storage with a single sync. Nothing is written if the batch function
+// fails. A batch is replayed in full or not at all, even if a crash cuts it short.
+func (j *Journal) AppendBatch(batch func(emit func(op string, key string, data interface{}) error) error) error {
+	var recs []Record
+	emit := func(op string, key string, data interface{}) error {
+		rec, err := newRecord(op, key, data)
+		if err != nil {
+			return err
+		}
+
+		recs = append(recs, rec)
+
+		return nil
+	}
+
+	if err := batch(emit); err != nil {
+		return fmt.Errorf("building batch: %w", err)
+	}
+
+	var buf bytes.Buffer
+	for i, rec := range recs {
+		if i == 0 && len(recs) > 1 {
+			rec.Batch = len(recs)
+		}
+
+		line, err := marshal(rec)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+	}
+	records := len(recs)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing journal records: %w", err)
+	}
+
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("syncing journal file: %w", err)
+	}
+
+	j.records += records
+
+	return nil
+}
+
+// Records returns the number of records currently stored in the journal.
+func (j *Journal) Records() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.records
+}
+
+// Compact replaces the contents of the journal with the records produced by the snapshot
+// function. The new journal is written to a temporary file which is then atomically renamed over
+// the old one, so a crash during compaction leaves the old journal intact.
+func (j *Journal) Compact(snapshot func(emit func(op string, key string, data interface{}) error) error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	tmpPath := j.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("creating compaction file: %w", err)
+	}
+
+	// Write out the snapshot into the temporary file.
+	var records int
+	writer := bufio.NewWriter(tmp)
+	emit := func(op string, key string, data interface{}) error {
+		line, err := encode(op, key, data)
+		if err != nil {
+			return err
+		}
+
+		if _, err := writer.Write(line); err != nil {
+			return fmt.Errorf("writing compaction record: %w", err)
+		}
+		records++
+
+		return nil
+	}
+
+	if err := snapshot(emit); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("taking snapshot: %w", err)
+	}
+
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("flushing compaction file: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("syncing compaction file: %w", err)
+	}
+
+	// Swap the compacted file in place of the current journal file.
+	if err := os.Rename(tmpPath, j.path); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("replacing journal file: %w", err)
+	}
+
+	j.file.Close()
+	j.file = tmp
+	j.records = records
+
+	return nil
+}
+
+// Close closes the underlying journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.file.Close()
+}
+
+// encode marshals a record into a single newline terminated line.
+func encode(op string, key string, data interface{}) ([]byte, error) {
+	rec, err := newRecord(op, key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshal(rec)
+}
+
+// newRecord constructs a record holding the given data.
+func newRecord(op string, key string, data interface{}) (Record, error) {
+	rec := Record{
+		Op:  op,
+		Key: key,
+	}
+
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return Record{}, fmt.Errorf("encoding record data: %w", err)
+		}
+		rec.Data = raw
+	}
+
+	return rec, nil
+}
+
+// marshal encodes a record into a single newline terminated line.
+func marshal(rec Record) ([]byte, error) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("encoding record: %w", err)
+	}
+
+	return append(line, '\n'), nil
+}
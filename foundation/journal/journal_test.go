@@ -0,0 +1,266 @@
+package journal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yashshah7197/shrt/foundation/journal"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// entry is the data the records of the tests carry.
+type entry struct {
+	N int `json:"n"`
+}
+
+// replayAll opens the journal at the given path and returns the keys of every record replayed.
+func replayAll(t *testing.T, path string) (*journal.Journal, []string) {
+	t.Helper()
+
+	var keys []string
+	j, err := journal.Open(path, func(rec journal.Record) error {
+		keys = append(keys, rec.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to open the journal: %s.", failed, err)
+	}
+
+	return j, keys
+}
+
+// batchOf returns a batch function which emits a record for every key.
+func batchOf(keys ...string) func(emit func(op string, key string, data interface{}) error) error {
+	return func(emit func(op string, key string, data interface{}) error) error {
+		for i, key := range keys {
+			if err := emit("put", key, entry{N: i}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// equal reports whether two lists of keys are the same.
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReplay(t *testing.T) {
+	t.Log("Given the need to replay the records of a journal.")
+	{
+		path := filepath.Join(t.TempDir(), "data", "test.log")
+
+		j, keys := replayAll(t, path)
+		if len(keys) != 0 {
+			t.Fatalf("\t%s\tShould start with an empty journal, got %v.", failed, keys)
+		}
+		t.Logf("\t%s\tShould start with an empty journal.", success)
+
+		if err := j.Append("put", "a", entry{N: 1}); err != nil {
+			t.Fatalf("\t%s\tShould be able to append a record: %s.", failed, err)
+		}
+		if err := j.AppendBatch(batchOf("b", "c")); err != nil {
+			t.Fatalf("\t%s\tShould be able to append a batch: %s.", failed, err)
+		}
+		if err := j.Append("delete", "a", nil); err != nil {
+			t.Fatalf("\t%s\tShould be able to append a record: %s.", failed, err)
+		}
+		if j.Records() != 4 {
+			t.Fatalf("\t%s\tShould count 4 records, got %d.", failed, j.Records())
+		}
+		t.Logf("\t%s\tShould be able to append records and batches.", success)
+		j.Close()
+
+		var recs []journal.Record
+		j, err := journal.Open(path, func(rec journal.Record) error {
+			recs = append(recs, rec)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to reopen the journal: %s.", failed, err)
+		}
+		defer j.Close()
+
+		if len(recs) != 4 || recs[0].Op != "put" || recs[3].Op != "delete" || recs[3].Data != nil {
+			t.Fatalf("\t%s\tShould replay every record in order, got %+v.", failed, recs)
+		}
+		var e entry
+		if err := json.Unmarshal(recs[2].Data, &e); err != nil || e.N != 1 {
+			t.Fatalf("\t%s\tShould replay the data of a record, got %s.", failed, recs[2].Data)
+		}
+		if j.Records() != 4 {
+			t.Fatalf("\t%s\tShould count the replayed records, got %d.", failed, j.Records())
+		}
+		t.Logf("\t%s\tShould replay every record in order.", success)
+	}
+}
+
+func TestTruncatedTail(t *testing.T) {
+	t.Log("Given the need to recover a journal cut short by a crash.")
+	{
+		tt := []struct {
+			name string
+			cut  func(full []byte, batchStart int) []byte
+			want []string
+		}{
+			{
+				name: "partial record",
+				cut:  func(full []byte, batchStart int) []byte { return append(full, `{"op":"put","key":"d"`...) },
+				want: []string{"a", "b", "c"},
+			},
+			{
+				name: "partial batch",
+				cut: func(full []byte, batchStart int) []byte {
+					// Keep the first record of the last batch and half of the second.
+					rest := full[batchStart:]
+					end := batchStart + bytes.IndexByte(rest, '\n') + 1
+					return full[:end+10]
+				},
+				want: []string{"a"},
+			},
+			{
+				name: "batch missing its last record",
+				cut: func(full []byte, batchStart int) []byte {
+					rest := full[batchStart:]
+					return full[:batchStart+bytes.IndexByte(rest, '\n')+1]
+				},
+				want: []string{"a"},
+			},
+		}
+
+		for testID, tst := range tt {
+			t.Run(tst.name, func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "test.log")
+
+				j, _ := replayAll(t, path)
+				if err := j.Append("put", "a", entry{}); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to append a record: %s.", failed, testID, err)
+				}
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to stat the journal: %s.", failed, testID, err)
+				}
+				batchStart := int(info.Size())
+				if err := j.AppendBatch(batchOf("b", "c")); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to append a batch: %s.", failed, testID, err)
+				}
+				j.Close()
+
+				full, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to read the journal: %s.", failed, testID, err)
+				}
+				if err := os.WriteFile(path, tst.cut(full, batchStart), 0o644); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to cut the journal: %s.", failed, testID, err)
+				}
+
+				j, keys := replayAll(t, path)
+				if !equal(keys, tst.want) {
+					t.Fatalf("\t%s\tTest %d:\tShould replay %v, got %v.", failed, testID, tst.want, keys)
+				}
+				t.Logf("\t%s\tTest %d:\tShould replay %v.", success, testID, tst.want)
+
+				// The journal must be usable again, with the cut records gone for good.
+				if err := j.Append("put", "e", entry{}); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to append after recovering: %s.", failed, testID, err)
+				}
+				j.Close()
+
+				j, keys = replayAll(t, path)
+				defer j.Close()
+				if want := append(append([]string{}, tst.want...), "e"); !equal(keys, want) {
+					t.Fatalf("\t%s\tTest %d:\tShould replay %v after appending, got %v.", failed, testID, want, keys)
+				}
+				t.Logf("\t%s\tTest %d:\tShould drop the cut records for good.", success, testID)
+			})
+		}
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	t.Log("Given the need to stop on a journal which can't be replayed.")
+	{
+		path := filepath.Join(t.TempDir(), "test.log")
+		if err := os.WriteFile(path, []byte("{\"op\":\"put\",\"key\":\"a\"}\nnot json\n"), 0o644); err != nil {
+			t.Fatalf("\t%s\tShould be able to write the journal: %s.", failed, err)
+		}
+
+		if _, err := journal.Open(path, func(journal.Record) error { return nil }); err == nil {
+			t.Fatalf("\t%s\tShould fail on a corrupt record.", failed)
+		}
+		t.Logf("\t%s\tShould fail on a corrupt record.", success)
+
+		errReplay := errors.New("replay failed")
+		if _, err := journal.Open(path, func(journal.Record) error { return errReplay }); !errors.Is(err, errReplay) {
+			t.Fatalf("\t%s\tShould pass on the error of the replay function, got %v.", failed, err)
+		}
+		t.Logf("\t%s\tShould pass on the error of the replay function.", success)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	t.Log("Given the need to compact a journal.")
+	{
+		path := filepath.Join(t.TempDir(), "test.log")
+
+		j, _ := replayAll(t, path)
+		for _, key := range []string{"a", "b", "a", "b", "c"} {
+			if err := j.Append("put", key, entry{}); err != nil {
+				t.Fatalf("\t%s\tShould be able to append a record: %s.", failed, err)
+			}
+		}
+
+		if err := j.Compact(batchOf("a", "b", "c")); err != nil {
+			t.Fatalf("\t%s\tShould be able to compact the journal: %s.", failed, err)
+		}
+		if j.Records() != 3 {
+			t.Fatalf("\t%s\tShould count 3 records after compacting, got %d.", failed, j.Records())
+		}
+		t.Logf("\t%s\tShould be able to compact the journal.", success)
+
+		if err := j.Append("put", "d", entry{}); err != nil {
+			t.Fatalf("\t%s\tShould be able to append after compacting: %s.", failed, err)
+		}
+		j.Close()
+
+		j, keys := replayAll(t, path)
+		if want := []string{"a", "b", "c", "d"}; !equal(keys, want) {
+			t.Fatalf("\t%s\tShould replay %v, got %v.", failed, want, keys)
+		}
+		t.Logf("\t%s\tShould replay the compacted records and those appended since.", success)
+
+		errSnapshot := errors.New("snapshot failed")
+		if err := j.Compact(func(emit func(op string, key string, data interface{}) error) error {
+			emit("put", "x", entry{})
+			return errSnapshot
+		}); !errors.Is(err, errSnapshot) {
+			t.Fatalf("\t%s\tShould fail to compact when the snapshot fails, got %v.", failed, err)
+		}
+		j.Close()
+
+		j, keys = replayAll(t, path)
+		defer j.Close()
+		if want := []string{"a", "b", "c", "d"}; !equal(keys, want) {
+			t.Fatalf("\t%s\tShould leave the journal intact after a failed compaction, got %v.", failed, keys)
+		}
+		t.Logf("\t%s\tShould leave the journal intact after a failed compaction.", success)
+	}
+}
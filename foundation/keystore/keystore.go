@@ -10,27 +10,44 @@ import (
 	"io/fs"
 	"path"
 	"strings"
+	"sync"
 
 	"github.com/lestrrat-go/jwx/jwk"
 )
 
+// These are the states a key in the keystore can be in. A single key is active at a time and signs
+// everything new. Keys which were active before stay around to verify what they signed until it
+// has all expired, after which they are retired and no longer trusted at all.
+const (
+	StateActive     = "active"
+	StateVerifyOnly = "verify-only"
+	StateRetired    = "retired"
+)
+
 // KeyStore represents an in-memory keystore for authentication and authorization.
 type KeyStore struct {
 	store jwk.Set
+
+	mu          sync.RWMutex
+	states      map[string]string
+	activeKeyID string
 }
 
 // New constructs a new, empty KeyStore.
 func New() *KeyStore {
 	return &KeyStore{
-		store: jwk.NewSet(),
+		store:  jwk.NewSet(),
+		states: make(map[string]string),
 	}
 }
 
 // NewFS constructs a new KeyStore based on a set of PEM files rooted inside a directory. The name
-// of each PEM file will be used as the key id for that particular key.
+// of each PEM file will be used as the key id for that particular key. Every key starts off as
+// verify-only.
 func NewFS(fsys fs.FS) (*KeyStore, error) {
 	ks := KeyStore{
-		store: jwk.NewSet(),
+		store:  jwk.NewSet(),
+		states: make(map[string]string),
 	}
 
 	// This is the function that will be used for walking the directory.
@@ -87,7 +104,8 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 	return &ks, nil
 }
 
-// Add adds a private key with its associated key id to the keystore.
+// Add adds a private key with its associated key id to the keystore. The key starts off as
+// verify-only.
 func (ks *KeyStore) Add(privateKey *rsa.PrivateKey, keyID string) error {
 	// Create a new JWK private key from the given private key.
 	jwkPrivateKey, err := jwk.New(privateKey)
@@ -101,14 +119,21 @@ func (ks *KeyStore) Add(privateKey *rsa.PrivateKey, keyID string) error {
 		return fmt.Errorf("setting kid header: %w", err)
 	}
 
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
 	// Add it to our JWK key set.
 	ks.store.Add(jwkPrivateKey)
+	ks.states[keyID] = StateVerifyOnly
 
 	return nil
 }
 
 // Remove removes a private key associated with a given key id from the keystore.
 func (ks *KeyStore) Remove(keyID string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
 	// Check if a key with the given id exists in our key set.
 	privateKey, ok := ks.store.LookupKeyID(keyID)
 	if !ok {
@@ -117,29 +142,116 @@ func (ks *KeyStore) Remove(keyID string) error {
 
 	// Remove the key from the key set.
 	ks.store.Remove(privateKey)
+	delete(ks.states, keyID)
+	if ks.activeKeyID == keyID {
+		ks.activeKeyID = ""
+	}
 
 	return nil
 }
 
+// SetState moves the key associated with a given key id to the given state. Activating a key
+// moves the key which was active before it to verify-only.
+func (ks *KeyStore) SetState(keyID string, state string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, ok := ks.states[keyID]; !ok {
+		return errors.New("no key was found with the given key id")
+	}
+
+	switch state {
+	case StateActive:
+		if ks.activeKeyID != "" && ks.activeKeyID != keyID {
+			ks.states[ks.activeKeyID] = StateVerifyOnly
+		}
+		ks.activeKeyID = keyID
+
+	case StateVerifyOnly, StateRetired:
+		if ks.activeKeyID == keyID {
+			ks.activeKeyID = ""
+		}
+
+	default:
+		return fmt.Errorf("unknown key state %q", state)
+	}
+	ks.states[keyID] = state
+
+	return nil
+}
+
+// State returns the state of the key associated with a given key id.
+func (ks *KeyStore) State(keyID string) (string, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	state, ok := ks.states[keyID]
+	if !ok {
+		return "", errors.New("no key was found with the given key id")
+	}
+
+	return state, nil
+}
+
+// SigningKey returns the active key along with its key id. Both are read under the same lock, so a
+// key rotation can't slip in between.
+func (ks *KeyStore) SigningKey() (string, jwk.Key, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keyID := ks.activeKeyID
+	if keyID == "" {
+		return "", nil, errors.New("no key is active")
+	}
+
+	key, err := ks.privateKey(keyID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return keyID, key, nil
+}
+
 // PrivateKey looks up the keystore for a given key id and returns the corresponding private key.
+// Only the active key may sign, so no other key is handed out.
 func (ks *KeyStore) PrivateKey(keyID string) (jwk.Key, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return ks.privateKey(keyID)
+}
+
+// privateKey looks up the private key of the given key id. The caller must hold the lock.
+func (ks *KeyStore) privateKey(keyID string) (jwk.Key, error) {
 	// Check if a key with the given id exists in our key set.
 	key, ok := ks.store.LookupKeyID(keyID)
 	if !ok {
 		return nil, errors.New("no key was found with the given key id")
 	}
 
+	if ks.states[keyID] != StateActive {
+		return nil, errors.New("the key with the given key id is not active")
+	}
+
 	return key, nil
 }
 
 // PublicKey looks up the keystore for a given key id and returns the corresponding public key.
+// Retired keys are no longer trusted, so they are treated as though they were missing.
 func (ks *KeyStore) PublicKey(keyID string) (jwk.Key, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
 	// Check if a key with the given id exists in our key set.
 	key, ok := ks.store.LookupKeyID(keyID)
 	if !ok {
 		return nil, errors.New("no key was found with the given key id")
 	}
 
+	if ks.states[keyID] == StateRetired {
+		return nil, errors.New("the key with the given key id is retired")
+	}
+
 	publicKey, err := key.PublicKey()
 	if err != nil {
 		return nil, errors.New("could not get public key from the private key")
@@ -0,0 +1,237 @@
+package mmdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// The set of data types in the data section.
+const (
+	typeExtended  = 0
+	typePointer   = 1
+	typeString    = 2
+	typeDouble    = 3
+	typeBytes     = 4
+	typeUint16    = 5
+	typeUint32    = 6
+	typeMap       = 7
+	typeInt32     = 8
+	typeUint64    = 9
+	typeUint128   = 10
+	typeArray     = 11
+	typeContainer = 12
+	typeEndMarker = 13
+	typeBool      = 14
+	typeFloat     = 15
+)
+
+// maxDepth bounds how deeply maps, arrays and pointers may nest, so a corrupt database can't send
+// the decoder into endless recursion.
+const maxDepth = 64
+
+// decoder decodes values out of a data section. Unsigned integers of up to 64 bits are decoded
+// as uint64, signed integers as int64, 128 bit integers as *big.Int, maps as
+// map[string]interface{} and arrays as []interface{}.
+type decoder struct {
+	buf []byte
+}
+
+// decode decodes the value at the given offset and returns it along with the offset just past it.
+func (d decoder) decode(offset int, depth int) (interface{}, int, error) {
+	if depth > maxDepth {
+		return nil, 0, fmt.Errorf("%w: values nested too deeply", ErrInvalidDatabase)
+	}
+
+	ctrl, offset, err := d.byteAt(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	typ := int(ctrl >> 5)
+	if typ == typeExtended {
+		var ext byte
+		ext, offset, err = d.byteAt(offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		typ = 7 + int(ext)
+	}
+
+	if typ == typePointer {
+		target, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		val, _, err := d.decode(target, depth+1)
+		return val, next, err
+	}
+
+	size, offset, err := d.size(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := 0; i < size; i++ {
+			var key, val interface{}
+			key, offset, err = d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("%w: map key is not a string", ErrInvalidDatabase)
+			}
+			val, offset, err = d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[k] = val
+		}
+		return m, offset, nil
+
+	case typeArray:
+		a := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			var val interface{}
+			val, offset, err = d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, val)
+		}
+		return a, offset, nil
+
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	b, next, err := d.bytes(offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+
+	case typeBytes:
+		return append([]byte(nil), b...), next, nil
+
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("%w: double of %d bytes", ErrInvalidDatabase, size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("%w: float of %d bytes", ErrInvalidDatabase, size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("%w: unsigned integer of %d bytes", ErrInvalidDatabase, size)
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, next, nil
+
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("%w: signed integer of %d bytes", ErrInvalidDatabase, size)
+		}
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int64(int32(n)), next, nil
+
+	case typeUint128:
+		if size > 16 {
+			return nil, 0, fmt.Errorf("%w: unsigned integer of %d bytes", ErrInvalidDatabase, size)
+		}
+		return new(big.Int).SetBytes(b), next, nil
+	}
+
+	return nil, 0, fmt.Errorf("%w: unexpected data type %d", ErrInvalidDatabase, typ)
+}
+
+// size reads the size of a value from its control byte and any bytes following it.
+func (d decoder) size(ctrl byte, offset int) (int, int, error) {
+	size := int(ctrl & 0x1F)
+	if size < 29 {
+		return size, offset, nil
+	}
+
+	extra := size - 28
+	b, next, err := d.bytes(offset, extra)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var n int
+	for _, c := range b {
+		n = n<<8 | int(c)
+	}
+
+	switch extra {
+	case 1:
+		return 29 + n, next, nil
+	case 2:
+		return 285 + n, next, nil
+	default:
+		return 65821 + n, next, nil
+	}
+}
+
+// pointer reads the target of a pointer from its control byte and the bytes following it.
+func (d decoder) pointer(ctrl byte, offset int) (int, int, error) {
+	size := int(ctrl>>3)&0x3 + 1
+	b, next, err := d.bytes(offset, size)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	n := 0
+	if size < 4 {
+		n = int(ctrl & 0x7)
+	}
+	for _, c := range b {
+		n = n<<8 | int(c)
+	}
+
+	switch size {
+	case 2:
+		n += 2048
+	case 3:
+		n += 526336
+	}
+
+	return n, next, nil
+}
+
+// byteAt returns the byte at the given offset along with the offset just past it.
+func (d decoder) byteAt(offset int) (byte, int, error) {
+	if offset < 0 || offset >= len(d.buf) {
+		return 0, 0, fmt.Errorf("%w: offset %d out of range", ErrInvalidDatabase, offset)
+	}
+
+	return d.buf[offset], offset + 1, nil
+}
+
+// bytes returns the given number of bytes at the given offset along with the offset just past them.
+func (d decoder) bytes(offset int, n int) ([]byte, int, error) {
+	if offset < 0 || n < 0 || offset+n > len(d.buf) {
+		return nil, 0, fmt.Errorf("%w: %d bytes at offset %d out of range", ErrInvalidDatabase, n, offset)
+	}
+
+	return d.buf[offset : offset+n], offset + n, nil
+}
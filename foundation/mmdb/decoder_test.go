@@ -0,0 +1,100 @@
+package mmdb
+
+import (
+	"errors"
+	"testing"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestPointers(t *testing.T) {
+	t.Log("Given the need to follow pointers to values stored once in the data section.")
+	{
+		tt := []struct {
+			name    string
+			pointer []byte
+			target  int
+		}{
+			{"one byte", []byte{0x20 | 0x3, 0x45}, 0x345},
+			{"two bytes", []byte{0x28 | 0x1, 0x02, 0x03}, 0x10203 + 2048},
+			{"three bytes", []byte{0x30, 0x00, 0x12, 0x34}, 0x1234 + 526336},
+			{"four bytes", []byte{0x38 | 0x7, 0x00, 0x09, 0x00, 0x00}, 0x90000},
+		}
+
+		for testID, tst := range tt {
+			// The pointer is followed by a second value, which must be decoded next.
+			buf := make([]byte, tst.target+3)
+			copy(buf, tst.pointer)
+			copy(buf[len(tst.pointer):], []byte{0x41, 'b'})
+			copy(buf[tst.target:], []byte{0x42, 'h', 'i'})
+
+			d := decoder{buf: buf}
+			val, next, err := d.decode(0, 0)
+			if err != nil || val != "hi" {
+				t.Fatalf("\t%s\tTest %d:\tShould follow a pointer of %s to %q, got %v: %v.", failed, testID, tst.name, "hi", val, err)
+			}
+			if next != len(tst.pointer) {
+				t.Fatalf("\t%s\tTest %d:\tShould continue after the pointer at %d, got %d.", failed, testID, len(tst.pointer), next)
+			}
+			if val, _, err := d.decode(next, 0); err != nil || val != "b" {
+				t.Fatalf("\t%s\tTest %d:\tShould decode the value after the pointer, got %v: %v.", failed, testID, val, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould follow a pointer of %s.", success, testID, tst.name)
+		}
+	}
+}
+
+func TestCorruptData(t *testing.T) {
+	t.Log("Given the need to fail on a corrupt data section rather than crash or hang.")
+	{
+		// nested returns an array holding itself the given number of times.
+		nested := func(depth int) []byte {
+			buf := make([]byte, 0, depth*2+1)
+			for i := 0; i < depth; i++ {
+				buf = append(buf, 0x01, 0x04)
+			}
+			return append(buf, 0x40)
+		}
+
+		tt := []struct {
+			name string
+			buf  []byte
+		}{
+			{"pointer to itself", []byte{0x20, 0x00}},
+			{"pointer out of range", []byte{0x20, 0x10}},
+			{"pointer cut short", []byte{0x28, 0x00}},
+			{"arrays nested too deeply", nested(maxDepth + 1)},
+			{"string cut short", []byte{0x45, 'a', 'b'}},
+			{"size cut short", []byte{0x5E, 0x01}},
+			{"map key not a string", []byte{0xE1, 0xA1, 0x01, 0x42, 'h', 'i'}},
+			{"map value missing", []byte{0xE1, 0x41, 'k'}},
+			{"double of 4 bytes", []byte{0x64, 0, 0, 0, 0}},
+			{"float of 8 bytes", []byte{0x08, 0x08, 0, 0, 0, 0, 0, 0, 0, 0}},
+			{"uint32 of 9 bytes", []byte{0xC9, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+			{"int32 of 5 bytes", []byte{0x05, 0x01, 0, 0, 0, 0, 0}},
+			{"uint128 of 17 bytes", append([]byte{0x11, 0x03}, make([]byte, 17)...)},
+			{"container type", []byte{0x00, 0x05}},
+			{"end marker", []byte{0x00, 0x06}},
+			{"extended type missing", []byte{0x00}},
+			{"nothing at all", nil},
+		}
+
+		for testID, tst := range tt {
+			d := decoder{buf: tst.buf}
+			if _, _, err := d.decode(0, 0); !errors.Is(err, ErrInvalidDatabase) {
+				t.Fatalf("\t%s\tTest %d:\tShould fail on a %s, got %v.", failed, testID, tst.name, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould fail on a %s.", success, testID, tst.name)
+		}
+
+		d := decoder{buf: nested(maxDepth)}
+		if _, _, err := d.decode(0, 0); err != nil {
+			t.Fatalf("\t%s\tShould decode arrays nested %d deep: %s.", failed, maxDepth, err)
+		}
+		t.Logf("\t%s\tShould decode arrays nested %d deep.", success, maxDepth)
+	}
+}
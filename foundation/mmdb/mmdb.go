@@ -0,0 +1,223 @@
+// Package mmdb reads databases in the MaxMind DB format, which maps IP networks to structured
+// records. It is a pure Go implementation of version 2 of the format that decodes records into
+// maps, slices and basic Go values.
+package mmdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+// metadataMarker precedes the metadata section at the end of a database.
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparator is the size of the block of zeros between the search tree and the data
+// section.
+const dataSectionSeparator = 16
+
+// ErrInvalidDatabase is returned when a database is not in the MaxMind DB format or is corrupt.
+var ErrInvalidDatabase = errors.New("invalid maxmind database")
+
+// Metadata describes the contents and layout of a database.
+type Metadata struct {
+	DatabaseType string
+	Languages    []string
+	Description  map[string]string
+	IPVersion    int
+	NodeCount    int
+	RecordSize   int
+	BuildEpoch   uint64
+	MajorVersion int
+	MinorVersion int
+}
+
+// Reader provides lookups against a database held in memory. It is safe for concurrent use.
+type Reader struct {
+	buf       []byte
+	metadata  Metadata
+	data      decoder
+	ipv4Start int
+}
+
+// Open reads the database stored in the file at the given path.
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading database file: %w", err)
+	}
+
+	return FromBytes(buf)
+}
+
+// FromBytes constructs a Reader for the database held in the given buffer. The buffer must not be
+// modified afterwards.
+func FromBytes(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf, metadataMarker)
+	if start == -1 {
+		return nil, fmt.Errorf("%w: metadata section not found", ErrInvalidDatabase)
+	}
+
+	meta := decoder{buf: buf[start+len(metadataMarker):]}
+	raw, _, err := meta.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("decoding metadata: %w", err)
+	}
+
+	metadata, err := parseMetadata(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch metadata.RecordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, metadata.RecordSize)
+	}
+
+	treeSize := metadata.NodeCount * metadata.RecordSize / 4
+	if treeSize+dataSectionSeparator > start {
+		return nil, fmt.Errorf("%w: search tree runs past the data section", ErrInvalidDatabase)
+	}
+
+	r := Reader{
+		buf:      buf,
+		metadata: metadata,
+		data:     decoder{buf: buf[treeSize+dataSectionSeparator : start]},
+	}
+
+	// IPv4 addresses live in the IPv4-compatible range of an IPv6 database, 96 zero bits in.
+	if metadata.IPVersion == 6 {
+		node := 0
+		for i := 0; i < 96 && node < metadata.NodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+
+	return &r, nil
+}
+
+// Metadata returns the metadata of the database.
+func (r *Reader) Metadata() Metadata {
+	return r.metadata
+}
+
+// Lookup finds the record for the network containing the given address. It reports whether the
+// database holds a record for that address at all.
+func (r *Reader) Lookup(ip net.IP) (interface{}, bool, error) {
+	node, bits := r.ipv4Start, 32
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else {
+		if r.metadata.IPVersion == 4 {
+			return nil, false, fmt.Errorf("looking up %s: ipv6 address in an ipv4 database", ip)
+		}
+		node, bits = 0, 128
+		ip = ip.To16()
+	}
+	if ip == nil {
+		return nil, false, errors.New("invalid ip address")
+	}
+
+	nodeCount := r.metadata.NodeCount
+	for i := 0; i < bits && node < nodeCount; i++ {
+		bit := int(ip[i>>3]>>(7-uint(i&7))) & 1
+		node = r.record(node, bit)
+	}
+
+	switch {
+	case node == nodeCount:
+		return nil, false, nil
+
+	case node < nodeCount:
+		return nil, false, fmt.Errorf("%w: search tree deeper than the address", ErrInvalidDatabase)
+	}
+
+	offset := node - nodeCount - dataSectionSeparator
+	val, _, err := r.data.decode(offset, 0)
+	if err != nil {
+		return nil, false, fmt.Errorf("decoding record: %w", err)
+	}
+
+	return val, true, nil
+}
+
+// record returns the left (0) or right (1) record of the given node in the search tree.
+func (r *Reader) record(node int, bit int) int {
+	switch r.metadata.RecordSize {
+	case 24:
+		b := r.buf[node*6+bit*3:]
+		return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+
+	case 28:
+		b := r.buf[node*7:]
+		if bit == 0 {
+			return int(b[3]&0xF0)<<20 | int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+		}
+		return int(b[3]&0x0F)<<24 | int(b[4])<<16 | int(b[5])<<8 | int(b[6])
+
+	default:
+		b := r.buf[node*8+bit*4:]
+		return int(b[0])<<24 | int(b[1])<<16 | int(b[2])<<8 | int(b[3])
+	}
+}
+
+// parseMetadata pulls the metadata fields out of the decoded metadata map.
+func parseMetadata(raw interface{}) (Metadata, error) {
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return Metadata{}, fmt.Errorf("%w: metadata is not a map", ErrInvalidDatabase)
+	}
+
+	var md Metadata
+	var err error
+	uintField := func(key string) int {
+		v, ok := m[key].(uint64)
+		if !ok && err == nil {
+			err = fmt.Errorf("%w: metadata field %q missing", ErrInvalidDatabase, key)
+		}
+		return int(v)
+	}
+
+	md.NodeCount = uintField("node_count")
+	md.RecordSize = uintField("record_size")
+	md.IPVersion = uintField("ip_version")
+	md.MajorVersion = uintField("binary_format_major_version")
+	md.MinorVersion = uintField("binary_format_minor_version")
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	md.DatabaseType, _ = m["database_type"].(string)
+	md.BuildEpoch, _ = m["build_epoch"].(uint64)
+
+	if langs, ok := m["languages"].([]interface{}); ok {
+		for _, l := range langs {
+			if s, ok := l.(string); ok {
+				md.Languages = append(md.Languages, s)
+			}
+		}
+	}
+
+	if desc, ok := m["description"].(map[string]interface{}); ok {
+		md.Description = make(map[string]string, len(desc))
+		for k, v := range desc {
+			if s, ok := v.(string); ok {
+				md.Description[k] = s
+			}
+		}
+	}
+
+	if md.MajorVersion != 2 {
+		return Metadata{}, fmt.Errorf("%w: unsupported format version %d", ErrInvalidDatabase, md.MajorVersion)
+	}
+
+	if md.IPVersion != 4 && md.IPVersion != 6 {
+		return Metadata{}, fmt.Errorf("%w: unsupported ip version %d", ErrInvalidDatabase, md.IPVersion)
+	}
+
+	return md, nil
+}
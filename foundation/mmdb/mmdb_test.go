@@ -0,0 +1,283 @@
+package mmdb_test
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yashshah7197/shrt/foundation/mmdb"
+	"github.com/yashshah7197/shrt/foundation/mmdb/mmdbtest"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// build returns a reader for a database holding the given networks.
+func build(t *testing.T, db *mmdbtest.Database, networks map[string]interface{}) *mmdb.Reader {
+	t.Helper()
+
+	for cidr, rec := range networks {
+		if err := db.Insert(cidr, rec); err != nil {
+			t.Fatalf("\t%s\tShould be able to insert %s: %s.", failed, cidr, err)
+		}
+	}
+
+	r, err := mmdb.FromBytes(db.Bytes())
+	if err != nil {
+		t.Fatalf("\t%s\tShould be able to read the database: %s.", failed, err)
+	}
+
+	return r
+}
+
+func TestLookup(t *testing.T) {
+	t.Log("Given the need to look up the network of an address.")
+	{
+		tt := []struct {
+			ipVersion  int
+			recordSize int
+		}{
+			{4, 24},
+			{4, 28},
+			{4, 32},
+			{6, 24},
+			{6, 28},
+			{6, 32},
+		}
+
+		lookups := []struct {
+			ip    string
+			want  string
+			found bool
+		}{
+			{"1.2.3.4", "1.2.3.0/24", true},
+			{"1.2.3.255", "1.2.3.0/24", true},
+			{"1.2.4.0", "1.2.4.0/23", true},
+			{"1.2.5.200", "1.2.4.0/23", true},
+			{"1.2.6.1", "", false},
+			{"10.200.0.1", "10.0.0.0/8", true},
+			{"11.0.0.1", "", false},
+			{"255.255.255.255", "", false},
+		}
+
+		for testID, tst := range tt {
+			db := mmdbtest.New(tst.ipVersion, tst.recordSize)
+			db.Languages = []string{"en", "de"}
+			db.Description = map[string]string{"en": "Test database"}
+			db.BuildEpoch = 1600000000
+
+			networks := map[string]interface{}{
+				"1.2.3.0/24": map[string]interface{}{"network": "1.2.3.0/24"},
+				"1.2.4.0/23": map[string]interface{}{"network": "1.2.4.0/23"},
+				"10.0.0.0/8": map[string]interface{}{"network": "10.0.0.0/8"},
+			}
+			if tst.ipVersion == 6 {
+				networks["2001:db8::/32"] = map[string]interface{}{"network": "2001:db8::/32"}
+			}
+			r := build(t, db, networks)
+
+			md := r.Metadata()
+			if md.IPVersion != tst.ipVersion || md.RecordSize != tst.recordSize || md.DatabaseType != "Test" ||
+				md.BuildEpoch != 1600000000 || !reflect.DeepEqual(md.Languages, []string{"en", "de"}) ||
+				md.Description["en"] != "Test database" || md.MajorVersion != 2 || md.NodeCount == 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould read the metadata, got %+v.", failed, testID, md)
+			}
+			t.Logf("\t%s\tTest %d:\tShould read the metadata of an ipv%d database with %d bit records.", success, testID, tst.ipVersion, tst.recordSize)
+
+			for _, l := range lookups {
+				rec, found, err := r.Lookup(net.ParseIP(l.ip))
+				if err != nil || found != l.found {
+					t.Fatalf("\t%s\tTest %d:\tShould report %s as found %t, got %t: %v.", failed, testID, l.ip, l.found, found, err)
+				}
+				if found && !reflect.DeepEqual(rec, map[string]interface{}{"network": l.want}) {
+					t.Fatalf("\t%s\tTest %d:\tShould find %s in %s, got %v.", failed, testID, l.ip, l.want, rec)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould find the network of ipv4 addresses.", success, testID)
+
+			rec, found, err := r.Lookup(net.ParseIP("2001:db8:1::1"))
+			switch tst.ipVersion {
+			case 4:
+				if err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould refuse to look up an ipv6 address.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould refuse to look up an ipv6 address.", success, testID)
+
+			case 6:
+				if err != nil || !found || !reflect.DeepEqual(rec, map[string]interface{}{"network": "2001:db8::/32"}) {
+					t.Fatalf("\t%s\tTest %d:\tShould find the network of an ipv6 address, got %v, %t: %v.", failed, testID, rec, found, err)
+				}
+				if _, found, err := r.Lookup(net.ParseIP("2001:db9::1")); err != nil || found {
+					t.Fatalf("\t%s\tTest %d:\tShould not find an ipv6 address outside every network, got %t: %v.", failed, testID, found, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould find the network of ipv6 addresses.", success, testID)
+			}
+		}
+	}
+}
+
+func TestLargeRecords(t *testing.T) {
+	t.Log("Given the need to point at records past the first 16MB of the data section.")
+	{
+		for testID, recordSize := range []int{28, 32} {
+			db := mmdbtest.New(4, recordSize)
+			if err := db.Insert("1.0.0.0/8", make([]byte, 1<<24)); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to insert the padding: %s.", failed, testID, err)
+			}
+			r := build(t, db, map[string]interface{}{"2.0.0.0/8": "far"})
+
+			rec, found, err := r.Lookup(net.ParseIP("2.1.1.1"))
+			if err != nil || !found || rec != "far" {
+				t.Fatalf("\t%s\tTest %d:\tShould find the record, got %v, %t: %v.", failed, testID, rec, found, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould find records past 16MB with %d bit records.", success, testID, recordSize)
+		}
+	}
+}
+
+func TestTypes(t *testing.T) {
+	t.Log("Given the need to decode every type of value.")
+	{
+		long := strings.Repeat("x", 300)
+		longer := strings.Repeat("y", 70000)
+		huge, _ := new(big.Int).SetString("340282366920938463463374607431768211455", 10)
+
+		rec := map[string]interface{}{
+			"string":  "München",
+			"empty":   "",
+			"long":    long,
+			"longer":  longer,
+			"double":  51.5,
+			"float":   float32(0.25),
+			"bytes":   []byte{0, 1, 2},
+			"uint16":  uint16(65535),
+			"uint32":  uint32(4000000000),
+			"uint64":  uint64(1<<64 - 1),
+			"zero":    uint64(0),
+			"int32":   int32(-42),
+			"uint128": huge,
+			"true":    true,
+			"false":   false,
+			"array":   []interface{}{"a", uint32(1), []interface{}{}},
+			"map":     map[string]interface{}{"nested": map[string]interface{}{"deep": "value"}},
+		}
+
+		want := map[string]interface{}{
+			"string":  "München",
+			"empty":   "",
+			"long":    long,
+			"longer":  longer,
+			"double":  51.5,
+			"float":   0.25,
+			"bytes":   []byte{0, 1, 2},
+			"uint16":  uint64(65535),
+			"uint32":  uint64(4000000000),
+			"uint64":  uint64(1<<64 - 1),
+			"zero":    uint64(0),
+			"int32":   int64(-42),
+			"uint128": huge,
+			"true":    true,
+			"false":   false,
+			"array":   []interface{}{"a", uint64(1), []interface{}{}},
+			"map":     map[string]interface{}{"nested": map[string]interface{}{"deep": "value"}},
+		}
+
+		r := build(t, mmdbtest.New(4, 24), map[string]interface{}{"0.0.0.0/1": rec})
+
+		got, found, err := r.Lookup(net.ParseIP("1.1.1.1"))
+		if err != nil || !found {
+			t.Fatalf("\t%s\tShould find the record, got %t: %v.", failed, found, err)
+		}
+
+		m, ok := got.(map[string]interface{})
+		if !ok {
+			t.Fatalf("\t%s\tShould decode the record as a map, got %T.", failed, got)
+		}
+		for k, v := range want {
+			if !reflect.DeepEqual(m[k], v) {
+				t.Fatalf("\t%s\tShould decode %s as %T %v, got %T %v.", failed, k, v, v, m[k], m[k])
+			}
+		}
+		if len(m) != len(want) {
+			t.Fatalf("\t%s\tShould decode %d fields, got %d.", failed, len(want), len(m))
+		}
+		t.Logf("\t%s\tShould decode every type of value.", success)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	t.Log("Given the need to reject databases which are corrupt or not in the format at all.")
+	{
+		valid := mmdbtest.New(4, 24)
+		if err := valid.Insert("1.2.3.0/24", "value"); err != nil {
+			t.Fatalf("\t%s\tShould be able to insert a network: %s.", failed, err)
+		}
+		marker := []byte("\xAB\xCD\xEFMaxMind.com")
+
+		// metadata returns a database with the search tree of the valid one and the given metadata.
+		metadata := func(md map[string]interface{}) []byte {
+			buf := valid.Bytes()
+			buf = buf[:bytes.LastIndex(buf, marker)+len(marker)]
+			return append(buf, mmdbtest.Encode(md)...)
+		}
+		fields := func(change map[string]interface{}) map[string]interface{} {
+			md := map[string]interface{}{
+				"binary_format_major_version": uint16(2),
+				"binary_format_minor_version": uint16(0),
+				"ip_version":                  uint16(4),
+				"node_count":                  uint32(24),
+				"record_size":                 uint16(24),
+			}
+			for k, v := range change {
+				if v == nil {
+					delete(md, k)
+					continue
+				}
+				md[k] = v
+			}
+			return md
+		}
+
+		tt := []struct {
+			name string
+			buf  []byte
+		}{
+			{"empty", nil},
+			{"no metadata", []byte("not a database at all")},
+			{"metadata cut short", valid.Bytes()[:len(valid.Bytes())-10]},
+			{"metadata is a string", append(append([]byte{}, marker...), mmdbtest.Encode("metadata")...)},
+			{"missing node count", metadata(fields(map[string]interface{}{"node_count": nil}))},
+			{"record size", metadata(fields(map[string]interface{}{"record_size": uint16(16)}))},
+			{"format version", metadata(fields(map[string]interface{}{"binary_format_major_version": uint16(3)}))},
+			{"ip version", metadata(fields(map[string]interface{}{"ip_version": uint16(5)}))},
+			{"search tree too large", metadata(fields(map[string]interface{}{"node_count": uint32(1000)}))},
+		}
+
+		for testID, tst := range tt {
+			if _, err := mmdb.FromBytes(tst.buf); !errors.Is(err, mmdb.ErrInvalidDatabase) {
+				t.Fatalf("\t%s\tTest %d:\tShould reject a database with a bad %s, got %v.", failed, testID, tst.name, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject a database with a bad %s.", success, testID, tst.name)
+		}
+
+		// A data section cut short is only noticed when a record in it is looked up.
+		buf := valid.Bytes()
+		at := bytes.LastIndex(buf, marker)
+		cut := append(append([]byte{}, buf[:at-3]...), buf[at:]...)
+		r, err := mmdb.FromBytes(cut)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to read a database with a short data section: %s.", failed, err)
+		}
+		if _, _, err := r.Lookup(net.ParseIP("1.2.3.4")); !errors.Is(err, mmdb.ErrInvalidDatabase) {
+			t.Fatalf("\t%s\tShould fail to decode a record cut short, got %v.", failed, err)
+		}
+		t.Logf("\t%s\tShould fail to decode a record cut short.", success)
+	}
+}
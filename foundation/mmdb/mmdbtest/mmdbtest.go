@@ -0,0 +1,264 @@
+// Package mmdbtest builds small databases in the MaxMind DB format, for testing code which reads
+// them.
+package mmdbtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"sort"
+)
+
+// metadataMarker precedes the metadata section at the end of a database.
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparator is the size of the block of zeros between the search tree and the data
+// section.
+const dataSectionSeparator = 16
+
+// Database is a database under construction. Records may be maps, slices, strings, []byte, bool,
+// float32, float64, int32, uint16, uint32, uint64 and *big.Int values, nested as deeply as needed.
+type Database struct {
+	IPVersion    int
+	RecordSize   int
+	DatabaseType string
+	Languages    []string
+	Description  map[string]string
+	BuildEpoch   uint64
+
+	nodes [][2]child
+	data  []byte
+}
+
+// child is one of the two records of a node in the search tree.
+type child struct {
+	kind  int
+	value int
+}
+
+// The set of things a record of the search tree can point at.
+const (
+	empty = iota
+	node
+	data
+)
+
+// New constructs an empty database for addresses of the given IP version, with search tree
+// records of the given size in bits.
+func New(ipVersion int, recordSize int) *Database {
+	return &Database{
+		IPVersion:    ipVersion,
+		RecordSize:   recordSize,
+		DatabaseType: "Test",
+		nodes:        make([][2]child, 1),
+	}
+}
+
+// Insert maps every address in the given network, in CIDR notation, to the given record.
+// IPv4 networks in an IPv6 database are inserted into the IPv4-compatible range.
+func (db *Database) Insert(cidr string, rec interface{}) error {
+	ip, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+
+	prefix, _ := network.Mask.Size()
+	addr := network.IP
+	if ip.To4() != nil && db.IPVersion == 6 {
+		addr = network.IP.To16()
+		addr[10], addr[11] = 0, 0
+		prefix += 96
+	}
+
+	offset := len(db.data)
+	db.data = append(db.data, Encode(rec)...)
+
+	n := 0
+	for i := 0; i < prefix; i++ {
+		bit := int(addr[i>>3]>>(7-uint(i&7))) & 1
+		if i == prefix-1 {
+			db.nodes[n][bit] = child{kind: data, value: offset}
+			break
+		}
+
+		switch next := db.nodes[n][bit]; next.kind {
+		case node:
+			n = next.value
+		case data:
+			return fmt.Errorf("network %s overlaps one already inserted", cidr)
+		default:
+			db.nodes = append(db.nodes, [2]child{})
+			db.nodes[n][bit] = child{kind: node, value: len(db.nodes) - 1}
+			n = len(db.nodes) - 1
+		}
+	}
+
+	return nil
+}
+
+// Bytes returns the database in the MaxMind DB format.
+func (db *Database) Bytes() []byte {
+	count := len(db.nodes)
+
+	var buf bytes.Buffer
+	for _, n := range db.nodes {
+		var records [2]uint32
+		for bit, c := range n {
+			switch c.kind {
+			case empty:
+				records[bit] = uint32(count)
+			case node:
+				records[bit] = uint32(c.value)
+			case data:
+				records[bit] = uint32(count + dataSectionSeparator + c.value)
+			}
+		}
+
+		left, right := records[0], records[1]
+		switch db.RecordSize {
+		case 24:
+			buf.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left)})
+			buf.Write([]byte{byte(right >> 16), byte(right >> 8), byte(right)})
+		case 28:
+			buf.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left)})
+			buf.WriteByte(byte(left>>20)&0xF0 | byte(right>>24)&0x0F)
+			buf.Write([]byte{byte(right >> 16), byte(right >> 8), byte(right)})
+		default:
+			binary.Write(&buf, binary.BigEndian, records)
+		}
+	}
+
+	buf.Write(make([]byte, dataSectionSeparator))
+	buf.Write(db.data)
+
+	languages := make([]interface{}, len(db.Languages))
+	for i, l := range db.Languages {
+		languages[i] = l
+	}
+	description := make(map[string]interface{}, len(db.Description))
+	for k, v := range db.Description {
+		description[k] = v
+	}
+
+	buf.Write(metadataMarker)
+	buf.Write(Encode(map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 db.BuildEpoch,
+		"database_type":               db.DatabaseType,
+		"description":                 description,
+		"ip_version":                  uint16(db.IPVersion),
+		"languages":                   languages,
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(db.RecordSize),
+	}))
+
+	return buf.Bytes()
+}
+
+// Encode returns the given value in the encoding of the data section. It panics on values of
+// types the format has no encoding for.
+func Encode(v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return append(control(2, len(v)), v...)
+
+	case float64:
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, math.Float64bits(v))
+		return append(control(3, 8), b...)
+
+	case []byte:
+		return append(control(4, len(v)), v...)
+
+	case uint16:
+		return unsigned(5, uint64(v))
+
+	case uint32:
+		return unsigned(6, uint64(v))
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b := control(7, len(v))
+		for _, k := range keys {
+			b = append(b, Encode(k)...)
+			b = append(b, Encode(v[k])...)
+		}
+		return b
+
+	case int32:
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(v))
+		return append(control(8, 4), b...)
+
+	case uint64:
+		return unsigned(9, v)
+
+	case *big.Int:
+		b := v.Bytes()
+		return append(control(10, len(b)), b...)
+
+	case []interface{}:
+		b := control(11, len(v))
+		for _, e := range v {
+			b = append(b, Encode(e)...)
+		}
+		return b
+
+	case bool:
+		if v {
+			return control(14, 1)
+		}
+		return control(14, 0)
+
+	case float32:
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, math.Float32bits(v))
+		return append(control(15, 4), b...)
+	}
+
+	panic(fmt.Sprintf("mmdbtest: can't encode a value of type %T", v))
+}
+
+// unsigned returns an unsigned integer of the given type in as few bytes as it fits in.
+func unsigned(typ int, n uint64) []byte {
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+
+	return append(control(typ, len(b)), b...)
+}
+
+// control returns the control byte, and any bytes following it, of a value of the given type
+// and size.
+func control(typ int, size int) []byte {
+	var b []byte
+	switch {
+	case size < 29:
+		b = []byte{byte(size)}
+	case size < 285:
+		b = []byte{29, byte(size - 29)}
+	case size < 65821:
+		size -= 285
+		b = []byte{30, byte(size >> 8), byte(size)}
+	default:
+		size -= 65821
+		b = []byte{31, byte(size >> 16), byte(size >> 8), byte(size)}
+	}
+
+	if typ <= 7 {
+		b[0] |= byte(typ) << 5
+		return b
+	}
+
+	return append([]byte{b[0]}, append([]byte{byte(typ - 7)}, b[1:]...)...)
+}
@@ -0,0 +1,538 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// formatInfo lists the format information of every level and mask as published in ISO/IEC 18004,
+// masked and in the order the bits are drawn.
+var formatInfo = map[int]struct {
+	level Level
+	mask  int
+}{
+	0x77C4: {Low, 0}, 0x72F3: {Low, 1}, 0x7DAA: {Low, 2}, 0x789D: {Low, 3},
+	0x662F: {Low, 4}, 0x6318: {Low, 5}, 0x6C41: {Low, 6}, 0x6976: {Low, 7},
+	0x5412: {Medium, 0}, 0x5125: {Medium, 1}, 0x5E7C: {Medium, 2}, 0x5B4B: {Medium, 3},
+	0x45F9: {Medium, 4}, 0x40CE: {Medium, 5}, 0x4F97: {Medium, 6}, 0x4AA0: {Medium, 7},
+	0x355F: {Quartile, 0}, 0x3068: {Quartile, 1}, 0x3F31: {Quartile, 2}, 0x3A06: {Quartile, 3},
+	0x24B4: {Quartile, 4}, 0x2183: {Quartile, 5}, 0x2EDA: {Quartile, 6}, 0x2BED: {Quartile, 7},
+	0x1689: {High, 0}, 0x13BE: {High, 1}, 0x1CE7: {High, 2}, 0x19D0: {High, 3},
+	0x0762: {High, 4}, 0x0255: {High, 5}, 0x0D0C: {High, 6}, 0x083B: {High, 7},
+}
+
+// versionInfo lists the version information of some versions as published in ISO/IEC 18004.
+var versionInfo = map[int]int{
+	7:  0x07C94,
+	8:  0x085BC,
+	10: 0x0A4D3,
+	20: 0x149A6,
+	40: 0x28C69,
+}
+
+func TestCapacity(t *testing.T) {
+	t.Log("Given the need to pick the smallest version which holds the data.")
+	{
+		// The byte mode capacities of ISO/IEC 18004, table 7.
+		tt := []struct {
+			level    Level
+			version  int
+			capacity int
+		}{
+			{Low, 1, 17},
+			{Medium, 1, 14},
+			{Quartile, 1, 11},
+			{High, 1, 7},
+			{Low, 2, 32},
+			{High, 5, 44},
+			{Low, 7, 154},
+			{Quartile, 9, 130},
+			{Low, 10, 271},
+			{High, 10, 119},
+			{Medium, 27, 1125},
+			{Low, 40, 2953},
+			{Medium, 40, 2331},
+			{Quartile, 40, 1663},
+			{High, 40, 1273},
+		}
+
+		for testID, tst := range tt {
+			c, err := Encode(make([]byte, tst.capacity), tst.level)
+			if err != nil || c.Version() != tst.version {
+				t.Fatalf("\t%s\tTest %d:\tShould fit %d bytes at level %s in version %d, got version %d: %v.", failed, testID, tst.capacity, tst.level, tst.version, versionOf(c), err)
+			}
+			if c.Size() != tst.version*4+17 {
+				t.Fatalf("\t%s\tTest %d:\tShould be %d modules wide, got %d.", failed, testID, tst.version*4+17, c.Size())
+			}
+			t.Logf("\t%s\tTest %d:\tShould fit %d bytes at level %s in version %d.", success, testID, tst.capacity, tst.level, tst.version)
+
+			c, err = Encode(make([]byte, tst.capacity+1), tst.level)
+			switch {
+			case tst.version == maxVersion:
+				if !errors.Is(err, ErrTooLong) {
+					t.Fatalf("\t%s\tTest %d:\tShould not fit %d bytes at level %s, got %v.", failed, testID, tst.capacity+1, tst.level, err)
+				}
+			case err != nil || c.Version() != tst.version+1:
+				t.Fatalf("\t%s\tTest %d:\tShould fit %d bytes at level %s in version %d, got version %d: %v.", failed, testID, tst.capacity+1, tst.level, tst.version+1, versionOf(c), err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould move on from version %d for %d bytes.", success, testID, tst.version, tst.capacity+1)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Log("Given the need to encode data which scanners can read back.")
+	{
+		binary := make([]byte, 256)
+		for i := range binary {
+			binary[i] = byte(i)
+		}
+
+		tt := []struct {
+			name string
+			data []byte
+		}{
+			{"empty", nil},
+			{"short url", []byte("https://shrt.example/aB3dE9x")},
+			{"long url", []byte("https://example.com/some/rather/long/path?with=query&and=parameters#fragment")},
+			{"binary", binary},
+			{"version 7", bytes.Repeat([]byte("x"), 120)},
+			{"largest", bytes.Repeat([]byte("qr"), 636)},
+		}
+
+		for testID, tst := range tt {
+			for _, level := range []Level{Low, Medium, Quartile, High} {
+				t.Run(tst.name+"/"+level.String(), func(t *testing.T) {
+					c, err := Encode(tst.data, level)
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to encode the data: %s.", failed, testID, err)
+					}
+
+					data, gotLevel := decode(t, c)
+					if gotLevel != level {
+						t.Fatalf("\t%s\tTest %d:\tShould carry level %s in the format information, got %s.", failed, testID, level, gotLevel)
+					}
+					if !bytes.Equal(data, tst.data) {
+						t.Fatalf("\t%s\tTest %d:\tShould read back the data, got %q.", failed, testID, data)
+					}
+					t.Logf("\t%s\tTest %d:\tShould read back the data from version %d.", success, testID, c.Version())
+				})
+			}
+		}
+	}
+}
+
+func TestFunctionPatterns(t *testing.T) {
+	t.Log("Given the need to draw the patterns scanners find a symbol by.")
+	{
+		for testID, version := range []int{1, 2, 7, 8, 10, 20, 40} {
+			c, err := Encode(make([]byte, capacityOf(version, Low)), Low)
+			if err != nil || c.Version() != version {
+				t.Fatalf("\t%s\tTest %d:\tShould encode a symbol of version %d, got version %d: %v.", failed, testID, version, versionOf(c), err)
+			}
+			size := c.Size()
+
+			// Every finder is a dark ring around a light ring around a dark 3x3 square, with a
+			// light separator on its inner sides.
+			for _, corner := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+				for dy := -4; dy <= 4; dy++ {
+					for dx := -4; dx <= 4; dx++ {
+						x, y := corner[0]+dx, corner[1]+dy
+						if x < 0 || y < 0 || x >= size || y >= size {
+							continue
+						}
+						ring := max(abs(dx), abs(dy))
+						if want := ring != 2 && ring != 4; c.Dark(x, y) != want {
+							t.Fatalf("\t%s\tTest %d:\tShould draw the finder at %v, module (%d,%d) is wrong.", failed, testID, corner, x, y)
+						}
+					}
+				}
+			}
+
+			for i := 8; i < size-8; i++ {
+				if c.Dark(i, 6) != (i%2 == 0) || c.Dark(6, i) != (i%2 == 0) {
+					t.Fatalf("\t%s\tTest %d:\tShould draw the timing patterns, module %d is wrong.", failed, testID, i)
+				}
+			}
+
+			if !c.Dark(8, size-8) {
+				t.Fatalf("\t%s\tTest %d:\tShould draw the dark module.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould draw the finder and timing patterns of version %d.", success, testID, version)
+
+			if want, ok := versionInfo[version]; ok {
+				var first, second int
+				for i := 17; i >= 0; i-- {
+					a, b := size-11+i%3, i/3
+					first = first<<1 | boolInt(c.Dark(a, b))
+					second = second<<1 | boolInt(c.Dark(b, a))
+				}
+				if first != want || second != want {
+					t.Fatalf("\t%s\tTest %d:\tShould draw version information %05X, got %05X and %05X.", failed, testID, want, first, second)
+				}
+				t.Logf("\t%s\tTest %d:\tShould draw version information %05X.", success, testID, want)
+			}
+		}
+
+		// The alignment pattern centres of ISO/IEC 18004, annex E.
+		positions := map[int][]int{
+			1:  nil,
+			2:  {6, 18},
+			7:  {6, 22, 38},
+			14: {6, 26, 46, 66},
+			32: {6, 34, 60, 86, 112, 138},
+			36: {6, 24, 50, 76, 102, 128, 154},
+			40: {6, 30, 58, 86, 114, 142, 170},
+		}
+		for version, want := range positions {
+			if got := alignmentPositions(version); !equalInts(got, want) {
+				t.Fatalf("\t%s\tShould centre the alignment patterns of version %d on %v, got %v.", failed, version, want, got)
+			}
+		}
+		t.Logf("\t%s\tShould centre the alignment patterns where the standard puts them.", success)
+	}
+}
+
+func TestRender(t *testing.T) {
+	t.Log("Given the need to render a symbol as an image.")
+	{
+		c, err := Encode([]byte("https://shrt.example/abc"), Medium)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to encode the data: %s.", failed, err)
+		}
+		style := Style{Size: 200, Margin: 4, Foreground: color.NRGBA{A: 0xFF}, Background: color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}}
+
+		img, err := c.Image(style)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to render the image: %s.", failed, err)
+		}
+		if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 200 {
+			t.Fatalf("\t%s\tShould render an image of 200 pixels, got %v.", failed, b)
+		}
+
+		// Every module is drawn with the same number of pixels, centred in the image.
+		modules := c.Modules(style)
+		scale := style.Size / modules
+		offset := (style.Size-scale*modules)/2 + style.Margin*scale
+		for y := 0; y < c.Size(); y++ {
+			for x := 0; x < c.Size(); x++ {
+				r, _, _, _ := img.At(offset+x*scale+scale/2, offset+y*scale+scale/2).RGBA()
+				if (r == 0) != c.Dark(x, y) {
+					t.Fatalf("\t%s\tShould render module (%d,%d) in its colour.", failed, x, y)
+				}
+			}
+		}
+		t.Logf("\t%s\tShould render every module in its colour.", success)
+
+		var buf bytes.Buffer
+		if err := c.WritePNG(&buf, style); err != nil || !bytes.HasPrefix(buf.Bytes(), []byte("\x89PNG")) {
+			t.Fatalf("\t%s\tShould be able to write a png: %v.", failed, err)
+		}
+		buf.Reset()
+		if err := c.WriteSVG(&buf, style); err != nil || !strings.HasPrefix(buf.String(), "<svg") || !strings.HasSuffix(buf.String(), "</svg>") {
+			t.Fatalf("\t%s\tShould be able to write an svg: %v.", failed, err)
+		}
+		t.Logf("\t%s\tShould be able to write a png and an svg.", success)
+
+		style.Size = modules - 1
+		if _, err := c.Image(style); !errors.Is(err, ErrTooSmall) {
+			t.Fatalf("\t%s\tShould refuse to render fewer pixels than modules, got %v.", failed, err)
+		}
+		if err := c.WriteSVG(&buf, style); !errors.Is(err, ErrTooSmall) {
+			t.Fatalf("\t%s\tShould refuse to write an svg smaller than the modules, got %v.", failed, err)
+		}
+		t.Logf("\t%s\tShould refuse to render fewer pixels than modules.", success)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Log("Given the need to parse the settings of a symbol.")
+	{
+		levels := map[string]Level{"L": Low, "m": Medium, "Q": Quartile, "h": High}
+		for s, want := range levels {
+			if got, err := ParseLevel(s); err != nil || got != want {
+				t.Fatalf("\t%s\tShould parse level %q as %s, got %s: %v.", failed, s, want, got, err)
+			}
+		}
+		if _, err := ParseLevel("X"); err == nil {
+			t.Fatalf("\t%s\tShould reject an unknown level.", failed)
+		}
+		t.Logf("\t%s\tShould parse the error correction levels.", success)
+
+		colours := []struct {
+			s    string
+			want color.NRGBA
+			ok   bool
+		}{
+			{"#000", color.NRGBA{A: 0xFF}, true},
+			{"fff", color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}, true},
+			{"#1a2B3c", color.NRGBA{R: 0x1A, G: 0x2B, B: 0x3C, A: 0xFF}, true},
+			{"1a2b3c80", color.NRGBA{R: 0x1A, G: 0x2B, B: 0x3C, A: 0x80}, true},
+			{"#12345", color.NRGBA{}, false},
+			{"ggg", color.NRGBA{}, false},
+		}
+		for testID, tst := range colours {
+			got, err := ParseColor(tst.s)
+			if (err == nil) != tst.ok || got != tst.want {
+				t.Fatalf("\t%s\tTest %d:\tShould parse %q as %v, got %v: %v.", failed, testID, tst.s, tst.want, got, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould parse %q.", success, testID, tst.s)
+		}
+	}
+}
+
+// =================================================================================================
+
+// decode reads the data and error correction level back out of a symbol the way a scanner does,
+// failing the test if any block doesn't check out.
+func decode(t *testing.T, c *Code) ([]byte, Level) {
+	t.Helper()
+	size := c.Size()
+
+	// Read both copies of the format information.
+	var first, second int
+	for i := 14; i >= 0; i-- {
+		var x, y int
+		switch {
+		case i <= 5:
+			x, y = 8, i
+		case i == 6:
+			x, y = 8, 7
+		case i == 7:
+			x, y = 8, 8
+		case i == 8:
+			x, y = 7, 8
+		default:
+			x, y = 14-i, 8
+		}
+		first = first<<1 | boolInt(c.Dark(x, y))
+
+		if i < 8 {
+			x, y = size-1-i, 8
+		} else {
+			x, y = 8, size-15+i
+		}
+		second = second<<1 | boolInt(c.Dark(x, y))
+	}
+
+	format, ok := formatInfo[first]
+	if !ok || first != second {
+		t.Fatalf("\t%s\tShould carry valid format information, got %04X and %04X.", failed, first, second)
+	}
+
+	// Find the modules which carry data by drawing the function patterns of the version.
+	blank := Code{
+		version:    c.Version(),
+		level:      format.level,
+		size:       size,
+		modules:    make([]bool, size*size),
+		isFunction: make([]bool, size*size),
+	}
+	blank.drawFunctionPatterns()
+
+	// Read the codewords, undoing the mask on the way.
+	var raw []byte
+	var cur byte
+	var n int
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < size; vert++ {
+			y := vert
+			if upward {
+				y = size - 1 - vert
+			}
+			for x := right; x >= right-1; x-- {
+				if blank.isFunction[y*size+x] {
+					continue
+				}
+				cur = cur<<1 | byte(boolInt(c.Dark(x, y) != masked(format.mask, x, y)))
+				if n++; n%8 == 0 {
+					raw = append(raw, cur)
+					cur = 0
+				}
+			}
+		}
+	}
+
+	// Undo the interleaving of the blocks and check every block against its error correction.
+	version := c.Version()
+	blocks := numBlocks[format.level][version]
+	eccLen := eccPerBlock[format.level][version]
+	total := numRawDataModules(version) / 8
+	if len(raw) != total {
+		t.Fatalf("\t%s\tShould read %d codewords, got %d.", failed, total, len(raw))
+	}
+	numShort := blocks - total%blocks
+	shortData := total/blocks - eccLen
+
+	dataLen := func(block int) int {
+		if block < numShort {
+			return shortData
+		}
+		return shortData + 1
+	}
+
+	data := make([][]byte, blocks)
+	k := 0
+	for i := 0; i <= shortData; i++ {
+		for b := 0; b < blocks; b++ {
+			if i < dataLen(b) {
+				data[b] = append(data[b], raw[k])
+				k++
+			}
+		}
+	}
+	ecc := make([][]byte, blocks)
+	for i := 0; i < eccLen; i++ {
+		for b := 0; b < blocks; b++ {
+			ecc[b] = append(ecc[b], raw[k])
+			k++
+		}
+	}
+
+	var codewords []byte
+	for b := 0; b < blocks; b++ {
+		if !checkBlock(append(append([]byte{}, data[b]...), ecc[b]...), eccLen) {
+			t.Fatalf("\t%s\tShould carry valid error correction in block %d.", failed, b)
+		}
+		codewords = append(codewords, data[b]...)
+	}
+
+	// Parse the byte mode segment and make sure only padding follows it.
+	bits := func(from, n int) int {
+		var v int
+		for i := from; i < from+n; i++ {
+			v = v<<1 | int(codewords[i/8]>>(7-uint(i%8))&1)
+		}
+		return v
+	}
+	if mode := bits(0, 4); mode != 0x4 {
+		t.Fatalf("\t%s\tShould encode in byte mode, got mode %X.", failed, mode)
+	}
+	countLen := 8
+	if version >= 10 {
+		countLen = 16
+	}
+	count := bits(4, countLen)
+	result := make([]byte, count)
+	for i := range result {
+		result[i] = byte(bits(4+countLen+i*8, 8))
+	}
+
+	end := (4 + countLen + count*8 + 4 + 7) / 8
+	if 4+countLen+count*8+4 > len(codewords)*8 {
+		end = len(codewords)
+	}
+	for i, pad := end, byte(0xEC); i < len(codewords); i, pad = i+1, pad^0xEC^0x11 {
+		if codewords[i] != pad {
+			t.Fatalf("\t%s\tShould pad the data with alternating codewords, got %X at %d.", failed, codewords[i], i)
+		}
+	}
+
+	return result, format.level
+}
+
+// masked reports whether the given mask flips the module at the given column and row, following
+// the mask conditions of ISO/IEC 18004, table 10, which are given as row i and column j.
+func masked(mask int, j, i int) bool {
+	switch mask {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return (i*j)%2+(i*j)%3 == 0
+	case 6:
+		return ((i*j)%2+(i*j)%3)%2 == 0
+	default:
+		return ((i*j)%3+(i+j)%2)%2 == 0
+	}
+}
+
+// checkBlock reports whether every syndrome of a block of data and error correction codewords is
+// zero, which it is for every block without errors.
+func checkBlock(block []byte, eccLen int) bool {
+	var exp [512]byte
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = byte(x), byte(x)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	var log [256]int
+	for i := 0; i < 255; i++ {
+		log[exp[i]] = i
+	}
+	mul := func(a, b byte) byte {
+		if a == 0 || b == 0 {
+			return 0
+		}
+		return exp[log[a]+log[b]]
+	}
+
+	for i := 0; i < eccLen; i++ {
+		var s byte
+		for _, c := range block {
+			s = mul(s, exp[i]) ^ c
+		}
+		if s != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// capacityOf returns the number of bytes a symbol of the given version and level holds.
+func capacityOf(version int, level Level) int {
+	return (numDataCodewords(version, level)*8 - 4 - countBits(version)) / 8
+}
+
+// boolInt returns 1 for true and 0 for false.
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// equalInts reports whether two slices hold the same values.
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// versionOf returns the version of a symbol, or zero if there is none.
+func versionOf(c *Code) int {
+	if c == nil {
+		return 0
+	}
+	return c.Version()
+}
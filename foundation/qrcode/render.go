@@ -0,0 +1,137 @@
+package qrcode
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrTooSmall is returned when the requested size leaves less than a pixel for each module.
+var ErrTooSmall = errors.New("size too small for the qr code")
+
+// Style represents how a symbol is rendered. Size is the width and height of the image in pixels
+// and Margin the width of the quiet zone around the symbol in modules.
+type Style struct {
+	Size       int
+	Margin     int
+	Foreground color.NRGBA
+	Background color.NRGBA
+}
+
+// Modules returns the number of modules along each side of the rendered image, quiet zone included.
+func (c *Code) Modules(style Style) int {
+	return c.size + 2*style.Margin
+}
+
+// Image renders the symbol as an image of the size of the style. Every module is drawn with the
+// same whole number of pixels and whatever is left over is spread around the quiet zone, so the
+// symbol stays crisp at any size.
+func (c *Code) Image(style Style) (image.Image, error) {
+	modules := c.Modules(style)
+	scale := style.Size / modules
+	if scale < 1 {
+		return nil, ErrTooSmall
+	}
+	offset := (style.Size-scale*modules)/2 + style.Margin*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, style.Size, style.Size), color.Palette{style.Background, style.Foreground})
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.Dark(x, y) {
+				continue
+			}
+			for py := 0; py < scale; py++ {
+				row := img.Pix[(offset+y*scale+py)*img.Stride:]
+				for px := 0; px < scale; px++ {
+					row[offset+x*scale+px] = 1
+				}
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// WritePNG renders the symbol as a PNG image of the size of the style.
+func (c *Code) WritePNG(w io.Writer, style Style) error {
+	img, err := c.Image(style)
+	if err != nil {
+		return err
+	}
+
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(w, img); err != nil {
+		return fmt.Errorf("encoding png: %w", err)
+	}
+
+	return nil
+}
+
+// WriteSVG renders the symbol as an SVG image of the size of the style. The image is laid out in
+// modules and scaled to the size, so it can be resized without losing any sharpness. The dark
+// modules of each row are merged into runs to keep the path short.
+func (c *Code) WriteSVG(w io.Writer, style Style) error {
+	modules := c.Modules(style)
+	if style.Size < modules {
+		return ErrTooSmall
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, style.Size, style.Size, modules, modules)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d"%s/>`, modules, modules, svgFill(style.Background))
+	fmt.Fprintf(bw, `<path%s d="`, svgFill(style.Foreground))
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.Dark(x, y) {
+				continue
+			}
+			run := 1
+			for c.Dark(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(bw, "M%d %dh%dv1h-%dz", x+style.Margin, y+style.Margin, run, run)
+			x += run
+		}
+	}
+	bw.WriteString(`"/></svg>`)
+
+	return bw.Flush()
+}
+
+// svgFill returns the fill attributes for the given colour.
+func svgFill(c color.NRGBA) string {
+	fill := fmt.Sprintf(` fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A < 0xFF {
+		fill += fmt.Sprintf(` fill-opacity="%s"`, strconv.FormatFloat(float64(c.A)/0xFF, 'f', 3, 64))
+	}
+
+	return fill
+}
+
+// ParseColor parses a colour written as hex digits in the form RGB, RRGGBB or RRGGBBAA, with or
+// without a leading #.
+func ParseColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	switch len(hex) {
+	case 3:
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]}) + "ff"
+	case 6:
+		hex += "ff"
+	case 8:
+	default:
+		return color.NRGBA{}, fmt.Errorf("invalid colour %q", s)
+	}
+
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid colour %q", s)
+	}
+
+	return color.NRGBA{R: uint8(n >> 24), G: uint8(n >> 16), B: uint8(n >> 8), A: uint8(n)}, nil
+}
@@ -0,0 +1,85 @@
+// Package ratelimit counts events per key over a fixed window so callers can cut off keys which
+// produce too many of them, such as repeated failed attempts at guessing a password.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// window tracks the events recorded for a single key.
+type window struct {
+	start time.Time
+	count int
+}
+
+// Limiter allows up to a limit of events per key within a window of time. The window of a key
+// starts with its first event, so a key that hits the limit is cut off until that window has
+// passed. It is safe for concurrent use.
+type Limiter struct {
+	mu        sync.Mutex
+	limit     int
+	period    time.Duration
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+// New constructs a Limiter which allows up to limit events per key within the given period.
+func New(limit int, period time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		period:  period,
+		windows: make(map[string]*window),
+	}
+}
+
+// Take counts an event for the key at the given time and reports whether it is within the limit
+// of the current window of the key. Checking and counting happen together, so concurrent callers
+// can't all get in under the limit before any of them is counted.
+func (l *Limiter) Take(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	w, exists := l.windows[key]
+	if !exists || l.expired(w, now) {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+
+	return true
+}
+
+// Reset forgets every event recorded for the key.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.windows, key)
+}
+
+// expired reports whether the window has passed at the given time.
+func (l *Limiter) expired(w *window, now time.Time) bool {
+	return !now.Before(w.start.Add(l.period))
+}
+
+// sweep drops the windows which have passed, at most once per period, so that keys which are never
+// seen again don't pile up. The caller must hold the lock.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.period {
+		return
+	}
+	l.lastSweep = now
+
+	for key, w := range l.windows {
+		if l.expired(w, now) {
+			delete(l.windows, key)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package realip works out the address of the client behind a request, looking through the
+// X-Forwarded-For header only when the request came in through a trusted proxy.
+package realip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolver resolves the client address of requests given the networks of the trusted proxies.
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// NewResolver constructs a Resolver which trusts proxies within the given CIDR blocks. Without any
+// trusted proxies the address of the peer is always taken as the client address.
+func NewResolver(cidrs []string) (*Resolver, error) {
+	var r Resolver
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing trusted proxy network %q: %w", cidr, err)
+		}
+		r.trusted = append(r.trusted, network)
+	}
+
+	return &r, nil
+}
+
+// ClientIP returns the address of the client which sent the request. When the peer is a trusted
+// proxy, the X-Forwarded-For header is walked from the right, past every trusted proxy, to the
+// first address that isn't one. Addresses further left were supplied by the client and can't be
+// relied on.
+func (r *Resolver) ClientIP(req *http.Request) net.IP {
+	peer := parseIP(req.RemoteAddr)
+	if peer == nil || !r.isTrusted(peer) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseIP(hops[i])
+		if ip == nil {
+			break
+		}
+
+		client = ip
+		if !r.isTrusted(ip) {
+			break
+		}
+	}
+
+	return client
+}
+
+// isTrusted reports whether the address belongs to a trusted proxy.
+func (r *Resolver) isTrusted(ip net.IP) bool {
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseIP parses an address with or without a port.
+func parseIP(addr string) net.IP {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	return net.ParseIP(addr)
+}
@@ -0,0 +1,412 @@
+// Package search provides an in-memory inverted index over small text documents. Queries match
+// the words of a document by prefix, rank the documents with a weighted TF-IDF score and highlight
+// the words which matched.
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minPrefixLength is the shortest query term that matches words by prefix. Shorter terms only
+// match whole words, since a single letter would match most of the index.
+const minPrefixLength = 2
+
+// maxExpansions bounds how many words of the index a single query term may expand to.
+const maxExpansions = 1000
+
+// snippetLength is the length in bytes past which a highlighted field is cut down to the part
+// around its first match.
+const snippetLength = 160
+
+// saturation controls how quickly repeated words stop adding to the score of a document.
+const saturation = 1.2
+
+// Document represents a piece of content to index. The scope is an opaque label which searches can
+// be limited to, such as the owner of the content. Fields map the name of a field to its text.
+type Document struct {
+	ID     string
+	Scope  string
+	Fields map[string]string
+}
+
+// Hit represents a document which matched a search. Highlights hold the fields which matched, as
+// HTML escaped text with the matching words wrapped in <mark> elements. Long fields are cut down to
+// the part around the first match, with an ellipsis marking where they were cut.
+type Hit struct {
+	ID         string
+	Score      float64
+	Highlights map[string]string
+}
+
+// document represents an indexed document along with the set of words it was indexed under.
+type document struct {
+	scope  string
+	fields map[string]string
+	words  []string
+}
+
+// Index is an inverted index of documents. It is safe for concurrent use.
+type Index struct {
+	weights map[string]float64
+
+	mu       sync.RWMutex
+	docs     map[string]document
+	postings map[string]map[string]float64
+	words    []string
+}
+
+// NewIndex constructs an empty index for documents with the given fields, each weighted by how
+// much a match within it counts towards the score. Fields which are not listed are not indexed.
+func NewIndex(weights map[string]float64) *Index {
+	return &Index{
+		weights:  weights,
+		docs:     make(map[string]document),
+		postings: make(map[string]map[string]float64),
+	}
+}
+
+// Len returns the number of documents in the index.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.docs)
+}
+
+// Put adds the document to the index, replacing any document with the same ID.
+func (idx *Index) Put(doc Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(doc.ID)
+	for _, word := range idx.add(doc) {
+		if len(idx.postings[word]) == 1 {
+			i := sort.SearchStrings(idx.words, word)
+			idx.words = append(idx.words, "")
+			copy(idx.words[i+1:], idx.words[i:])
+			idx.words[i] = word
+		}
+	}
+}
+
+// Remove takes the document with the given ID out of the index. Removing a document which isn't in
+// the index does nothing.
+func (idx *Index) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+}
+
+// Replace swaps the whole content of the index for the given documents. Searches keep running
+// against the old content until the new one is ready.
+func (idx *Index) Replace(docs []Document) {
+	fresh := Index{
+		weights:  idx.weights,
+		docs:     make(map[string]document, len(docs)),
+		postings: make(map[string]map[string]float64),
+	}
+	for _, doc := range docs {
+		fresh.remove(doc.ID)
+		fresh.add(doc)
+	}
+
+	fresh.words = make([]string, 0, len(fresh.postings))
+	for word := range fresh.postings {
+		fresh.words = append(fresh.words, word)
+	}
+	sort.Strings(fresh.words)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.docs = fresh.docs
+	idx.postings = fresh.postings
+	idx.words = fresh.words
+}
+
+// Search returns at most limit documents matching every word of the query, best match first. A
+// query word matches the words of a document which start with it, so "exa" finds "example". A
+// blank scope searches every document, otherwise only the documents within the scope.
+func (idx *Index) Search(query string, scope string, limit int) []Hit {
+	terms := queryTerms(query)
+	if len(terms) == 0 || limit < 1 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// Every term must match, so the scores only ever keep documents which matched all the terms
+	// so far. A document scores the best of the words a term expands to.
+	var scores map[string]float64
+	total := float64(len(idx.docs))
+	for i, term := range terms {
+		matched := make(map[string]float64)
+		for _, word := range idx.expand(term) {
+			postings := idx.postings[word]
+			idf := math.Log(1 + total/float64(len(postings)))
+			boost := float64(len(term)) / float64(len(word))
+
+			for id, tf := range postings {
+				if scope != "" && idx.docs[id].scope != scope {
+					continue
+				}
+				if i > 0 {
+					if _, ok := scores[id]; !ok {
+						continue
+					}
+				}
+
+				score := boost * idf * tf * (saturation + 1) / (tf + saturation)
+				if score > matched[id] {
+					matched[id] = score
+				}
+			}
+		}
+
+		if i == 0 {
+			scores = matched
+		} else {
+			for id := range scores {
+				if m, ok := matched[id]; ok {
+					scores[id] += m
+				} else {
+					delete(scores, id)
+				}
+			}
+		}
+
+		if len(scores) == 0 {
+			return nil
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	for i := range hits {
+		hits[i].Highlights = make(map[string]string)
+		for name, text := range idx.docs[hits[i].ID].fields {
+			if snippet, ok := highlight(text, terms); ok {
+				hits[i].Highlights[name] = snippet
+			}
+		}
+	}
+
+	return hits
+}
+
+// add indexes the document, which must not be in the index, and returns the words it was indexed
+// under. The sorted list of words is left for the caller to maintain.
+func (idx *Index) add(doc Document) []string {
+	d := document{
+		scope:  doc.Scope,
+		fields: make(map[string]string, len(doc.Fields)),
+	}
+
+	tf := make(map[string]float64)
+	for name, text := range doc.Fields {
+		weight, ok := idx.weights[name]
+		if !ok || text == "" {
+			continue
+		}
+		d.fields[name] = text
+
+		for _, t := range tokenize(text) {
+			tf[t.word] += weight
+		}
+	}
+
+	for word, n := range tf {
+		postings, ok := idx.postings[word]
+		if !ok {
+			postings = make(map[string]float64)
+			idx.postings[word] = postings
+		}
+		postings[doc.ID] = n
+		d.words = append(d.words, word)
+	}
+
+	idx.docs[doc.ID] = d
+
+	return d.words
+}
+
+// remove takes the document out of the index along with any word only it was indexed under.
+func (idx *Index) remove(id string) {
+	d, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, word := range d.words {
+		postings := idx.postings[word]
+		delete(postings, id)
+		if len(postings) > 0 {
+			continue
+		}
+
+		delete(idx.postings, word)
+		if i := sort.SearchStrings(idx.words, word); i < len(idx.words) && idx.words[i] == word {
+			idx.words = append(idx.words[:i], idx.words[i+1:]...)
+		}
+	}
+
+	delete(idx.docs, id)
+}
+
+// expand returns the words of the index which the query term matches.
+func (idx *Index) expand(term string) []string {
+	if len(term) < minPrefixLength {
+		if _, ok := idx.postings[term]; ok {
+			return []string{term}
+		}
+		return nil
+	}
+
+	var words []string
+	for i := sort.SearchStrings(idx.words, term); i < len(idx.words) && len(words) < maxExpansions; i++ {
+		if !strings.HasPrefix(idx.words[i], term) {
+			break
+		}
+		words = append(words, idx.words[i])
+	}
+
+	return words
+}
+
+// =================================================================================================
+
+// token represents a word of a text along with where it sits within the text.
+type token struct {
+	word  string
+	start int
+	end   int
+}
+
+// tokenize splits a text into lowercased words made of letters and digits. Everything else, such as
+// the punctuation of a URL, separates words.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			tokens = append(tokens, token{word: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		tokens = append(tokens, token{word: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+
+	return tokens
+}
+
+// queryTerms returns the distinct words of a query.
+func queryTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range tokenize(query) {
+		if !seen[t.word] {
+			seen[t.word] = true
+			terms = append(terms, t.word)
+		}
+	}
+
+	return terms
+}
+
+// matches reports whether a word is matched by any of the query terms.
+func matches(word string, terms []string) bool {
+	for _, term := range terms {
+		if word == term || (len(term) >= minPrefixLength && strings.HasPrefix(word, term)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// highlight marks the words of the text which are matched by the query terms, and reports whether
+// there were any.
+func highlight(text string, terms []string) (string, bool) {
+	var marked []token
+	for _, t := range tokenize(text) {
+		if matches(t.word, terms) {
+			marked = append(marked, t)
+		}
+	}
+	if len(marked) == 0 {
+		return "", false
+	}
+
+	// Cut long texts down to a window which starts a little before the first match.
+	from, to := 0, len(text)
+	if len(text) > snippetLength {
+		from = marked[0].start - snippetLength/4
+		if from < 0 {
+			from = 0
+		}
+		to = from + snippetLength
+		if to > len(text) {
+			to = len(text)
+			from = to - snippetLength
+		}
+		for from > 0 && !utf8.RuneStart(text[from]) {
+			from--
+		}
+		for to < len(text) && !utf8.RuneStart(text[to]) {
+			to++
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+
+	pos := from
+	for _, t := range marked {
+		if t.start < from || t.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:t.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[t.start:t.end]))
+		b.WriteString("</mark>")
+		pos = t.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+
+	if to < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String(), true
+}
@@ -0,0 +1,213 @@
+package search_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/yashshah7197/shrt/foundation/search"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// newIndex returns an index of links holding the given documents.
+func newIndex(docs ...search.Document) *search.Index {
+	idx := search.NewIndex(map[string]float64{"title": 2, "destination": 1})
+	for _, doc := range docs {
+		idx.Put(doc)
+	}
+
+	return idx
+}
+
+// link returns a document for a link with the given title and destination.
+func link(id string, scope string, title string, destination string) search.Document {
+	return search.Document{
+		ID:     id,
+		Scope:  scope,
+		Fields: map[string]string{"title": title, "destination": destination},
+	}
+}
+
+// ids returns the IDs of the hits in order.
+func ids(hits []search.Hit) string {
+	s := make([]string, len(hits))
+	for i, h := range hits {
+		s[i] = h.ID
+	}
+
+	return strings.Join(s, ",")
+}
+
+func TestSearch(t *testing.T) {
+	t.Log("Given the need to find documents by the words in them.")
+	{
+		idx := newIndex(
+			link("1", "alice", "Example landing page", "https://example.com/landing"),
+			link("2", "alice", "Spring sale", "https://shop.example.org/sale?utm=spring"),
+			link("3", "bob", "Docs", "https://docs.example.net/a/b"),
+			link("4", "bob", "Example example example", "https://other.test/"),
+			search.Document{ID: "5", Scope: "bob", Fields: map[string]string{"notes": "unindexed landing"}},
+		)
+		if idx.Len() != 5 {
+			t.Fatalf("\t%s\tShould hold 5 documents, got %d.", failed, idx.Len())
+		}
+
+		tt := []struct {
+			name  string
+			query string
+			scope string
+			limit int
+			want  string
+		}{
+			{"whole word", "landing", "", 10, "1"},
+			{"any case", "LANDING", "", 10, "1"},
+			{"prefix", "lan", "", 10, "1"},
+			{"prefix of two letters", "sp", "", 10, "2"},
+			{"single letter as a whole word", "a", "", 10, "3"},
+			{"single letter not as a prefix", "l", "", 10, ""},
+			{"words of a url", "utm", "", 10, "2"},
+			{"every word must match", "example landing", "", 10, "1"},
+			{"no document matches every word", "spring docs", "", 10, ""},
+			{"repeated words rank higher", "example", "", 10, "4,1,2,3"},
+			{"scope", "example", "alice", 10, "1,2"},
+			{"unknown scope", "example", "carol", 10, ""},
+			{"limit", "example", "", 2, "4,1"},
+			{"zero limit", "example", "", 0, ""},
+			{"blank query", " ?! ", "", 10, ""},
+			{"unindexed field", "unindexed", "", 10, ""},
+			{"no match", "missing", "", 10, ""},
+		}
+
+		for testID, tst := range tt {
+			got := ids(idx.Search(tst.query, tst.scope, tst.limit))
+			if got != tst.want {
+				t.Fatalf("\t%s\tTest %d:\tShould find %q for %s, got %q.", failed, testID, tst.want, tst.name, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould find %q for %s.", success, testID, tst.want, tst.name)
+		}
+
+		// A title match counts double, and an exact word beats a word it is only a prefix of.
+		title := newIndex(link("t", "", "promo", "https://a.test/"), link("d", "", "other", "https://a.test/promo"))
+		if got := ids(title.Search("promo", "", 10)); got != "t,d" {
+			t.Fatalf("\t%s\tShould rank a title match first, got %q.", failed, got)
+		}
+		prefix := newIndex(link("p", "", "promotion", ""), link("w", "", "promo", ""))
+		if got := ids(prefix.Search("promo", "", 10)); got != "w,p" {
+			t.Fatalf("\t%s\tShould rank a whole word above a longer word, got %q.", failed, got)
+		}
+		t.Logf("\t%s\tShould weigh the fields and the length of the matched words.", success)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	t.Log("Given the need to show where a document matched.")
+	{
+		idx := newIndex(link("1", "", "Tom & Jerry's <b>show</b>", "https://example.com/show?ep=1"))
+
+		hits := idx.Search("sho", "", 10)
+		if len(hits) != 1 {
+			t.Fatalf("\t%s\tShould find the document, got %d hits.", failed, len(hits))
+		}
+
+		want := map[string]string{
+			"title":       "Tom &amp; Jerry&#39;s &lt;b&gt;<mark>show</mark>&lt;/b&gt;",
+			"destination": "https://example.com/<mark>show</mark>?ep=1",
+		}
+		for name, w := range want {
+			if got := hits[0].Highlights[name]; got != w {
+				t.Fatalf("\t%s\tShould highlight the %s as %q, got %q.", failed, name, w, got)
+			}
+		}
+		t.Logf("\t%s\tShould escape the fields and mark the matched words.", success)
+
+		hits = idx.Search("jerry", "", 10)
+		if _, ok := hits[0].Highlights["destination"]; ok || len(hits[0].Highlights) != 1 {
+			t.Fatalf("\t%s\tShould only highlight the fields which matched, got %v.", failed, hits[0].Highlights)
+		}
+		t.Logf("\t%s\tShould only highlight the fields which matched.", success)
+
+		long := strings.Repeat("ä filler ", 40) + "needle " + strings.Repeat("more text ", 40)
+		idx = newIndex(link("2", "", long, ""))
+		hits = idx.Search("needle", "", 10)
+		if len(hits) != 1 {
+			t.Fatalf("\t%s\tShould find the long document, got %d hits.", failed, len(hits))
+		}
+
+		snippet := hits[0].Highlights["title"]
+		if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") || !strings.Contains(snippet, "<mark>needle</mark>") {
+			t.Fatalf("\t%s\tShould cut a long field down to the match, got %q.", failed, snippet)
+		}
+		if !utf8.ValidString(snippet) || len(snippet) > 200 {
+			t.Fatalf("\t%s\tShould cut a long field on character boundaries, got %q.", failed, snippet)
+		}
+		t.Logf("\t%s\tShould cut a long field down to the match.", success)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	t.Log("Given the need to keep the index in step with the documents.")
+	{
+		idx := newIndex(
+			link("1", "", "Summer campaign", "https://example.com/summer"),
+			link("2", "", "Winter campaign", "https://example.com/winter"),
+		)
+
+		idx.Put(link("1", "", "Autumn campaign", "https://example.com/autumn"))
+		if idx.Len() != 2 {
+			t.Fatalf("\t%s\tShould replace a document with the same ID, got %d documents.", failed, idx.Len())
+		}
+		if got := ids(idx.Search("summer", "", 10)); got != "" {
+			t.Fatalf("\t%s\tShould forget the old words of a replaced document, got %q.", failed, got)
+		}
+		if got := ids(idx.Search("autu", "", 10)); got != "1" {
+			t.Fatalf("\t%s\tShould find a replaced document by its new words, got %q.", failed, got)
+		}
+		t.Logf("\t%s\tShould replace a document with the same ID.", success)
+
+		idx.Remove("2")
+		idx.Remove("missing")
+		if idx.Len() != 1 {
+			t.Fatalf("\t%s\tShould remove the document, got %d documents.", failed, idx.Len())
+		}
+		if got := ids(idx.Search("wi", "", 10)); got != "" {
+			t.Fatalf("\t%s\tShould forget the words of a removed document, got %q.", failed, got)
+		}
+		if got := ids(idx.Search("campaign", "", 10)); got != "1" {
+			t.Fatalf("\t%s\tShould keep the words other documents share, got %q.", failed, got)
+		}
+		t.Logf("\t%s\tShould remove documents.", success)
+
+		idx.Replace([]search.Document{
+			link("3", "", "Black friday", "https://example.com/bf"),
+			link("4", "", "Cyber monday", "https://example.com/cm"),
+			link("4", "", "Cyber week", "https://example.com/cw"),
+		})
+		if idx.Len() != 2 {
+			t.Fatalf("\t%s\tShould hold the new documents only, got %d documents.", failed, idx.Len())
+		}
+		if got := ids(idx.Search("campaign", "", 10)); got != "" {
+			t.Fatalf("\t%s\tShould forget the old documents, got %q.", failed, got)
+		}
+		if got := ids(idx.Search("cy we", "", 10)); got != "4" {
+			t.Fatalf("\t%s\tShould find the last of the new documents with an ID, got %q.", failed, got)
+		}
+		if got := ids(idx.Search("mo", "", 10)); got != "" {
+			t.Fatalf("\t%s\tShould not index documents replaced within the new ones, got %q.", failed, got)
+		}
+		if got := ids(idx.Search("example", "", 10)); got != "3,4" {
+			t.Fatalf("\t%s\tShould find every new document, got %q.", failed, got)
+		}
+		t.Logf("\t%s\tShould swap in the new documents.", success)
+
+		idx.Put(link("5", "", "Boxing day", "https://example.com/bd"))
+		if got := ids(idx.Search("bo", "", 10)); got != "5" {
+			t.Fatalf("\t%s\tShould keep indexing after a replace, got %q.", failed, got)
+		}
+		t.Logf("\t%s\tShould keep indexing after a replace.", success)
+	}
+}
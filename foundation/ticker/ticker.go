@@ -0,0 +1,65 @@
+// Package ticker runs a function periodically in the background until it is shut down.
+package ticker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Ticker runs a function on a fixed interval in its own goroutine.
+type Ticker struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start runs the function on every tick of the given interval until Shutdown is called. The
+// context handed to the function is cancelled when shutdown starts, so long running work can stop
+// early.
+func Start(interval time.Duration, fn func(ctx context.Context)) *Ticker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := Ticker{
+		cancel: cancel,
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-tick.C:
+				fn(ctx)
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return &t
+}
+
+// Shutdown stops the ticker and waits for a run that is in progress to finish, or for the
+// context to be done, whichever happens first.
+func (t *Ticker) Shutdown(ctx context.Context) error {
+	t.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
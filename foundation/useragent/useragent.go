@@ -0,0 +1,117 @@
+// Package useragent classifies User-Agent strings into a browser, an operating system and a class
+// of device. It only looks for the well known tokens, which is enough for coarse analytics.
+package useragent
+
+import "strings"
+
+// The set of device classes.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// Other is reported for a browser or operating system which isn't recognised.
+const Other = "Other"
+
+// UserAgent represents the classification of a User-Agent string.
+type UserAgent struct {
+	Browser string
+	OS      string
+	Device  string
+}
+
+// token pairs a substring of a User-Agent string with the name it identifies.
+type token struct {
+	match string
+	name  string
+}
+
+// bots lists the substrings which identify crawlers, link previewers and scripted clients.
+var bots = []string{
+	"bot", "crawler", "spider", "slurp", "facebookexternalhit", "preview",
+	"curl/", "wget/", "python-requests", "go-http-client", "okhttp", "httpclient",
+}
+
+// browsers lists the browser tokens in the order they must be checked, since most browsers also
+// claim to be the ones they are built on.
+var browsers = []token{
+	{"edg/", "Edge"},
+	{"edge/", "Edge"},
+	{"opr/", "Opera"},
+	{"opera", "Opera"},
+	{"samsungbrowser", "Samsung Internet"},
+	{"firefox/", "Firefox"},
+	{"fxios", "Firefox"},
+	{"crios", "Chrome"},
+	{"chromium", "Chromium"},
+	{"chrome/", "Chrome"},
+	{"trident/", "Internet Explorer"},
+	{"msie", "Internet Explorer"},
+	{"safari/", "Safari"},
+}
+
+// systems lists the operating system tokens in the order they must be checked.
+var systems = []token{
+	{"windows", "Windows"},
+	{"iphone", "iOS"},
+	{"ipad", "iOS"},
+	{"ipod", "iOS"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"android", "Android"},
+	{"cros", "ChromeOS"},
+	{"linux", "Linux"},
+}
+
+// Parse classifies the given User-Agent string.
+func Parse(ua string) UserAgent {
+	if ua == "" {
+		return UserAgent{
+			Browser: Other,
+			OS:      Other,
+			Device:  DeviceUnknown,
+		}
+	}
+
+	lower := strings.ToLower(ua)
+
+	return UserAgent{
+		Browser: match(lower, browsers),
+		OS:      match(lower, systems),
+		Device:  device(lower),
+	}
+}
+
+// match returns the name of the first token found in the User-Agent string.
+func match(ua string, tokens []token) string {
+	for _, t := range tokens {
+		if strings.Contains(ua, t.match) {
+			return t.name
+		}
+	}
+
+	return Other
+}
+
+// device classifies the kind of device the User-Agent string belongs to.
+func device(ua string) string {
+	for _, b := range bots {
+		if strings.Contains(ua, b) {
+			return DeviceBot
+		}
+	}
+
+	switch {
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"):
+		return DeviceTablet
+	case strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return DeviceTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		return DeviceMobile
+	}
+
+	return DeviceDesktop
+}
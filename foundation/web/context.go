@@ -3,6 +3,7 @@ package web
 import (
 	"context"
 	"errors"
+	"net"
 	"time"
 )
 
@@ -12,6 +13,9 @@ type ctxKey int
 // key is how request values are stored and retrieved.
 const key ctxKey = 1
 
+// connKey is how the connection of a request is stored and retrieved.
+const connKey ctxKey = 2
+
 // Values represent the state for each request.
 type Values struct {
 	TraceID    string
@@ -50,3 +54,20 @@ func SetStatusCode(ctx context.Context, statusCode int) error {
 
 	return nil
 }
+
+// ConnContext stores the connection a request came in on in its context. It is meant to be used as
+// the ConnContext of an http.Server, so that SetDeadline can be used by the handlers.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connKey, c)
+}
+
+// SetDeadline sets the time by which the request has to be read and answered in full, in place of
+// the read and write timeouts of the server. It only lasts for the current request.
+func SetDeadline(ctx context.Context, t time.Time) error {
+	c, ok := ctx.Value(connKey).(net.Conn)
+	if !ok {
+		return errors.New("connection missing from context")
+	}
+
+	return c.SetDeadline(t)
+}
@@ -36,3 +36,30 @@ func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statu
 
 	return nil
 }
+
+// RespondRaw sends the given bytes to the client as is, using the provided content type.
+func RespondRaw(ctx context.Context, w http.ResponseWriter, contentType string, data []byte, statusCode int) error {
+	// Set the status code for the request logger middleware.
+	SetStatusCode(ctx, statusCode)
+
+	// Set the content type and write the status code to the response.
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+
+	// Send the result back to the client.
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Redirect replies to the request with a redirect to the given URL using the provided status code.
+func Redirect(ctx context.Context, w http.ResponseWriter, r *http.Request, url string, statusCode int) error {
+	// Set the status code for the request logger middleware.
+	SetStatusCode(ctx, statusCode)
+
+	http.Redirect(w, r, url, statusCode)
+
+	return nil
+}
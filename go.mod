@@ -7,8 +7,10 @@ require (
 	github.com/go-chi/chi/v5 v5.0.7
 	github.com/google/uuid v1.3.0
 	github.com/lestrrat-go/jwx v1.2.18
+	github.com/lib/pq v1.10.4
 	go.uber.org/automaxprocs v1.4.0
 	go.uber.org/zap v1.19.1
+	golang.org/x/crypto v0.0.0-20201217014255-9d1352758620
 )
 
 require (
@@ -22,5 +24,4 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
 	go.uber.org/multierr v1.6.0 // indirect
-	golang.org/x/crypto v0.0.0-20201217014255-9d1352758620 // indirect
 )